@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/model"
+
+	"github.com/spf13/cobra"
+)
+
+// intArgs returns a validator of the positional arguments, which must be integers with the given names
+// (eg: `RACE_ID`), in the same order
+func intArgs(names ...string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) != len(names) {
+			return fmt.Errorf("accepts %d arg(s), received %d", len(names), len(args))
+		}
+		for i, arg := range args {
+			if _, err := strconv.Atoi(arg); err != nil {
+				return fmt.Errorf("invalid %s: '%s' is not a number", names[i], arg)
+			}
+		}
+		return nil
+	}
+}
+
+// intArg returns the positional argument at the given index, which was already validated by `intArgs`
+func intArg(args []string, index int) int {
+	value, _ := strconv.Atoi(args[index])
+	return value
+}
+
+// timeStr returns the given time in a human readable format in the time zone of the events, or "-" if it is zero
+func timeStr(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return model.LocalTime(t).Format("2006-01-02 15:04:05 MST")
+}
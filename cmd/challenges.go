@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/cobra"
+)
+
+func newChallengesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "challenges",
+		Short: "Manages the challenges of the races, in which the teams are ranked separately",
+	}
+	cmd.AddCommand(
+		newListChallengesCommand(),
+		newCreateChallengeCommand(),
+	)
+	return cmd
+}
+
+func newListChallengesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list RACE_ID",
+		Short: "Lists the challenges declared in a race",
+		Args:  intArgs("RACE_ID"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raceID := intArg(args, 0)
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				challenges, err := svc.ListChallenges(ctx, raceID)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tNAME")
+				for _, challenge := range challenges {
+					fmt.Fprintf(w, "%d\t%s\n", challenge.ID, challenge.Name)
+				}
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func newCreateChallengeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "create RACE_ID NAME",
+		Short: "Declares a challenge in a race",
+		Long: `Declares a challenge in a race (eg: "Challenge Entreprise"). The teams are imported in a challenge when
+the 4th column of their records matches its name, and the results include a ranking for each challenge.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+				return err
+			}
+			return intArgs("RACE_ID")(cmd, args[:1])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			challenge := model.Challenge{
+				RaceID: intArg(args, 0),
+				Name:   args[1],
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				challenge, err := svc.CreateChallenge(ctx, challenge)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "challenge '%s' created with ID %d\n", challenge.Name, challenge.ID)
+				return nil
+			})
+		},
+	}
+}
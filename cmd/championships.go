@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// defaultChampionshipPoints the default points earned by the teams in each race of a championship, by rank
+var defaultChampionshipPoints = []int{20, 17, 15, 13, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+
+func newChampionshipsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "championships",
+		Short: "Manages the championships, in which the teams earn points over a series of races",
+	}
+	cmd.AddCommand(
+		newListChampionshipsCommand(),
+		newCreateChampionshipCommand(),
+		newAddChampionshipRaceCommand(),
+		newStandingsCommand(),
+	)
+	return cmd
+}
+
+func newListChampionshipsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Lists the championships",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				championships, err := svc.ListChampionships(ctx)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tNAME\tPOINTS\tBEST OF\tTEAM MATCHING")
+				for _, c := range championships {
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", c.ID, c.Name, pointsStr(c.Points), bestOfStr(c.BestOf), c.TeamMatching)
+				}
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func newCreateChampionshipCommand() *cobra.Command {
+	var points []int
+	var bestOf int
+	var teamMatching string
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Creates a championship with the given name",
+		Long: `Creates a championship with the given name. The teams earn points in each race according to their scratch rank,
+and only their best results count when --best-of is set. Since the teams are registered again in each race,
+their results are matched by team name or by members (in any order), regardless of the case and accents.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			championship := model.Championship{
+				Name:         args[0],
+				Points:       make(pq.Int64Array, len(points)),
+				BestOf:       bestOf,
+				TeamMatching: model.TeamMatching(teamMatching),
+			}
+			for i, p := range points {
+				if p < 0 {
+					return fmt.Errorf("invalid points: '%d' is negative", p)
+				}
+				championship.Points[i] = int64(p)
+			}
+			if bestOf < 0 {
+				return fmt.Errorf("invalid number of results counted: '%d' is negative", bestOf)
+			}
+			if championship.TeamMatching != model.TeamMatchingByName && championship.TeamMatching != model.TeamMatchingByMembers {
+				return fmt.Errorf("invalid team matching: '%s' (expected one of: %s, %s)", teamMatching, model.TeamMatchingByName, model.TeamMatchingByMembers)
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				championship, err := svc.CreateChampionship(ctx, championship)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "championship '%s' created with ID %d\n", championship.Name, championship.ID)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().IntSliceVar(&points, "points", defaultChampionshipPoints, "comma-separated list of the points earned in each race, by scratch rank")
+	cmd.Flags().IntVar(&bestOf, "best-of", 0, "number of results of each team which count in the standings (0: all results)")
+	cmd.Flags().StringVar(&teamMatching, "match", string(model.TeamMatchingByName), "how the teams are matched across the races ('name' or 'members')")
+	return cmd
+}
+
+func newAddChampionshipRaceCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add-race CHAMPIONSHIP_ID RACE_ID",
+		Short: "Adds a race to a championship",
+		Args:  intArgs("CHAMPIONSHIP_ID", "RACE_ID"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			championshipID, raceID := intArg(args, 0), intArg(args, 1)
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				err := svc.AddChampionshipRace(ctx, championshipID, raceID)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "race %d added to championship %d\n", raceID, championshipID)
+				return nil
+			})
+		},
+	}
+}
+
+func newStandingsCommand() *cobra.Command {
+	var outputDir string
+	var outputFormats string
+	var lang string
+	cmd := &cobra.Command{
+		Use:   "standings CHAMPIONSHIP_ID",
+		Short: "Generates the standings of a championship",
+		Long: `Generates the standings of a championship, from the scratch rankings of its races, in all the given formats.
+The points which do not count in the total of a team (see --best-of) are displayed in parentheses.`,
+		Args: intArgs("CHAMPIONSHIP_ID"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			championshipID := intArg(args, 0)
+			formats, err := service.ParseOutputFormats(outputFormats)
+			if err != nil {
+				return err
+			}
+			if _, err := service.ParseLocale(lang); err != nil {
+				return err
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				logrus.WithField("championship_id", championshipID).WithField("output_dir", outputDir).Info("Generating standings...")
+				svc := service.NewResultService(db)
+				return svc.GenerateStandings(ctx, championshipID, outputDir, formats, lang)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&outputDir, "output", ".", "directory in which the standings are generated")
+	cmd.Flags().StringVar(&outputFormats, "format", "adoc", formatsUsage)
+	cmd.Flags().StringVar(&lang, "lang", "", langUsage)
+	return cmd
+}
+
+// pointsStr returns the given points as a comma-separated list
+func pointsStr(points pq.Int64Array) string {
+	result := make([]string, len(points))
+	for i, p := range points {
+		result[i] = fmt.Sprintf("%d", p)
+	}
+	return strings.Join(result, ",")
+}
+
+// bestOfStr returns the number of results counted in a championship, in a human readable format
+func bestOfStr(bestOf int) string {
+	if bestOf == 0 {
+		return "all"
+	}
+	return fmt.Sprintf("%d", bestOf)
+}
@@ -0,0 +1,120 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/cmd"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommands(t *testing.T) {
+
+	t.Run("help", func(t *testing.T) {
+		testcases := [][]string{
+			{"serve"},
+			{"migrate"},
+			{"events", "list"},
+			{"events", "create"},
+			{"import"},
+			{"results"},
+			{"podiums"},
+			{"clubs"},
+			{"startlist"},
+			{"races", "list"},
+			{"races", "create"},
+			{"races", "start"},
+			{"races", "end"},
+			{"challenges", "list"},
+			{"challenges", "create"},
+			{"teams", "list"},
+			{"laps", "add"},
+			{"laps", "delete"},
+			{"championships", "list"},
+			{"championships", "create"},
+			{"championships", "add-race"},
+			{"championships", "standings"},
+		}
+		for _, args := range testcases {
+			t.Run(args[len(args)-1], func(t *testing.T) {
+				// given
+				root := cmd.NewRootCommand()
+				out := &bytes.Buffer{}
+				root.SetOutput(out)
+				root.SetArgs(append(args, "--help"))
+				// when
+				err := root.Execute()
+				// then
+				require.NoError(t, err)
+				assert.Contains(t, out.String(), "Usage:")
+			})
+		}
+	})
+
+	// invalid arguments and flags are rejected before connecting to the database
+	t.Run("invalid arguments", func(t *testing.T) {
+		testcases := map[string]struct {
+			args          []string
+			expectedError string
+		}{
+			"missing race ID":        {args: []string{"results"}, expectedError: "accepts 1 arg(s), received 0"},
+			"invalid race ID":        {args: []string{"races", "start", "abc"}, expectedError: "invalid RACE_ID: 'abc' is not a number"},
+			"invalid bib number":     {args: []string{"laps", "add", "1", "x"}, expectedError: "invalid BIBNUMBER: 'x' is not a number"},
+			"missing bib number":     {args: []string{"laps", "delete", "1"}, expectedError: "accepts 2 arg(s), received 1"},
+			"missing file":           {args: []string{"import", "--event", "1"}, expectedError: "accepts 1 arg(s), received 0"},
+			"missing import event":   {args: []string{"import", "teams.csv"}, expectedError: "required flag(s) \"event\" not set"},
+			"missing race event":     {args: []string{"races", "create", "race"}, expectedError: "required flag(s) \"event\" not set"},
+			"invalid event date":     {args: []string{"events", "create", "event", "--date", "17/11/2019"}, expectedError: "invalid date: '17/11/2019'"},
+			"missing event date":     {args: []string{"events", "create", "event"}, expectedError: "required flag(s) \"date\" not set"},
+			"invalid format":         {args: []string{"results", "1", "--format", "doc"}, expectedError: "unsupported output format: 'doc'"},
+			"invalid sort order":     {args: []string{"startlist", "1", "--sort", "age"}, expectedError: "age"},
+			"invalid team status":    {args: []string{"teams", "list", "1", "--status", "lost"}, expectedError: "lost"},
+			"invalid planned time":   {args: []string{"races", "create", "race", "--event", "1", "--planned-start-time", "10:00"}, expectedError: "invalid planned start time: '10:00'"},
+			"invalid points":         {args: []string{"championships", "create", "c", "--points", "10,-1"}, expectedError: "invalid points: '-1' is negative"},
+			"invalid best of":        {args: []string{"championships", "create", "c", "--best-of", "-2"}, expectedError: "invalid number of results counted: '-2' is negative"},
+			"invalid team matching":  {args: []string{"championships", "create", "c", "--match", "bib"}, expectedError: "invalid team matching: 'bib'"},
+			"invalid championship":   {args: []string{"championships", "standings", "x"}, expectedError: "invalid CHAMPIONSHIP_ID: 'x' is not a number"},
+			"missing race to add":    {args: []string{"championships", "add-race", "1"}, expectedError: "accepts 2 arg(s), received 1"},
+			"invalid club scoring":   {args: []string{"clubs", "1", "--scoring", "podiums"}, expectedError: "invalid club scoring: 'podiums'"},
+			"invalid best ranks":     {args: []string{"clubs", "1", "--scoring", "best-ranks", "--best-ranks", "0"}, expectedError: "invalid number of ranks counted: '0' is not positive"},
+			"missing challenge name": {args: []string{"challenges", "create", "1"}, expectedError: "accepts 2 arg(s), received 1"},
+			"invalid challenge race": {args: []string{"challenges", "create", "XS", "Challenge Entreprise"}, expectedError: "invalid RACE_ID: 'XS' is not a number"},
+			"unexpected argument":    {args: []string{"migrate", "now"}, expectedError: "unknown command \"now\""},
+		}
+		for name, tc := range testcases {
+			t.Run(name, func(t *testing.T) {
+				// given
+				root := cmd.NewRootCommand()
+				root.SetOutput(&bytes.Buffer{})
+				root.SetArgs(tc.args)
+				// when
+				err := root.Execute()
+				// then
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedError)
+			})
+		}
+	})
+	// the time zone of the events is validated before connecting to the database
+	t.Run("invalid time zone", func(t *testing.T) {
+		for _, name := range []string{"Mars/Olympus_Mons", "Local"} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				os.Setenv("STOPWATCH_TIMEZONE", name)
+				defer os.Unsetenv("STOPWATCH_TIMEZONE")
+				root := cmd.NewRootCommand()
+				root.SetOutput(&bytes.Buffer{})
+				root.SetArgs([]string{"races", "list"})
+				// when
+				err := root.Execute()
+				// then
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), fmt.Sprintf("invalid time zone: '%s'", name))
+			})
+		}
+	})
+}
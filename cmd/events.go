@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/cobra"
+)
+
+const dateFmt = "2006-01-02"
+
+func newEventsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Manages the events, during which the races take place",
+	}
+	cmd.AddCommand(
+		newListEventsCommand(),
+		newCreateEventCommand(),
+	)
+	return cmd
+}
+
+func newListEventsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Lists the events, from the most recent to the oldest one",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				events, err := svc.ListEvents(ctx)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tNAME\tDATE\tVENUE")
+				for _, event := range events {
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", event.ID, event.Name, event.Date.Format(dateFmt), event.Venue)
+				}
+				return w.Flush()
+			})
+		},
+	}
+}
+
+func newCreateEventCommand() *cobra.Command {
+	var date string
+	var venue string
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Creates an event with the given name",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, err := time.Parse(dateFmt, date)
+			if err != nil {
+				return fmt.Errorf("invalid date: '%s' (expected format: %s)", date, dateFmt)
+			}
+			event := model.Event{
+				Name:  args[0],
+				Date:  d,
+				Venue: venue,
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				event, err := svc.CreateEvent(ctx, event)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "event '%s' created with ID %d\n", event.Name, event.ID)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&date, "date", "", "date of the event (eg: '2019-11-17')")
+	cmd.Flags().StringVar(&venue, "venue", "", "venue of the event")
+	cmd.MarkFlagRequired("date")
+	return cmd
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newImportCommand() *cobra.Command {
+	var eventID int
+	cmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Imports the teams of the races of an event from a CSV, XLSX or ODS file",
+		Long: `Imports the teams from a CSV, XLSX or ODS file, in a single transaction.
+
+The first row contains the headers, then each team is described by 2 consecutive rows (one per team member).
+The first column of each row is the name of the race of the team, which must exist in the event.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				logrus.WithField("file", args[0]).Info("importing...")
+				svc := service.NewImportService(db)
+				return svc.ImportFromFile(ctx, eventID, args[0])
+			})
+		},
+	}
+	cmd.Flags().IntVar(&eventID, "event", 0, "ID of the event")
+	cmd.MarkFlagRequired("event")
+	return cmd
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/cobra"
+)
+
+func newLapsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "laps",
+		Short: "Manages the laps of the teams",
+	}
+	cmd.AddCommand(
+		newAddLapCommand(),
+		newDeleteLapCommand(),
+	)
+	return cmd
+}
+
+func newAddLapCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add RACE_ID BIBNUMBER",
+		Short: "Records a lap (now) for the team with the given bib number",
+		Args:  intArgs("RACE_ID", "BIBNUMBER"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				team, err := svc.AddLap(ctx, intArg(args, 0), intArg(args, 1))
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "lap recorded for team '%s' (bib number %d): %d lap(s)\n", team.Name, team.BibNumber, len(team.Laps))
+				return nil
+			})
+		},
+	}
+}
+
+func newDeleteLapCommand() *cobra.Command {
+	var lapID int
+	cmd := &cobra.Command{
+		Use:   "delete RACE_ID BIBNUMBER",
+		Short: "Deletes the last lap (or the lap with the given ID) of the team with the given bib number",
+		Args:  intArgs("RACE_ID", "BIBNUMBER"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				team, err := svc.DeleteLap(ctx, intArg(args, 0), intArg(args, 1), lapID)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "lap deleted for team '%s' (bib number %d): %d lap(s)\n", team.Name, team.BibNumber, len(team.Laps))
+				return nil
+			})
+		},
+	}
+	cmd.Flags().IntVar(&lapID, "lap", 0, "ID of the lap to delete (default: the last lap of the team)")
+	return cmd
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Creates or upgrades the database schema",
+		Long: `Creates or upgrades the database schema, by applying the migrations which were not applied yet.
+Each migration is applied in its own transaction.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				version, err := model.Migrate(ctx, db.DB())
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "database schema is at version %d\n", version)
+				return nil
+			})
+		},
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/cobra"
+)
+
+func newRacesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "races",
+		Short: "Manages the races",
+	}
+	cmd.AddCommand(
+		newListRacesCommand(),
+		newCreateRaceCommand(),
+		newStartRaceCommand(),
+		newEndRaceCommand(),
+	)
+	return cmd
+}
+
+func newListRacesCommand() *cobra.Command {
+	var eventID int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists the races (of all events, or of the given event)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				var races []model.Race
+				var err error
+				if eventID != 0 {
+					races, err = svc.ListEventRaces(ctx, eventID)
+				} else {
+					races, err = svc.ListRaces(ctx)
+				}
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tEVENT\tNAME\tGENDERS\tFIRST LAP\tPLANNED START\tSTART\tEND")
+				for _, race := range races {
+					fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", race.ID, race.EventID, race.Name,
+						strings.Join(race.Genders, ","), firstLapStr(race),
+						timeStr(race.PlannedStartTime), timeStr(race.StartTime), timeStr(race.EndTime))
+				}
+				return w.Flush()
+			})
+		},
+	}
+	cmd.Flags().IntVar(&eventID, "event", 0, "ID of the event")
+	return cmd
+}
+
+// firstLapStr describes whether the first lap can be recorded for all teams in the given race
+func firstLapStr(race model.Race) string {
+	switch {
+	case race.HasFirstLap:
+		return "recorded"
+	case race.AllowsFirstLap:
+		return "allowed"
+	default:
+		return "-"
+	}
+}
+
+func newCreateRaceCommand() *cobra.Command {
+	var eventID int
+	var allowsFirstLap bool
+	var plannedStartTime string
+	var genders []string
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Creates a race with the given name in an event",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			race := model.Race{
+				Name:           args[0],
+				EventID:        eventID,
+				AllowsFirstLap: allowsFirstLap,
+				Genders:        genders,
+			}
+			if plannedStartTime != "" {
+				t, err := time.Parse(time.RFC3339, plannedStartTime)
+				if err != nil {
+					return fmt.Errorf("invalid planned start time: '%s' (expected format: %s)", plannedStartTime, time.RFC3339)
+				}
+				race.PlannedStartTime = t
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				race, err := svc.CreateRace(ctx, race)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "race '%s' created with ID %d\n", race.Name, race.ID)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().IntVar(&eventID, "event", 0, "ID of the event")
+	cmd.MarkFlagRequired("event")
+	cmd.Flags().BoolVar(&allowsFirstLap, "allows-first-lap", false, "allow recording the first lap for all teams at once")
+	cmd.Flags().StringSliceVar(&genders, "genders", nil, "gender categories of the race: 'H' (men), 'F' (women) and/or 'M' (mixed) (default: H,F,M)")
+	cmd.Flags().StringVar(&plannedStartTime, "planned-start-time", "", "time at which the race is planned to start (eg: '2019-11-17T10:00:00+01:00')")
+	return cmd
+}
+
+func newStartRaceCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "start RACE_ID",
+		Short: "Starts a race",
+		Args:  intArgs("RACE_ID"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				race, err := svc.StartRace(ctx, intArg(args, 0))
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "race '%s' started at %s\n", race.Name, timeStr(race.StartTime))
+				return nil
+			})
+		},
+	}
+}
+
+func newEndRaceCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "end RACE_ID",
+		Short: "Ends a race",
+		Args:  intArgs("RACE_ID"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				race, err := svc.EndRace(ctx, intArg(args, 0))
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "race '%s' ended at %s\n", race.Name, timeStr(race.EndTime))
+				return nil
+			})
+		},
+	}
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const formatsUsage = "comma-separated list of output formats ('adoc', 'csv', 'json', 'html', 'pdf')"
+
+const langUsage = "language of the generated documents ('fr', 'en' or 'de') (default: STOPWATCH_LOCALE or 'fr')"
+
+func newResultsCommand() *cobra.Command {
+	var eventID int
+	var outputDir string
+	var outputFormats string
+	var includeLapAnalytics bool
+	var lang string
+	cmd := &cobra.Command{
+		Use:   "results [RACE_ID]",
+		Short: "Generates the results of a race, or of all the races of an event",
+		Long: `Generates the results of a race (or of all the races of the event given with --event): the scratch,
+the challenges and the rankings by age and gender, in all the given formats (PDF documents are print-ready).`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if eventID != 0 {
+				return cobra.NoArgs(cmd, args)
+			}
+			return intArgs("RACE_ID")(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			formats, err := service.ParseOutputFormats(outputFormats)
+			if err != nil {
+				return err
+			}
+			if _, err := service.ParseLocale(lang); err != nil {
+				return err
+			}
+			opts := service.ResultOptions{
+				IncludeLapAnalytics: includeLapAnalytics,
+				Locale:              lang,
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewResultService(db)
+				if eventID != 0 {
+					logrus.WithField("event_id", eventID).WithField("output_dir", outputDir).Info("Generating results...")
+					return svc.GenerateEventResults(ctx, eventID, outputDir, formats, opts)
+				}
+				raceID := intArg(args, 0)
+				logrus.WithField("race_id", raceID).WithField("output_dir", outputDir).Info("Generating results...")
+				return svc.GenerateResults(ctx, raceID, outputDir, formats, opts)
+			})
+		},
+	}
+	cmd.Flags().IntVar(&eventID, "event", 0, "ID of the event, to generate the results of all its races")
+	cmd.Flags().StringVar(&outputDir, "output", ".", "directory in which the results are generated")
+	cmd.Flags().StringVar(&outputFormats, "format", "adoc", formatsUsage)
+	cmd.Flags().BoolVar(&includeLapAnalytics, "lap-analytics", false, "include the fastest lap and average pace of the teams in the results, along with the fastest lap awards")
+	cmd.Flags().StringVar(&lang, "lang", "", langUsage)
+	return cmd
+}
+
+func newPodiumsCommand() *cobra.Command {
+	var outputDir string
+	var outputFormats string
+	var size int
+	var lang string
+	cmd := &cobra.Command{
+		Use:   "podiums RACE_ID",
+		Short: "Generates the podiums of a race, in ceremony order",
+		Long: `Generates the podiums of a race, in ceremony order: the categories (from the youngest to the oldest teams),
+then the challenges and finally, the scratch.
+
+The teams on the scratch podium are not awarded in their category when STOPWATCH_PODIUM_EXCLUDE_SCRATCH_WINNERS is set.`,
+		Args: intArgs("RACE_ID"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raceID := intArg(args, 0)
+			formats, err := service.ParseOutputFormats(outputFormats)
+			if err != nil {
+				return err
+			}
+			if _, err := service.ParseLocale(lang); err != nil {
+				return err
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				logrus.WithField("race_id", raceID).WithField("output_dir", outputDir).Info("Generating podiums...")
+				svc := service.NewResultService(db)
+				return svc.GeneratePodiums(ctx, raceID, outputDir, formats, service.PodiumOptions{
+					Size:                  size,
+					ExcludeScratchWinners: config.IsPodiumExcludeScratchWinnersEnabled(),
+					Locale:                lang,
+				})
+			})
+		},
+	}
+	cmd.Flags().StringVar(&outputDir, "output", ".", "directory in which the podiums are generated")
+	cmd.Flags().StringVar(&outputFormats, "format", "adoc", formatsUsage)
+	cmd.Flags().IntVar(&size, "size", 3, "number of teams on each podium")
+	cmd.Flags().StringVar(&lang, "lang", "", langUsage)
+	return cmd
+}
+
+func newClubsCommand() *cobra.Command {
+	var outputDir string
+	var outputFormats string
+	var scoring string
+	var bestRanks int
+	var lang string
+	cmd := &cobra.Command{
+		Use:   "clubs RACE_ID",
+		Short: "Generates the ranking of the clubs in a race",
+		Long: `Generates the ranking of the clubs in a race, by number of teams ('participation'), by sum of the scratch
+ranks of their best teams ('best-ranks') or by total laps ('laps'). A team whose members belong to different clubs
+counts as half a team (with half of its laps) for each club, while its rank counts for each club.`,
+		Args: intArgs("RACE_ID"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raceID := intArg(args, 0)
+			clubScoring, err := service.ParseClubScoring(scoring)
+			if err != nil {
+				return err
+			}
+			if bestRanks <= 0 {
+				return fmt.Errorf("invalid number of ranks counted: '%d' is not positive", bestRanks)
+			}
+			formats, err := service.ParseOutputFormats(outputFormats)
+			if err != nil {
+				return err
+			}
+			if _, err := service.ParseLocale(lang); err != nil {
+				return err
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				logrus.WithField("race_id", raceID).WithField("output_dir", outputDir).Info("Generating club ranking...")
+				svc := service.NewResultService(db)
+				return svc.GenerateClubRanking(ctx, raceID, outputDir, formats, service.ClubRankingOptions{
+					Scoring:   clubScoring,
+					BestRanks: bestRanks,
+					Locale:    lang,
+				})
+			})
+		},
+	}
+	cmd.Flags().StringVar(&outputDir, "output", ".", "directory in which the club ranking is generated")
+	cmd.Flags().StringVar(&outputFormats, "format", "adoc", formatsUsage)
+	cmd.Flags().StringVar(&scoring, "scoring", string(service.ClubScoringParticipation), "how the clubs are ranked ('participation', 'best-ranks' or 'laps')")
+	cmd.Flags().IntVar(&bestRanks, "best-ranks", 3, "number of ranks counted for each club, with the 'best-ranks' scoring")
+	cmd.Flags().StringVar(&lang, "lang", "", langUsage)
+	return cmd
+}
+
+func newStartListCommand() *cobra.Command {
+	var outputDir string
+	var outputFormats string
+	var sortOrder string
+	var lang string
+	cmd := &cobra.Command{
+		Use:   "startlist RACE_ID",
+		Short: "Exports the start list of a race",
+		Args:  intArgs("RACE_ID"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raceID := intArg(args, 0)
+			order, err := service.ParseStartListOrder(sortOrder)
+			if err != nil {
+				return err
+			}
+			formats, err := service.ParseOutputFormats(outputFormats)
+			if err != nil {
+				return err
+			}
+			if _, err := service.ParseLocale(lang); err != nil {
+				return err
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				logrus.WithField("race_id", raceID).WithField("output_dir", outputDir).Info("Exporting start list...")
+				svc := service.NewExportService(db)
+				return svc.ExportStartList(ctx, raceID, outputDir, order, formats, lang)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&outputDir, "output", ".", "directory in which the start list is exported")
+	cmd.Flags().StringVar(&outputFormats, "format", "adoc", formatsUsage)
+	cmd.Flags().StringVar(&sortOrder, "sort", "bib", "order of the teams ('bib' or 'name')")
+	cmd.Flags().StringVar(&lang, "lang", "", langUsage)
+	return cmd
+}
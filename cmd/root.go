@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/connection"
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+	_ "github.com/lib/pq" // need to import postgres driver
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NewRootCommand returns the root command of the CLI, along with all its subcommands
+func NewRootCommand() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "stopwatch",
+		Short: "Records the laps of the teams during run&bike events, and generates the results",
+		// do not print the usage when a command fails (eg: when the database is unreachable)
+		SilenceUsage: true,
+	}
+	rootCmd.AddCommand(
+		newServeCommand(),
+		newMigrateCommand(),
+		newEventsCommand(),
+		newImportCommand(),
+		newResultsCommand(),
+		newPodiumsCommand(),
+		newClubsCommand(),
+		newStartListCommand(),
+		newRacesCommand(),
+		newChallengesCommand(),
+		newTeamsCommand(),
+		newLapsCommand(),
+		newChampionshipsCommand(),
+	)
+	return rootCmd
+}
+
+// Execute executes the command given in the arguments of the program, and exits with a non-zero code if it failed
+func Execute() {
+	if err := NewRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// connect loads the configuration, applies it to the services and returns a new connection to the database
+func connect() (*configuration.Configuration, *gorm.DB, error) {
+	config, err := configuration.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	// the time zone must be valid before it is sent to the database along with the connection settings
+	timeZone, err := loadTimeZone(config.GetTimeZone())
+	if err != nil {
+		return nil, nil, err
+	}
+	model.SetTimeZone(timeZone)
+	db, err := connection.NewUserConnection(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	db.LogMode(config.IsDBLogsEnabled())
+	service.SetDBLogsEnabled(config.IsDBLogsEnabled())
+	txIsoLevel, err := service.ParseTXIsoLevel(config.GetPostgresTransactionIsolation())
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	if err := service.SetDefaultTransactionIsolationLevel(txIsoLevel); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	err = service.SetTransactionRetryPolicy(service.TransactionRetryPolicy{
+		MaxRetries: config.GetPostgresTransactionRetryMax(),
+		Backoff:    config.GetPostgresTransactionRetryBackoff(),
+	})
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	service.SetMinLapInterval(config.GetLapMinInterval())
+	if err := configureLabels(config); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	service.SetDatabaseTransactionTimeout(config.GetPostgresTransactionTimeout())
+	return config, db, nil
+}
+
+// loadTimeZone loads the time zone with the given IANA name (eg: "Europe/Paris")
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, errors.Errorf("invalid time zone: '%s' (expected an IANA time zone name, eg: Europe/Paris)", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid time zone: '%s'", name)
+	}
+	return loc, nil
+}
+
+// configureLabels applies the mapping of the imported gender values and the labels of the categories
+func configureLabels(config *configuration.Configuration) error {
+	mapping, err := service.ParseGenderMapping(config.GetGenderMapping())
+	if err != nil {
+		return err
+	}
+	service.SetGenderMapping(mapping)
+	for _, locale := range service.Locales() {
+		if err := service.SetGenderLabels(locale, config.GetGenderLabels(locale)); err != nil {
+			return err
+		}
+	}
+	return service.SetLocale(config.GetLocale())
+}
+
+// notifyShutdown returns a channel which receives the `SIGINT` and `SIGTERM` signals
+func notifyShutdown() chan os.Signal {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	return signals
+}
+
+// runWithDB connects to the database and runs the given function with a context which is cancelled on `SIGINT`
+// or `SIGTERM`, so the open transactions are rolled back. Then waits for the open transactions before closing
+// the connection to the database.
+func runWithDB(f func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error) error {
+	config, db, err := connect()
+	if err != nil {
+		return err
+	}
+	defer shutdown(nil, db, config.GetShutdownTimeout())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := notifyShutdown()
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			logrus.Warn("Interrupted, rolling back the open transactions...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return f(ctx, config, db)
+}
+
+// shutdown stops the given server (if any) once its in-flight requests are complete, then waits for the open
+// transactions before closing the connection to the database. Gives up waiting after the given timeout.
+func shutdown(s *echo.Echo, db *gorm.DB, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if s != nil {
+		logrus.Warn("Stopping the server...")
+		if err := s.Shutdown(ctx); err != nil {
+			logrus.Errorf("error while stopping the server: %v", err)
+		}
+	}
+	logrus.Debug("Waiting for the open transactions...")
+	if err := service.WaitForTransactions(ctx); err != nil {
+		logrus.Errorf("error while waiting for the open transactions: %v", err)
+	}
+	logrus.Debug("Closing DB connection before complete shutdown")
+	if err := db.Close(); err != nil {
+		logrus.Errorf("error while closing the connection to the database: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/vatriathlon/stopwatch/server"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newServeCommand() *cobra.Command {
+	var address string
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Starts the server of the REST API used to record the laps",
+		Long: `Starts the server of the REST API used to record the laps.
+
+On SIGINT or SIGTERM, the server stops accepting connections and waits for the in-flight requests
+and the open database transactions to complete before closing the connection to the database.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, db, err := connect()
+			if err != nil {
+				return err
+			}
+			signals := notifyShutdown()
+			s := server.New(service.NewApplicationService(db))
+			go func() {
+				if err := s.Start(address); err != nil && err != http.ErrServerClosed {
+					logrus.Fatalf("failed to start the server: %s", err.Error())
+				}
+			}()
+			<-signals
+			shutdown(s, db, config.GetShutdownTimeout())
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&address, "address", ":8080", "address on which the server listens")
+	return cmd
+}
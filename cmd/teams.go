@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/cobra"
+)
+
+func newTeamsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "teams",
+		Short: "Manages the teams",
+	}
+	cmd.AddCommand(newListTeamsCommand())
+	return cmd
+}
+
+func newListTeamsCommand() *cobra.Command {
+	var filter model.TeamFilter
+	var status string
+	cmd := &cobra.Command{
+		Use:   "list RACE_ID",
+		Short: "Lists the teams of a race, with their number of laps",
+		Args:  intArgs("RACE_ID"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raceID := intArg(args, 0)
+			if status != "" {
+				s, err := model.ParseTeamStatus(status)
+				if err != nil {
+					return err
+				}
+				filter.Status = s
+			}
+			return runWithDB(func(ctx context.Context, config *configuration.Configuration, db *gorm.DB) error {
+				svc := service.NewApplicationService(db)
+				teams, err := svc.ListTeamSummaries(ctx, raceID, filter)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "BIB\tNAME\tCATEGORY\tGENDER\tCHALLENGE\tSTATUS\tLAPS\tLAST LAP")
+				for _, team := range teams {
+					lastLap := "-"
+					if team.LastLapTime != nil {
+						lastLap = timeStr(*team.LastLapTime)
+					}
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", team.BibNumber, team.Name, team.AgeCategory,
+						team.Gender, team.Challenge, team.Status, team.LapCount, lastLap)
+				}
+				return w.Flush()
+			})
+		},
+	}
+	cmd.Flags().StringVar(&filter.AgeCategory, "category", "", "only list the teams in the given age category")
+	cmd.Flags().StringVar(&filter.Gender, "gender", "", "only list the teams of the given gender")
+	cmd.Flags().StringVar(&filter.Challenge, "challenge", "", "only list the teams in the given challenge")
+	cmd.Flags().StringVar(&status, "status", "", "only list the teams with the given status ('not_started', 'racing' or 'finished')")
+	return cmd
+}
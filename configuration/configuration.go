@@ -39,6 +39,16 @@ const (
 	varCleanTestDataEnabled = "clean.test.data"
 	varDBLogsEnabled        = "enable.db.logs"
 	varLogLevel             = "logrus.level"
+	// Server
+	varShutdownTimeout = "shutdown.timeout"
+	// Laps
+	varLapMinInterval = "lap.min.interval"
+	// Results
+	varPodiumExcludeScratchWinners = "podium.exclude.scratch.winners"
+	varLocale                      = "locale"
+	varTimeZone                    = "timezone"
+	varGenderMapping               = "gender.mapping"
+	varGenderLabels                = "gender.labels"
 	// Postgres
 	varPostgresHost                 = "postgres.host"
 	varPostgresPort                 = "postgres.port"
@@ -50,7 +60,11 @@ const (
 	varPostgresSSLMode              = "postgres.sslmode"
 	varPostgresConnectionTimeout    = "postgres.connection.timeout"
 	varPostgresTransactionTimeout   = "postgres.transaction.timeout"
+	varPostgresTransactionIsolation = "postgres.transaction.isolation"
+	varPostgresTransactionRetryMax  = "postgres.transaction.retry.max"
+	varPostgresTransactionBackoff   = "postgres.transaction.retry.backoff"
 	varPostgresConnectionRetrySleep = "postgres.connection.retrysleep"
+	varPostgresConnectionRetryMax   = "postgres.connection.retrymax"
 	varPostgresConnectionMaxIdle    = "postgres.connection.maxidle"
 	varPostgresConnectionMaxOpen    = "postgres.connection.maxopen"
 )
@@ -119,9 +133,20 @@ func (c *Configuration) setConfigDefaults() {
 	// Number of seconds to wait before trying to connect again
 	c.v.SetDefault(varPostgresConnectionRetrySleep, time.Duration(time.Second))
 
+	// Number of attempts to connect to the database at startup (eg: while the database container is starting)
+	c.v.SetDefault(varPostgresConnectionRetryMax, 30)
+
 	// Timeout of a transaction in minutes
 	c.v.SetDefault(varPostgresTransactionTimeout, time.Duration(5*time.Minute))
 
+	// Isolation level of the transactions (`default`, `read committed`, `repeatable read` or `serializable`)
+	c.v.SetDefault(varPostgresTransactionIsolation, "serializable")
+
+	// Number of times a transaction is retried after a serialization failure or a deadlock, and the initial
+	// duration to wait before retrying (doubled after each attempt)
+	c.v.SetDefault(varPostgresTransactionRetryMax, 5)
+	c.v.SetDefault(varPostgresTransactionBackoff, time.Duration(20*time.Millisecond))
+
 	//-----
 	// Misc
 	//-----
@@ -133,6 +158,20 @@ func (c *Configuration) setConfigDefaults() {
 
 	c.v.SetDefault(varLogLevel, defaultLogLevel)
 
+	// Maximum duration to complete the in-flight requests and open transactions when the server shuts down
+	c.v.SetDefault(varShutdownTimeout, time.Duration(30*time.Second))
+
+	// By default, all laps are recorded, even if the previous lap of the team was recorded just before
+	c.v.SetDefault(varLapMinInterval, time.Duration(0))
+
+	// By default, teams on the scratch podium can also be awarded in their category
+	c.v.SetDefault(varPodiumExcludeScratchWinners, false)
+
+	// By default, the documents are generated in French
+	c.v.SetDefault(varLocale, "fr")
+	// the time zone of the events, in which the times are displayed
+	c.v.SetDefault(varTimeZone, "Europe/Paris")
+
 	// By default, test data should be cleaned from DB, unless explicitly said otherwise.
 	c.v.SetDefault(varCleanTestDataEnabled, true)
 	// By default, DB logs are not output in the console
@@ -191,11 +230,32 @@ func (c *Configuration) GetPostgresConnectionRetrySleep() time.Duration {
 	return c.v.GetDuration(varPostgresConnectionRetrySleep)
 }
 
+// GetPostgresConnectionRetryMax returns the number of attempts to connect to the database at startup
+func (c *Configuration) GetPostgresConnectionRetryMax() int {
+	return c.v.GetInt(varPostgresConnectionRetryMax)
+}
+
 // GetPostgresTransactionTimeout returns the number of minutes to timeout a transaction
 func (c *Configuration) GetPostgresTransactionTimeout() time.Duration {
 	return c.v.GetDuration(varPostgresTransactionTimeout)
 }
 
+// GetPostgresTransactionIsolation returns the isolation level of the transactions
+func (c *Configuration) GetPostgresTransactionIsolation() string {
+	return c.v.GetString(varPostgresTransactionIsolation)
+}
+
+// GetPostgresTransactionRetryMax returns the number of times a transaction is retried after a serialization failure
+// or a deadlock
+func (c *Configuration) GetPostgresTransactionRetryMax() int {
+	return c.v.GetInt(varPostgresTransactionRetryMax)
+}
+
+// GetPostgresTransactionRetryBackoff returns the duration to wait before retrying a transaction for the first time
+func (c *Configuration) GetPostgresTransactionRetryBackoff() time.Duration {
+	return c.v.GetDuration(varPostgresTransactionBackoff)
+}
+
 // GetPostgresConnectionMaxIdle returns the number of connections that should be keept alive in the database connection pool at
 // any given time. -1 represents no restrictions/default behavior
 func (c *Configuration) GetPostgresConnectionMaxIdle() int {
@@ -210,7 +270,7 @@ func (c *Configuration) GetPostgresConnectionMaxOpen() int {
 
 // GetPostgresConfigString returns a ready to use string for usage in sql.Open()
 func (c *Configuration) GetPostgresConfigString() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=%d",
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=%d timezone=%s",
 		c.GetPostgresHost(),
 		c.GetPostgresPort(),
 		c.GetPostgresUser(),
@@ -218,19 +278,21 @@ func (c *Configuration) GetPostgresConfigString() string {
 		c.GetPostgresDatabase(),
 		c.GetPostgresSSLMode(),
 		c.GetPostgresConnectionTimeout(),
+		c.GetTimeZone(),
 	)
 }
 
 //GetPostgresAdminConfigString returns the settings for opening a new connection on a PostgreSQL server
 func (c *Configuration) GetPostgresAdminConfigString() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=%d",
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=%d timezone=%s",
 		c.GetPostgresHost(),
 		c.GetPostgresPort(),
 		c.GetPostgresSuperUser(),
 		c.GetPostgresAdminPassword(),
 		c.GetPostgresDatabase(),
 		c.GetPostgresSSLMode(),
-		c.GetPostgresConnectionTimeout())
+		c.GetPostgresConnectionTimeout(),
+		c.GetTimeZone())
 }
 
 // IsCleanTestDataEnabled returns `true` if the test data should be cleaned after each test. (default: true)
@@ -247,3 +309,43 @@ func (c *Configuration) IsDBLogsEnabled() bool {
 func (c *Configuration) GetLogLevel() string {
 	return c.v.GetString(varLogLevel)
 }
+
+// GetShutdownTimeout returns the maximum duration to complete the in-flight requests and the open transactions
+// when the server shuts down
+func (c *Configuration) GetShutdownTimeout() time.Duration {
+	return c.v.GetDuration(varShutdownTimeout)
+}
+
+// GetLapMinInterval returns the minimum duration between 2 laps of a team. A lap recorded sooner after the previous one
+// is rejected as a duplicate, eg: when a bib number is entered twice. (default: 0, i.e., no lap is rejected)
+func (c *Configuration) GetLapMinInterval() time.Duration {
+	return c.v.GetDuration(varLapMinInterval)
+}
+
+// IsPodiumExcludeScratchWinnersEnabled returns `true` if the teams on the scratch podium should be excluded
+// from the podiums of their category, since a team can only be awarded once. (default: false)
+func (c *Configuration) IsPodiumExcludeScratchWinnersEnabled() bool {
+	return c.v.GetBool(varPodiumExcludeScratchWinners)
+}
+
+// GetLocale returns the locale of the labels of the categories in the generated documents (default: fr)
+func (c *Configuration) GetLocale() string {
+	return c.v.GetString(varLocale)
+}
+
+// GetTimeZone returns the name of the time zone of the events, in which the times are displayed (default: Europe/Paris)
+func (c *Configuration) GetTimeZone() string {
+	return c.v.GetString(varTimeZone)
+}
+
+// GetGenderMapping returns the additional mapping of the gender values found in the imported files to the gender codes,
+// eg: "M=H,W=F". (default: empty, i.e., only the built-in values are recognized)
+func (c *Configuration) GetGenderMapping() string {
+	return c.v.GetString(varGenderMapping)
+}
+
+// GetGenderLabels returns the custom labels of the gender categories in the given locale, eg: "H=Messieurs,F=Dames"
+// for `STOPWATCH_GENDER_LABELS_FR`. (default: empty, i.e., the built-in labels are used)
+func (c *Configuration) GetGenderLabels(locale string) string {
+	return c.v.GetString(fmt.Sprintf("%s.%s", varGenderLabels, strings.ToLower(locale)))
+}
@@ -1,19 +1,39 @@
 package connection
 
 import (
+	"time"
+
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/vatriathlon/stopwatch/configuration"
 )
 
-// NewUserConnection returns a new database connection.
+// NewUserConnection returns a new database connection, with the configured pool limits. Retries to connect
+// (after the configured sleep) until the database is reachable or the configured number of attempts is reached.
 func NewUserConnection(config *configuration.Configuration) (*gorm.DB, error) {
 	logrus.Infof("Connecting to Postgres database using: host=`%s:%d` dbname=`%s` username=`%s`",
 		config.GetPostgresHost(), config.GetPostgresPort(), config.GetPostgresDatabase(), config.GetPostgresUser())
-	db, err := gorm.Open("postgres", config.GetPostgresConfigString())
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to open connection to database")
+	var db *gorm.DB
+	var err error
+	for attempt := 1; ; attempt++ {
+		db, err = gorm.Open("postgres", config.GetPostgresConfigString())
+		if err == nil {
+			break
+		}
+		if attempt >= config.GetPostgresConnectionRetryMax() {
+			return nil, errors.Wrapf(err, "failed to open connection to database after %d attempt(s)", attempt)
+		}
+		logrus.WithError(err).WithField("attempt", attempt).
+			Warnf("failed to open connection to database, retrying in %s...", config.GetPostgresConnectionRetrySleep())
+		time.Sleep(config.GetPostgresConnectionRetrySleep())
+	}
+	// negative values keep the defaults of the `database/sql` package
+	if maxIdle := config.GetPostgresConnectionMaxIdle(); maxIdle >= 0 {
+		db.DB().SetMaxIdleConns(maxIdle)
+	}
+	if maxOpen := config.GetPostgresConnectionMaxOpen(); maxOpen >= 0 {
+		db.DB().SetMaxOpenConns(maxOpen)
 	}
 	return db, nil
 }
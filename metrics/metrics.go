@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "stopwatch"
+
+// TransactionOutcome the outcome of a database transaction
+type TransactionOutcome string
+
+const (
+	// TransactionCommit the outcome of a committed transaction
+	TransactionCommit TransactionOutcome = "commit"
+	// TransactionRollback the outcome of a transaction which was rolled back because of an error
+	TransactionRollback TransactionOutcome = "rollback"
+	// TransactionTimeout the outcome of a transaction which was rolled back because it timed out
+	TransactionTimeout TransactionOutcome = "timeout"
+)
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "The duration of the HTTP requests, per route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	transactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "transaction_duration_seconds",
+		Help:      "The duration of the database transactions, per outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+
+	transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_total",
+		Help:      "The number of database transactions, per outcome (commit, rollback or timeout).",
+	}, []string{"outcome"})
+
+	transactionRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transaction_retries_total",
+		Help:      "The number of database transactions retried, per SQLSTATE of the error (eg: '40001' for serialization failures).",
+	}, []string{"code"})
+
+	lapsRecorded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "laps_recorded_total",
+		Help:      "The number of laps recorded, per race.",
+	}, []string{"race"})
+
+	lapsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "laps_rejected_total",
+		Help:      "The number of laps rejected, per race and reason (eg: 'team_not_found' or 'duplicate_lap').",
+	}, []string{"race", "reason"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		httpRequestDuration,
+		transactionDuration,
+		transactions,
+		transactionRetries,
+		lapsRecorded,
+		lapsRejected,
+	)
+}
+
+// Handler returns the HTTP handler which exposes the metrics to Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTPRequest records the duration of an HTTP request on the given route (i.e., the path template),
+// along with its response status
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveTransaction records the duration and the outcome of a database transaction
+func ObserveTransaction(outcome TransactionOutcome, duration time.Duration) {
+	transactionDuration.WithLabelValues(string(outcome)).Observe(duration.Seconds())
+	transactions.WithLabelValues(string(outcome)).Inc()
+}
+
+// RetryTransaction records a database transaction retried because of an error with the given SQLSTATE code
+func RetryTransaction(code string) {
+	transactionRetries.WithLabelValues(code).Inc()
+}
+
+// RecordLap records a lap in the given race
+func RecordLap(raceID int) {
+	lapsRecorded.WithLabelValues(strconv.Itoa(raceID)).Inc()
+}
+
+// UnknownRace the race of the laps rejected before their race was found, so the values of the `race` label are
+// limited to the existing races, whatever the requested race IDs
+const UnknownRace = "unknown"
+
+// RejectLap records a lap rejected in the given race (or `UnknownRace`), for the given reason
+func RejectLap(race string, reason string) {
+	lapsRejected.WithLabelValues(race, reason).Inc()
+}
@@ -0,0 +1,39 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	// given
+	metrics.RecordLap(42)
+	metrics.RecordLap(42)
+	metrics.RejectLap("42", "duplicate_lap")
+	metrics.RejectLap(metrics.UnknownRace, "team_not_found")
+	metrics.ObserveTransaction(metrics.TransactionCommit, 10*time.Millisecond)
+	metrics.ObserveTransaction(metrics.TransactionTimeout, time.Minute)
+	metrics.RetryTransaction("40001")
+	metrics.ObserveHTTPRequest(http.MethodPost, "/api/races/:raceID/bibnumber/:bibnumber/laps", http.StatusCreated, 20*time.Millisecond)
+	// when
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	// then
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `stopwatch_laps_recorded_total{race="42"} 2`)
+	assert.Contains(t, body, `stopwatch_laps_rejected_total{race="42",reason="duplicate_lap"} 1`)
+	assert.Contains(t, body, `stopwatch_laps_rejected_total{race="unknown",reason="team_not_found"} 1`)
+	assert.Contains(t, body, `stopwatch_transactions_total{outcome="commit"} 1`)
+	assert.Contains(t, body, `stopwatch_transactions_total{outcome="timeout"} 1`)
+	assert.Contains(t, body, `stopwatch_transaction_retries_total{code="40001"} 1`)
+	assert.Contains(t, body, `stopwatch_transaction_duration_seconds_count{outcome="commit"} 1`)
+	assert.Contains(t, body, `stopwatch_http_request_duration_seconds_count{code="201",method="POST",route="/api/races/:raceID/bibnumber/:bibnumber/laps"} 1`)
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Challenge a challenge of a race (eg: "Challenge Entreprise"), in which the teams are ranked separately
+// from the scratch. Each team takes part in at most one challenge.
+type Challenge struct {
+	ID     int    `gorm:"primary_key;column:challenge_id"`
+	RaceID int    `gorm:"column:race_id"`
+	Name   string `gorm:"column:name"`
+}
+
+const (
+	challengesTableName = "challenge"
+)
+
+// TableName implements gorm.tabler
+func (c Challenge) TableName() string {
+	return challengesTableName
+}
+
+// Ensure Challenge implements the Equaler interface
+var _ Equaler = Challenge{}
+var _ Equaler = (*Challenge)(nil)
+
+// Equal returns true if two Challenge objects are equal; otherwise false is returned.
+func (c Challenge) Equal(o Equaler) bool {
+	other, ok := o.(Challenge)
+	if !ok {
+		return false
+	}
+	return c.ID == other.ID
+}
+
+// ChallengeRepository provides functions to create and view the challenges of the races
+type ChallengeRepository interface {
+	Create(challenge *Challenge) error
+	List(raceID int) ([]Challenge, error)
+}
+
+// NewChallengeRepository creates a new GormChallengeRepository
+func NewChallengeRepository(db *gorm.DB) ChallengeRepository {
+	repository := &GormChallengeRepository{
+		db: db,
+	}
+	return repository
+}
+
+// GormChallengeRepository implements Repository using gorm
+type GormChallengeRepository struct {
+	db *gorm.DB
+}
+
+// Create creates a challenge in its race
+func (r *GormChallengeRepository) Create(challenge *Challenge) error {
+	// check values
+	if challenge == nil {
+		return NewValidationError(ErrCodeInvalidChallenge, "missing challenge to create")
+	}
+	if challenge.Name == "" {
+		return NewValidationError(ErrCodeInvalidChallenge, "challenge name is missing")
+	}
+	if challenge.RaceID == 0 {
+		return NewValidationError(ErrCodeInvalidChallenge, "challenge race is missing")
+	}
+	db := r.db.Create(challenge)
+	if err := db.Error; isUniqueViolation(err) {
+		return NewConflictError(ErrCodeDuplicateChallenge, "challenge '%s' already exists in race with id='%d'", challenge.Name, challenge.RaceID)
+	} else if err != nil {
+		return errors.Wrap(err, "fail to store challenge in DB")
+	}
+	return nil
+}
+
+// List lists the challenges of the given race, by name
+func (r *GormChallengeRepository) List(raceID int) ([]Challenge, error) {
+	result := make([]Challenge, 0)
+	db := r.db.Where("race_id = ?", raceID).Order("name ASC").Find(&result)
+	if err := db.Error; err != nil {
+		return result, errors.Wrap(err, "fail to list challenges")
+	}
+	return result, nil
+}
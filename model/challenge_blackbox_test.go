@@ -0,0 +1,117 @@
+package model_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	testmodel "github.com/vatriathlon/stopwatch/test/model"
+	testsuite "github.com/vatriathlon/stopwatch/test/suite"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestChallengeRepository(t *testing.T) {
+	config, err := configuration.New()
+	require.NoError(t, err)
+	suite.Run(t, &ChallengeRepositoryTestSuite{DBTestSuite: testsuite.NewDBTestSuite(config)})
+}
+
+type ChallengeRepositoryTestSuite struct {
+	testsuite.DBTestSuite
+}
+
+func (s *ChallengeRepositoryTestSuite) TestCreateChallenge() {
+	// given
+	challengeRepo := model.NewChallengeRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := model.NewRaceRepository(s.DB).Create(&race)
+	require.NoError(s.T(), err)
+
+	s.T().Run("ok", func(t *testing.T) {
+		// given
+		challenge := model.Challenge{
+			RaceID: race.ID,
+			Name:   "Challenge Entreprise",
+		}
+		// when
+		err := challengeRepo.Create(&challenge)
+		// then
+		require.NoError(t, err)
+		require.NotEqual(t, 0, challenge.ID)
+	})
+
+	s.T().Run("failure", func(t *testing.T) {
+
+		t.Run("missing name", func(t *testing.T) {
+			// when
+			err := challengeRepo.Create(&model.Challenge{RaceID: race.ID})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("missing race", func(t *testing.T) {
+			// when
+			err := challengeRepo.Create(&model.Challenge{Name: "Challenge Famille"})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("duplicate name", func(t *testing.T) {
+			// given
+			challenge := model.Challenge{
+				RaceID: race.ID,
+				Name:   "Challenge Scolaire",
+			}
+			err := challengeRepo.Create(&challenge)
+			require.NoError(t, err)
+			// when
+			err = challengeRepo.Create(&model.Challenge{RaceID: race.ID, Name: challenge.Name})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsConflictError(err))
+		})
+	})
+}
+
+func (s *ChallengeRepositoryTestSuite) TestListChallenges() {
+	// given 2 races with challenges
+	challengeRepo := model.NewChallengeRepository(s.DB)
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	races := []model.Race{}
+	for i := 0; i < 2; i++ {
+		race := model.Race{
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+		}
+		err := raceRepo.Create(&race)
+		require.NoError(s.T(), err)
+		races = append(races, race)
+	}
+	for _, c := range []model.Challenge{
+		{RaceID: races[0].ID, Name: "Challenge Scolaire"},
+		{RaceID: races[0].ID, Name: "Challenge Entreprise"},
+		{RaceID: races[1].ID, Name: "Challenge Famille"},
+	} {
+		err := challengeRepo.Create(&c)
+		require.NoError(s.T(), err)
+	}
+	// when
+	challenges, err := challengeRepo.List(races[0].ID)
+	// then
+	require.NoError(s.T(), err)
+	require.Len(s.T(), challenges, 2)
+	assert.Equal(s.T(), "Challenge Entreprise", challenges[0].Name)
+	assert.Equal(s.T(), "Challenge Scolaire", challenges[1].Name)
+}
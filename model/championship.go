@@ -0,0 +1,181 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// TeamMatching the way the results of the same team are matched across the races of a championship,
+// since the teams are registered again (with a new bib number) in each race
+type TeamMatching string
+
+const (
+	// TeamMatchingByName the teams with the same name are the same team
+	TeamMatchingByName TeamMatching = "name"
+	// TeamMatchingByMembers the teams with the same members (in any order) are the same team
+	TeamMatchingByMembers TeamMatching = "members"
+)
+
+// Championship a championship, in which the teams earn points in each race of a series, according to their rank
+type Championship struct {
+	ID   int    `gorm:"primary_key;column:championship_id"`
+	Name string `gorm:"column:name"`
+	// Points the points earned by the teams in each race, according to their scratch rank: the first team
+	// earns `Points[0]`, the second one `Points[1]`, etc. The teams ranked after the last entry earn no point.
+	Points pq.Int64Array `gorm:"column:points;type:integer[]"`
+	// BestOf the number of results of each team which count in the standings: only the results with the most points
+	// are counted. Zero means that all the results count.
+	BestOf       int          `gorm:"column:best_of"`
+	TeamMatching TeamMatching `gorm:"column:team_matching"`
+}
+
+const (
+	championshipsTableName     = "championship"
+	championshipRacesTableName = "championship_race"
+)
+
+// TableName implements gorm.tabler
+func (c Championship) TableName() string {
+	return championshipsTableName
+}
+
+// Ensure Championship implements the Equaler interface
+var _ Equaler = Championship{}
+var _ Equaler = (*Championship)(nil)
+
+// Equal returns true if two Championship objects are equal; otherwise false is returned.
+func (c Championship) Equal(o Equaler) bool {
+	other, ok := o.(Championship)
+	if !ok {
+		return false
+	}
+	return c.ID == other.ID
+}
+
+// PointsForRank returns the points earned by a team at the given rank
+func (c Championship) PointsForRank(rank int) int {
+	if rank < 1 || rank > len(c.Points) {
+		return 0
+	}
+	return int(c.Points[rank-1])
+}
+
+// championshipRace a race of a championship
+type championshipRace struct {
+	ChampionshipID int `gorm:"primary_key;column:championship_id"`
+	RaceID         int `gorm:"primary_key;column:race_id"`
+}
+
+// TableName implements gorm.tabler
+func (c championshipRace) TableName() string {
+	return championshipRacesTableName
+}
+
+// ChampionshipRepository provides functions to create and view championships, and to manage their races
+type ChampionshipRepository interface {
+	Create(championship *Championship) error
+	Lookup(id int) (Championship, error)
+	List() ([]Championship, error)
+	AddRace(championship Championship, race Race) error
+	ListRaces(championship Championship) ([]Race, error)
+}
+
+// NewChampionshipRepository creates a new GormChampionshipRepository
+func NewChampionshipRepository(db *gorm.DB) ChampionshipRepository {
+	repository := &GormChampionshipRepository{
+		db: db,
+	}
+	return repository
+}
+
+// GormChampionshipRepository implements Repository using gorm
+type GormChampionshipRepository struct {
+	db *gorm.DB
+}
+
+// Create creates a championship. The teams are matched by name unless specified otherwise
+func (r *GormChampionshipRepository) Create(championship *Championship) error {
+	// check values
+	if championship == nil {
+		return NewValidationError(ErrCodeInvalidChampionship, "missing championship to create")
+	}
+	if championship.Name == "" {
+		return NewValidationError(ErrCodeInvalidChampionship, "championship name is missing")
+	}
+	if len(championship.Points) == 0 {
+		return NewValidationError(ErrCodeInvalidChampionship, "championship points are missing")
+	}
+	for _, p := range championship.Points {
+		if p < 0 {
+			return NewValidationError(ErrCodeInvalidChampionship, "invalid championship points: %d", p)
+		}
+	}
+	if championship.BestOf < 0 {
+		return NewValidationError(ErrCodeInvalidChampionship, "invalid number of results counted in the championship: %d", championship.BestOf)
+	}
+	switch championship.TeamMatching {
+	case "":
+		championship.TeamMatching = TeamMatchingByName
+	case TeamMatchingByName, TeamMatchingByMembers:
+	default:
+		return NewValidationError(ErrCodeInvalidChampionship, "invalid team matching: '%s'", championship.TeamMatching)
+	}
+	db := r.db.Create(championship)
+	if err := db.Error; isUniqueViolation(err) {
+		return NewConflictError(ErrCodeDuplicateChampionship, "championship '%s' already exists", championship.Name)
+	} else if err != nil {
+		return errors.Wrap(err, "fail to store championship in DB")
+	}
+	return nil
+}
+
+// Lookup find the championship with its ID. Returns an error if none was found
+func (r *GormChampionshipRepository) Lookup(id int) (Championship, error) {
+	var result Championship
+	db := r.db.First(&result, "championship_id = ?", id)
+	if err := db.Error; isRecordNotFound(err) {
+		return result, NewNotFoundError("championship", id)
+	} else if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// List lists all championships, by name
+func (r *GormChampionshipRepository) List() ([]Championship, error) {
+	result := make([]Championship, 0)
+	db := r.db.Order("name ASC").Find(&result)
+	if err := db.Error; err != nil {
+		return result, errors.Wrap(err, "fail to list championships")
+	}
+	return result, nil
+}
+
+// AddRace adds the given race to the given championship
+func (r *GormChampionshipRepository) AddRace(championship Championship, race Race) error {
+	db := r.db.Create(&championshipRace{
+		ChampionshipID: championship.ID,
+		RaceID:         race.ID,
+	})
+	if err := db.Error; isUniqueViolation(err) {
+		return NewConflictError(ErrCodeDuplicateChampionshipRace, "race '%s' is already part of championship '%s'", race.Name, championship.Name)
+	} else if err != nil {
+		return errors.Wrap(err, "fail to add race to championship")
+	}
+	return nil
+}
+
+// ListRaces lists the races of the given championship, in the order of their event date
+func (r *GormChampionshipRepository) ListRaces(championship Championship) ([]Race, error) {
+	result := make([]Race, 0)
+	db := r.db.Joins("join championship_race cr on cr.race_id = race.race_id").
+		Joins("join event e on e.event_id = race.event_id").
+		Where("cr.championship_id = ?", championship.ID).
+		Order("e.date ASC, race.name ASC").
+		Find(&result)
+	if err := db.Error; err != nil {
+		return result, errors.Wrap(err, "fail to list championship races")
+	}
+	return result, nil
+}
@@ -0,0 +1,154 @@
+package model_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	testmodel "github.com/vatriathlon/stopwatch/test/model"
+	testsuite "github.com/vatriathlon/stopwatch/test/suite"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestChampionshipRepository(t *testing.T) {
+	config, err := configuration.New()
+	require.NoError(t, err)
+	suite.Run(t, &ChampionshipRepositoryTestSuite{DBTestSuite: testsuite.NewDBTestSuite(config)})
+}
+
+type ChampionshipRepositoryTestSuite struct {
+	testsuite.DBTestSuite
+}
+
+func (s *ChampionshipRepositoryTestSuite) TestCreateChampionship() {
+	// given
+	championshipRepo := model.NewChampionshipRepository(s.DB)
+
+	s.T().Run("ok", func(t *testing.T) {
+		// given
+		championship := model.Championship{
+			Name:   fmt.Sprintf("championship %s", uuid.NewV4()),
+			Points: []int64{10, 8, 6},
+			BestOf: 2,
+		}
+		// when
+		err := championshipRepo.Create(&championship)
+		// then
+		require.NoError(t, err)
+		require.NotEqual(t, 0, championship.ID)
+		result, err := championshipRepo.Lookup(championship.ID)
+		require.NoError(t, err)
+		assert.Equal(t, championship.Points, result.Points)
+		assert.Equal(t, 2, result.BestOf)
+		// teams are matched by name by default
+		assert.Equal(t, model.TeamMatchingByName, result.TeamMatching)
+	})
+
+	s.T().Run("failure", func(t *testing.T) {
+
+		t.Run("missing name", func(t *testing.T) {
+			// when
+			err := championshipRepo.Create(&model.Championship{Points: []int64{10}})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("missing points", func(t *testing.T) {
+			// when
+			err := championshipRepo.Create(&model.Championship{Name: fmt.Sprintf("championship %s", uuid.NewV4())})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("invalid team matching", func(t *testing.T) {
+			// when
+			err := championshipRepo.Create(&model.Championship{
+				Name:         fmt.Sprintf("championship %s", uuid.NewV4()),
+				Points:       []int64{10},
+				TeamMatching: "bib",
+			})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("duplicate name", func(t *testing.T) {
+			// given
+			championship := model.Championship{
+				Name:   fmt.Sprintf("championship %s", uuid.NewV4()),
+				Points: []int64{10},
+			}
+			err := championshipRepo.Create(&championship)
+			require.NoError(t, err)
+			// when
+			err = championshipRepo.Create(&model.Championship{Name: championship.Name, Points: []int64{10}})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsConflictError(err))
+		})
+	})
+}
+
+func (s *ChampionshipRepositoryTestSuite) TestLookupChampionship() {
+	// given
+	championshipRepo := model.NewChampionshipRepository(s.DB)
+	// when
+	_, err := championshipRepo.Lookup(0)
+	// then
+	require.Error(s.T(), err)
+	assert.True(s.T(), model.IsNotFoundError(err))
+}
+
+func (s *ChampionshipRepositoryTestSuite) TestChampionshipRaces() {
+	// given
+	championshipRepo := model.NewChampionshipRepository(s.DB)
+	raceRepo := model.NewRaceRepository(s.DB)
+	championship := model.Championship{
+		Name:   fmt.Sprintf("championship %s", uuid.NewV4()),
+		Points: []int64{10, 8, 6},
+	}
+	err := championshipRepo.Create(&championship)
+	require.NoError(s.T(), err)
+	races := []model.Race{}
+	for _, d := range []int{17, 3} {
+		event := testmodel.CreateEvent(s.T(), s.DB)
+		event.Date = event.Date.AddDate(0, 0, d)
+		require.NoError(s.T(), s.DB.Save(&event).Error)
+		race := model.Race{
+			EventID: event.ID,
+			Name:    "Bike & Run XS",
+		}
+		err := raceRepo.Create(&race)
+		require.NoError(s.T(), err)
+		races = append(races, race)
+	}
+
+	s.T().Run("add races", func(t *testing.T) {
+		// when
+		for _, race := range races {
+			err := championshipRepo.AddRace(championship, race)
+			require.NoError(t, err)
+		}
+		// then, in chronological order
+		result, err := championshipRepo.ListRaces(championship)
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, races[1].ID, result[0].ID)
+		assert.Equal(t, races[0].ID, result[1].ID)
+	})
+
+	s.T().Run("add race twice", func(t *testing.T) {
+		// when
+		err := championshipRepo.AddRace(championship, races[0])
+		// then
+		require.Error(t, err)
+		assert.True(t, model.IsConflictError(err))
+	})
+}
@@ -0,0 +1,198 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// ErrorCode a stable code which identifies an error, so the clients can translate it
+type ErrorCode string
+
+const (
+	// ErrCodeInvalidEvent the code of the error returned when an event is invalid
+	ErrCodeInvalidEvent ErrorCode = "invalid_event"
+	// ErrCodeInvalidRace the code of the error returned when a race is invalid
+	ErrCodeInvalidRace ErrorCode = "invalid_race"
+	// ErrCodeInvalidTeam the code of the error returned when a team is invalid
+	ErrCodeInvalidTeam ErrorCode = "invalid_team"
+	// ErrCodeInvalidChallenge the code of the error returned when a challenge is invalid
+	ErrCodeInvalidChallenge ErrorCode = "invalid_challenge"
+	// ErrCodeInvalidChampionship the code of the error returned when a championship is invalid
+	ErrCodeInvalidChampionship ErrorCode = "invalid_championship"
+	// ErrCodeInvalidLap the code of the error returned when a lap is invalid
+	ErrCodeInvalidLap ErrorCode = "invalid_lap"
+	// ErrCodeDuplicateEvent the code of the error returned when an event with the same name already exists at the same date
+	ErrCodeDuplicateEvent ErrorCode = "duplicate_event"
+	// ErrCodeDuplicateRace the code of the error returned when a race with the same name already exists in the event
+	ErrCodeDuplicateRace ErrorCode = "duplicate_race"
+	// ErrCodeDuplicateTeam the code of the error returned when a team with the same bib number already exists in the race
+	ErrCodeDuplicateTeam ErrorCode = "duplicate_team"
+	// ErrCodeDuplicateChallenge the code of the error returned when a challenge with the same name already exists in the race
+	ErrCodeDuplicateChallenge ErrorCode = "duplicate_challenge"
+	// ErrCodeDuplicateChampionship the code of the error returned when a championship with the same name already exists
+	ErrCodeDuplicateChampionship ErrorCode = "duplicate_championship"
+	// ErrCodeDuplicateChampionshipRace the code of the error returned when a race is added twice to a championship
+	ErrCodeDuplicateChampionshipRace ErrorCode = "duplicate_championship_race"
+	// ErrCodeDuplicateLap the code of the error returned when a lap is recorded too soon after the previous lap of the team
+	ErrCodeDuplicateLap ErrorCode = "duplicate_lap"
+	// ErrCodeRaceAlreadyStarted the code of the error returned when a race is started twice, or when its planned
+	// start is changed after it started
+	ErrCodeRaceAlreadyStarted ErrorCode = "race_already_started"
+	// ErrCodeRaceNotStarted the code of the error returned when a race which has not started yet is ended
+	ErrCodeRaceNotStarted ErrorCode = "race_not_started"
+	// ErrCodeRaceAlreadyEnded the code of the error returned when a race which already ended is changed
+	ErrCodeRaceAlreadyEnded ErrorCode = "race_already_ended"
+	// ErrCodeFirstLapAlreadyRecorded the code of the error returned when the first lap option of a race is changed
+	// after the first lap was recorded
+	ErrCodeFirstLapAlreadyRecorded ErrorCode = "first_lap_already_recorded"
+	// ErrCodeFirstLapNotAllowed the code of the error returned when the first lap cannot be recorded for all teams
+	ErrCodeFirstLapNotAllowed ErrorCode = "first_lap_not_allowed"
+	// ErrCodeNoLap the code of the error returned when the last lap of a team without any lap is deleted
+	ErrCodeNoLap ErrorCode = "no_lap"
+)
+
+// CodedError an error with a stable code
+type CodedError interface {
+	error
+	Code() ErrorCode
+}
+
+// NotFoundError the error returned when an entity does not exist
+type NotFoundError struct {
+	// Entity the kind of entity, eg: "race" or "team"
+	Entity string
+	// Key the key used to look-up the entity (its ID, bib number, name, etc.)
+	Key interface{}
+}
+
+// NewNotFoundError returns a new NotFoundError
+func NewNotFoundError(entity string, key interface{}) NotFoundError {
+	return NotFoundError{
+		Entity: entity,
+		Key:    key,
+	}
+}
+
+// Error implements error
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("%s '%v' not found", e.Entity, e.Key)
+}
+
+// Code implements CodedError
+func (e NotFoundError) Code() ErrorCode {
+	return ErrorCode(fmt.Sprintf("%s_not_found", e.Entity))
+}
+
+// ValidationError the error returned when an entity or a request is invalid
+type ValidationError struct {
+	code ErrorCode
+	msg  string
+}
+
+// NewValidationError returns a new ValidationError
+func NewValidationError(code ErrorCode, format string, args ...interface{}) ValidationError {
+	return ValidationError{
+		code: code,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Error implements error
+func (e ValidationError) Error() string {
+	return e.msg
+}
+
+// Code implements CodedError
+func (e ValidationError) Code() ErrorCode {
+	return e.code
+}
+
+// ConflictError the error returned when an entity conflicts with another one
+type ConflictError struct {
+	code ErrorCode
+	msg  string
+}
+
+// NewConflictError returns a new ConflictError
+func NewConflictError(code ErrorCode, format string, args ...interface{}) ConflictError {
+	return ConflictError{
+		code: code,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Error implements error
+func (e ConflictError) Error() string {
+	return e.msg
+}
+
+// Code implements CodedError
+func (e ConflictError) Code() ErrorCode {
+	return e.code
+}
+
+// StateError the error returned when an operation is not allowed given the current state of an entity,
+// eg: starting a race which already started
+type StateError struct {
+	code ErrorCode
+	msg  string
+}
+
+// NewStateError returns a new StateError
+func NewStateError(code ErrorCode, format string, args ...interface{}) StateError {
+	return StateError{
+		code: code,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Error implements error
+func (e StateError) Error() string {
+	return e.msg
+}
+
+// Code implements CodedError
+func (e StateError) Code() ErrorCode {
+	return e.code
+}
+
+// IsNotFoundError returns true if the cause of the given error is a NotFoundError
+func IsNotFoundError(err error) bool {
+	_, ok := errors.Cause(err).(NotFoundError)
+	return ok
+}
+
+// IsValidationError returns true if the cause of the given error is a ValidationError
+func IsValidationError(err error) bool {
+	_, ok := errors.Cause(err).(ValidationError)
+	return ok
+}
+
+// IsConflictError returns true if the cause of the given error is a ConflictError
+func IsConflictError(err error) bool {
+	_, ok := errors.Cause(err).(ConflictError)
+	return ok
+}
+
+// IsStateError returns true if the cause of the given error is a StateError
+func IsStateError(err error) bool {
+	_, ok := errors.Cause(err).(StateError)
+	return ok
+}
+
+// uniqueViolation the SQLSTATE of the errors raised by Postgres when a unique constraint is violated
+const uniqueViolation = "23505"
+
+// isUniqueViolation returns true if the given error was raised because of a unique constraint violation
+func isUniqueViolation(err error) bool {
+	e, ok := errors.Cause(err).(*pq.Error)
+	return ok && e.Code == uniqueViolation
+}
+
+// isRecordNotFound returns true if the given error was raised because no record was found
+func isRecordNotFound(err error) bool {
+	return gorm.IsRecordNotFoundError(errors.Cause(err))
+}
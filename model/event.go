@@ -0,0 +1,103 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Event an event (eg: a yearly edition of the run&bike), which takes place at a given date and venue,
+// and during which several races are organized
+type Event struct {
+	ID    int       `gorm:"primary_key;column:event_id"`
+	Name  string    `gorm:"column:name"`
+	Date  time.Time `gorm:"column:date"`
+	Venue string    `gorm:"column:venue"`
+}
+
+const (
+	eventsTableName = "event"
+	eventDateFmt    = "2006-01-02"
+)
+
+// TableName implements gorm.tabler
+func (e Event) TableName() string {
+	return eventsTableName
+}
+
+// Ensure Event implements the Equaler interface
+var _ Equaler = Event{}
+var _ Equaler = (*Event)(nil)
+
+// Equal returns true if two Event objects are equal; otherwise false is returned.
+func (e Event) Equal(o Equaler) bool {
+	other, ok := o.(Event)
+	if !ok {
+		return false
+	}
+	return e.ID == other.ID
+}
+
+// EventRepository provides functions to create and view events
+type EventRepository interface {
+	Create(event *Event) error
+	Lookup(id int) (Event, error)
+	List() ([]Event, error)
+}
+
+// NewEventRepository creates a new GormEventRepository
+func NewEventRepository(db *gorm.DB) EventRepository {
+	repository := &GormEventRepository{
+		db: db,
+	}
+	return repository
+}
+
+// GormEventRepository implements Repository using gorm
+type GormEventRepository struct {
+	db *gorm.DB
+}
+
+// Create creates an event
+func (r *GormEventRepository) Create(event *Event) error {
+	// check values
+	if event == nil {
+		return NewValidationError(ErrCodeInvalidEvent, "missing event to create")
+	}
+	if event.Name == "" {
+		return NewValidationError(ErrCodeInvalidEvent, "event name is missing")
+	}
+	if event.Date.IsZero() {
+		return NewValidationError(ErrCodeInvalidEvent, "event date is missing")
+	}
+	db := r.db.Create(event)
+	if err := db.Error; isUniqueViolation(err) {
+		return NewConflictError(ErrCodeDuplicateEvent, "event '%s' already exists on %s", event.Name, event.Date.Format(eventDateFmt))
+	} else if err != nil {
+		return errors.Wrap(err, "fail to store event in DB")
+	}
+	return nil
+}
+
+// Lookup find the event with its ID. Returns an error if none was found
+func (r *GormEventRepository) Lookup(id int) (Event, error) {
+	var result Event
+	db := r.db.First(&result, "event_id = ?", id)
+	if err := db.Error; isRecordNotFound(err) {
+		return result, NewNotFoundError("event", id)
+	} else if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// List lists all events, from the most recent to the oldest one
+func (r *GormEventRepository) List() ([]Event, error) {
+	result := make([]Event, 0)
+	db := r.db.Order("date DESC, name ASC").Find(&result)
+	if err := db.Error; err != nil {
+		return result, errors.Wrap(err, "fail to list events")
+	}
+	return result, nil
+}
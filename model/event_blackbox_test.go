@@ -0,0 +1,142 @@
+package model_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	testsuite "github.com/vatriathlon/stopwatch/test/suite"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestEventRepository(t *testing.T) {
+	config, err := configuration.New()
+	require.NoError(t, err)
+	suite.Run(t, &EventRepositoryTestSuite{DBTestSuite: testsuite.NewDBTestSuite(config)})
+}
+
+type EventRepositoryTestSuite struct {
+	testsuite.DBTestSuite
+}
+
+func (s *EventRepositoryTestSuite) TestCreateEvent() {
+	// given
+	eventRepo := model.NewEventRepository(s.DB)
+	date := time.Date(2019, 11, 17, 0, 0, 0, 0, time.UTC)
+
+	s.T().Run("ok", func(t *testing.T) {
+		// given
+		event := model.Event{
+			Name:  fmt.Sprintf("event %s", uuid.NewV4()),
+			Date:  date,
+			Venue: "Vatry",
+		}
+		// when
+		err := eventRepo.Create(&event)
+		// then
+		require.NoError(t, err)
+		require.NotEqual(t, 0, event.ID)
+	})
+
+	s.T().Run("same name another year", func(t *testing.T) {
+		// given
+		event := model.Event{
+			Name: fmt.Sprintf("event %s", uuid.NewV4()),
+			Date: date,
+		}
+		err := eventRepo.Create(&event)
+		require.NoError(t, err)
+		// when
+		err = eventRepo.Create(&model.Event{Name: event.Name, Date: date.AddDate(1, 0, 0)})
+		// then
+		require.NoError(t, err)
+	})
+
+	s.T().Run("failure", func(t *testing.T) {
+
+		t.Run("missing name", func(t *testing.T) {
+			// when
+			err := eventRepo.Create(&model.Event{Date: date})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("missing date", func(t *testing.T) {
+			// when
+			err := eventRepo.Create(&model.Event{Name: fmt.Sprintf("event %s", uuid.NewV4())})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("duplicate name and date", func(t *testing.T) {
+			// given
+			event := model.Event{
+				Name: fmt.Sprintf("event %s", uuid.NewV4()),
+				Date: date,
+			}
+			err := eventRepo.Create(&event)
+			require.NoError(t, err)
+			// when
+			err = eventRepo.Create(&model.Event{Name: event.Name, Date: date})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsConflictError(err))
+		})
+	})
+}
+
+func (s *EventRepositoryTestSuite) TestLookupEvent() {
+	// given
+	eventRepo := model.NewEventRepository(s.DB)
+
+	s.T().Run("ok", func(t *testing.T) {
+		// given
+		event := model.Event{
+			Name: fmt.Sprintf("event %s", uuid.NewV4()),
+			Date: time.Date(2019, 11, 17, 0, 0, 0, 0, time.UTC),
+		}
+		err := eventRepo.Create(&event)
+		require.NoError(t, err)
+		// when
+		result, err := eventRepo.Lookup(event.ID)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, event.Name, result.Name)
+	})
+
+	s.T().Run("no match", func(t *testing.T) {
+		// when
+		_, err := eventRepo.Lookup(0)
+		// then
+		require.Error(t, err)
+		assert.True(t, model.IsNotFoundError(err))
+	})
+}
+
+func (s *EventRepositoryTestSuite) TestListEvents() {
+	// given
+	eventRepo := model.NewEventRepository(s.DB)
+	for _, year := range []int{2018, 2019} {
+		event := model.Event{
+			Name: fmt.Sprintf("event %s", uuid.NewV4()),
+			Date: time.Date(year, 11, 17, 0, 0, 0, 0, time.UTC),
+		}
+		err := eventRepo.Create(&event)
+		require.NoError(s.T(), err)
+	}
+	// when
+	events, err := eventRepo.List()
+	// then
+	require.NoError(s.T(), err)
+	require.True(s.T(), len(events) >= 2)
+	// most recent first
+	assert.False(s.T(), events[0].Date.Before(events[1].Date))
+}
@@ -24,6 +24,17 @@ func (l Lap) TableName() string {
 	return lapsTableName
 }
 
+// AfterFind expresses the time of the lap in the time zone of the events (implements gorm callback)
+func (l *Lap) AfterFind() error {
+	l.Time = LocalTime(l.Time)
+	return nil
+}
+
+// AfterSave expresses the time of the lap in the time zone of the events (implements gorm callback)
+func (l *Lap) AfterSave() error {
+	return l.AfterFind()
+}
+
 // Ensure Lap implements the Equaler interface
 var _ Equaler = Lap{}
 var _ Equaler = (*Lap)(nil)
@@ -40,6 +51,8 @@ func (l Lap) Equal(o Equaler) bool {
 // LapRepository provides functions to create and view team laps
 type LapRepository interface {
 	Create(lap *Lap) error
+	List(raceID int) ([]Lap, error)
+	Delete(lap Lap) error
 }
 
 // NewLapRepository creates a new GormLapRepository
@@ -59,13 +72,13 @@ type GormLapRepository struct {
 func (r *GormLapRepository) Create(lap *Lap) error {
 	// check values
 	if lap == nil {
-		return errors.New("missing lap to create")
+		return NewValidationError(ErrCodeInvalidLap, "missing lap to create")
 	}
 	if lap.RaceID == 0 {
-		return errors.New("missing 'RaceID' field")
+		return NewValidationError(ErrCodeInvalidLap, "missing 'RaceID' field")
 	}
 	if lap.TeamID == 0 {
-		return errors.New("missing 'TeamID' field")
+		return NewValidationError(ErrCodeInvalidLap, "missing 'TeamID' field")
 	}
 	db := r.db.Create(lap)
 	if err := db.Error; err != nil {
@@ -73,3 +86,25 @@ func (r *GormLapRepository) Create(lap *Lap) error {
 	}
 	return nil
 }
+
+// List lists all laps in the given race, ordered by team and time
+func (r *GormLapRepository) List(raceID int) ([]Lap, error) {
+	result := make([]Lap, 0)
+	db := r.db.Where("race_id = ?", raceID).Order("team_id ASC, time ASC").Find(&result)
+	if err := db.Error; err != nil {
+		return result, errors.Wrap(err, "fail to list laps")
+	}
+	return result, nil
+}
+
+// Delete deletes the given lap
+func (r *GormLapRepository) Delete(lap Lap) error {
+	db := r.db.Where("lap_id = ?", lap.ID).Delete(Lap{})
+	if err := db.Error; err != nil {
+		return errors.Wrap(err, "fail to delete lap")
+	}
+	if db.RowsAffected == 0 {
+		return NewNotFoundError("lap", lap.ID)
+	}
+	return nil
+}
@@ -29,11 +29,13 @@ type LapRepositoryTestSuite struct {
 func (s *LapRepositoryTestSuite) TestCreateLap() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	teamRepo := model.NewTeamRepository(s.DB)
 	lapRepo := model.NewLapRepository(s.DB)
 	now := time.Now()
 	race := model.Race{
-		Name: fmt.Sprintf("race-%s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race-%s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race)
 	require.NoError(s.T(), err)
@@ -87,3 +89,43 @@ func (s *LapRepositoryTestSuite) TestCreateLap() {
 		})
 	})
 }
+
+func (s *LapRepositoryTestSuite) TestListLaps() {
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	teamRepo := model.NewTeamRepository(s.DB)
+	lapRepo := model.NewLapRepository(s.DB)
+	now := time.Now()
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race-%s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	team1 := testmodel.NewTeam(race.ID, 1)
+	err = teamRepo.Create(&team1)
+	require.NoError(s.T(), err)
+	team2 := testmodel.NewTeam(race.ID, 2)
+	err = teamRepo.Create(&team2)
+	require.NoError(s.T(), err)
+	for _, l := range []model.Lap{
+		{RaceID: race.ID, TeamID: team2.ID, Time: now.Add(2 * time.Minute)},
+		{RaceID: race.ID, TeamID: team1.ID, Time: now.Add(3 * time.Minute)},
+		{RaceID: race.ID, TeamID: team1.ID, Time: now.Add(1 * time.Minute)},
+	} {
+		lap := l
+		err := lapRepo.Create(&lap)
+		require.NoError(s.T(), err)
+	}
+	// when
+	laps, err := lapRepo.List(race.ID)
+	// then
+	require.NoError(s.T(), err)
+	require.Len(s.T(), laps, 3)
+	// verify result ordering
+	assert.Equal(s.T(), team1.ID, laps[0].TeamID)
+	assert.True(s.T(), laps[0].Time.Before(laps[1].Time))
+	assert.Equal(s.T(), team1.ID, laps[1].TeamID)
+	assert.Equal(s.T(), team2.ID, laps[2].TeamID)
+}
@@ -0,0 +1,224 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// migrations the SQL statements to migrate the database schema, in order: the statements at index `i` migrate
+// the schema from version `i` to version `i+1`. New migrations must be appended, never modified once released.
+var migrations = [...]string{
+	// version 1: initial schema
+	`-- schema version (updated after each migration)
+CREATE TABLE schema_version (
+    version int NOT NULL
+);
+insert into schema_version(version) values (0);
+
+-- races
+CREATE TABLE race (
+    race_id serial primary key,
+    name varchar NOT NULL CHECK (name <> ''),
+    start_time timestamp,
+    end_time timestamp,
+    planned_start_time timestamp,
+    allows_first_lap boolean default false,
+    has_first_lap boolean default false
+);
+
+-- index to query event type by name, which must be unique
+CREATE UNIQUE INDEX uix_race_name ON race USING btree (name);
+
+-- teams
+CREATE TABLE team (
+    team_id serial primary key,
+    race_id int NOT NULL,
+    bib_number int NOT NULL CHECK (bib_number > 0),
+    name varchar NOT NULL CHECK (name <> ''),
+    gender varchar(1) NOT NULL CHECK (gender <> ''),
+    challenge varchar NOT NULL,
+    age_category varchar NOT NULL CHECK (age_category <> ''),
+    member1_first_name varchar NOT NULL CHECK (member1_first_name <> ''),
+    member1_last_name varchar NOT NULL CHECK (member1_last_name <> ''),
+    member1_date_of_birth date NOT NULL CHECK (member1_last_name <> ''),
+    member1_age_category varchar NOT NULL CHECK (member1_age_category <> ''),
+    member1_gender varchar(1) NOT NULL CHECK (member1_date_of_birth > '0001-01-01 00:00:00'),
+    member1_club varchar,
+    member2_first_name varchar NOT NULL CHECK (member2_first_name <> ''),
+    member2_last_name varchar NOT NULL CHECK (member2_last_name <> ''),
+    member2_date_of_birth date NOT NULL CHECK (member2_date_of_birth > '0001-01-01 00:00:00'),
+    member2_age_category varchar NOT NULL CHECK (member2_age_category <> ''),
+    member2_gender varchar(1) NOT NULL CHECK (member2_gender <> ''),
+    member2_club varchar
+);
+
+-- Add a foreign key constraint to race
+ALTER TABLE team add constraint team_race_fk foreign key (race_id) REFERENCES race (race_id);
+CREATE UNIQUE INDEX uix_team_bibnumber ON team USING btree (race_id, bib_number);
+
+-- laps
+CREATE TABLE lap (
+    lap_id serial primary key,
+    time timestamp NOT NULL CHECK (time > '0001-01-01 00:00:00'),
+    race_id int NOT NULL,
+    team_id int NOT NULL
+);
+
+-- Add a foreign key constraint to race
+ALTER TABLE team add constraint lap_race_fk foreign key (race_id) REFERENCES race (race_id);
+-- Add a foreign key constraint to team
+ALTER TABLE team add constraint lap_team_fk foreign key (team_id) REFERENCES team (team_id);`,
+	// version 2: events, which own their races
+	`CREATE TABLE event (
+    event_id serial primary key,
+    name varchar NOT NULL CHECK (name <> ''),
+    date date NOT NULL,
+    venue varchar NOT NULL default ''
+);
+
+-- index to query events by name and date, which must be unique
+CREATE UNIQUE INDEX uix_event_name_date ON event USING btree (name, date);
+
+-- existing races are moved into a single event
+ALTER TABLE race ADD COLUMN event_id int;
+INSERT INTO event(name, date)
+    SELECT 'Bike & Run', coalesce(min(coalesce(start_time, planned_start_time))::date, current_date) FROM race
+    HAVING count(*) > 0;
+UPDATE race SET event_id = (SELECT min(event_id) FROM event);
+ALTER TABLE race ALTER COLUMN event_id SET NOT NULL;
+ALTER TABLE race add constraint race_event_fk foreign key (event_id) REFERENCES event (event_id);
+
+-- the name of a race is unique in its event
+DROP INDEX uix_race_name;
+CREATE UNIQUE INDEX uix_race_event_name ON race USING btree (event_id, name);`,
+	// version 3: championships, which rank the teams over a series of races
+	`CREATE TABLE championship (
+    championship_id serial primary key,
+    name varchar NOT NULL CHECK (name <> ''),
+    points integer[] NOT NULL,
+    best_of int NOT NULL default 0 CHECK (best_of >= 0),
+    team_matching varchar NOT NULL default 'name'
+);
+
+-- index to query championships by name, which must be unique
+CREATE UNIQUE INDEX uix_championship_name ON championship USING btree (name);
+
+CREATE TABLE championship_race (
+    championship_id int NOT NULL REFERENCES championship (championship_id),
+    race_id int NOT NULL REFERENCES race (race_id),
+    primary key (championship_id, race_id)
+);`,
+	// version 4: challenges, which are declared in each race
+	`CREATE TABLE challenge (
+    challenge_id serial primary key,
+    race_id int NOT NULL REFERENCES race (race_id),
+    name varchar NOT NULL CHECK (name <> '')
+);
+
+-- index to query challenges by race and name, which must be unique in the race
+CREATE UNIQUE INDEX uix_challenge_race_name ON challenge USING btree (race_id, name);
+
+-- the challenges of the existing teams are declared in their race
+INSERT INTO challenge(race_id, name)
+    SELECT DISTINCT race_id, challenge FROM team WHERE challenge <> '';`,
+	// version 5: gender categories of each race (men, women and mixed by default)
+	`ALTER TABLE race ADD COLUMN genders varchar(1)[] NOT NULL DEFAULT '{H,F,M}';`,
+	// version 6: times stored in UTC
+	`-- the existing times were recorded in the local time of the event, which is the time zone of the session
+ALTER TABLE race ALTER COLUMN start_time TYPE timestamptz,
+    ALTER COLUMN end_time TYPE timestamptz,
+    ALTER COLUMN planned_start_time TYPE timestamptz;
+ALTER TABLE lap ALTER COLUMN time TYPE timestamptz;`,
+	// version 7: the constraints which were misplaced in the initial schema
+	`-- the foreign keys of the laps were declared on the team table (where the team referenced itself)
+ALTER TABLE team DROP CONSTRAINT IF EXISTS lap_race_fk,
+    DROP CONSTRAINT IF EXISTS lap_team_fk;
+ALTER TABLE lap DROP CONSTRAINT IF EXISTS lap_race_fk,
+    DROP CONSTRAINT IF EXISTS lap_team_fk,
+    ADD CONSTRAINT lap_race_fk foreign key (race_id) REFERENCES race (race_id),
+    ADD CONSTRAINT lap_team_fk foreign key (team_id) REFERENCES team (team_id);
+
+-- the check of the date of birth of the first member was declared on its gender (and vice versa)
+ALTER TABLE team DROP CONSTRAINT IF EXISTS team_member1_date_of_birth_check,
+    DROP CONSTRAINT IF EXISTS team_member1_gender_check,
+    ADD CONSTRAINT team_member1_date_of_birth_check CHECK (member1_date_of_birth > '0001-01-01 00:00:00'),
+    ADD CONSTRAINT team_member1_gender_check CHECK (member1_gender <> '');`,
+	// version 8: the data which was wrongly migrated by the previous versions
+	`-- the times of the races which did not start (or end) were recorded as '0001-01-01 00:00:00', which is not the
+-- zero time in UTC once converted from the time zone of the session, so they are cleared
+UPDATE race SET start_time = NULL WHERE start_time < '0001-01-02 00:00:00+00'::timestamptz;
+UPDATE race SET end_time = NULL WHERE end_time < '0001-01-02 00:00:00+00'::timestamptz;
+UPDATE race SET planned_start_time = NULL WHERE planned_start_time < '0001-01-02 00:00:00+00'::timestamptz;
+ALTER TABLE lap DROP CONSTRAINT lap_time_check,
+    ADD CONSTRAINT lap_time_check CHECK (time > '0001-01-01 00:00:00+00'::timestamptz);
+
+-- the event of the existing races was dated by the races which did not start
+UPDATE event SET date = coalesce((SELECT min(coalesce(start_time, planned_start_time))::date FROM race
+        WHERE race.event_id = event.event_id), current_date)
+    WHERE date = '0001-01-01';
+
+-- the existing teams which did not take part in a challenge were registered in "open"
+UPDATE team SET challenge = '' WHERE lower(trim(challenge)) = 'open';
+DELETE FROM challenge WHERE lower(trim(name)) = 'open';`,
+}
+
+// Migrate applies the missing migrations to the given database, each one in its own transaction,
+// and returns the resulting version of the schema
+func Migrate(ctx context.Context, db *sql.DB) (int, error) {
+	version, err := currentSchemaVersion(ctx, db)
+	if err != nil {
+		return version, err
+	}
+	for ; version < len(migrations); version++ {
+		err := migrate(ctx, db, version+1, migrations[version])
+		if err != nil {
+			return version, err
+		}
+	}
+	return version, nil
+}
+
+// currentSchemaVersion returns the version of the schema of the given database, or 0 if the schema
+// does not exist yet. The databases created before the migrations (which have the `race` table but no
+// `schema_version` table) are stamped with the version of the initial schema, once they have its
+// `planned_start_time` column.
+func currentSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var versioned, baseline bool
+	err := db.QueryRowContext(ctx, "select to_regclass('schema_version') is not null, to_regclass('race') is not null").
+		Scan(&versioned, &baseline)
+	if err != nil {
+		return -1, errors.Wrap(err, "fail to check schema version")
+	}
+	switch {
+	case versioned:
+		return LoadSchemaVersion(ctx, db)
+	case baseline:
+		_, err := db.ExecContext(ctx, `ALTER TABLE race ADD COLUMN IF NOT EXISTS planned_start_time timestamp;
+CREATE TABLE schema_version (version int NOT NULL);
+insert into schema_version(version) values (1);`)
+		if err != nil {
+			return -1, errors.Wrap(err, "fail to stamp the initial schema version")
+		}
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func migrate(ctx context.Context, db *sql.DB, version int, statements string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "fail to migrate schema to version %d", version)
+	}
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "fail to migrate schema to version %d", version)
+	}
+	if _, err := tx.ExecContext(ctx, "update schema_version set version = $1", version); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "fail to migrate schema to version %d", version)
+	}
+	return errors.Wrapf(tx.Commit(), "fail to migrate schema to version %d", version)
+}
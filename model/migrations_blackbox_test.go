@@ -0,0 +1,181 @@
+package model_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	testsuite "github.com/vatriathlon/stopwatch/test/suite"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMigrations(t *testing.T) {
+	config, err := configuration.New()
+	require.NoError(t, err)
+	suite.Run(t, &MigrationsTestSuite{DBTestSuite: testsuite.NewDBTestSuite(config), config: config})
+}
+
+type MigrationsTestSuite struct {
+	testsuite.DBTestSuite
+	config *configuration.Configuration
+}
+
+// legacySchema the schema of the databases created before the migrations (without the `schema_version` table)
+const legacySchema = `CREATE TABLE race (
+    race_id serial primary key,
+    name varchar NOT NULL CHECK (name <> ''),
+    start_time timestamp,
+    end_time timestamp,
+    allows_first_lap boolean default false,
+    has_first_lap boolean default false
+);
+CREATE UNIQUE INDEX uix_race_name ON race USING btree (name);
+CREATE TABLE team (
+    team_id serial primary key,
+    race_id int NOT NULL,
+    bib_number int NOT NULL CHECK (bib_number > 0),
+    name varchar NOT NULL CHECK (name <> ''),
+    gender varchar(1) NOT NULL CHECK (gender <> ''),
+    challenge varchar NOT NULL,
+    age_category varchar NOT NULL CHECK (age_category <> ''),
+    member1_first_name varchar NOT NULL CHECK (member1_first_name <> ''),
+    member1_last_name varchar NOT NULL CHECK (member1_last_name <> ''),
+    member1_date_of_birth date NOT NULL CHECK (member1_last_name <> ''),
+    member1_age_category varchar NOT NULL CHECK (member1_age_category <> ''),
+    member1_gender varchar(1) NOT NULL CHECK (member1_date_of_birth > '0001-01-01 00:00:00'),
+    member1_club varchar,
+    member2_first_name varchar NOT NULL CHECK (member2_first_name <> ''),
+    member2_last_name varchar NOT NULL CHECK (member2_last_name <> ''),
+    member2_date_of_birth date NOT NULL CHECK (member2_date_of_birth > '0001-01-01 00:00:00'),
+    member2_age_category varchar NOT NULL CHECK (member2_age_category <> ''),
+    member2_gender varchar(1) NOT NULL CHECK (member2_gender <> ''),
+    member2_club varchar
+);
+ALTER TABLE team add constraint team_race_fk foreign key (race_id) REFERENCES race (race_id);
+CREATE UNIQUE INDEX uix_team_bibnumber ON team USING btree (race_id, bib_number);
+CREATE TABLE lap (
+    lap_id serial primary key,
+    time timestamp NOT NULL CHECK (time > '0001-01-01 00:00:00'),
+    race_id int NOT NULL,
+    team_id int NOT NULL
+);
+ALTER TABLE team add constraint lap_race_fk foreign key (race_id) REFERENCES race (race_id);
+ALTER TABLE team add constraint lap_team_fk foreign key (team_id) REFERENCES team (team_id);`
+
+// newSchema returns a connection to a new (empty) schema, and a function to drop it. The time zone of the
+// session is the one in which the times were recorded before they were stored in UTC.
+func (s *MigrationsTestSuite) newSchema() (*sql.DB, func()) {
+	name := "migrations_" + strings.Replace(uuid.NewV4().String(), "-", "", -1)
+	err := s.DB.Exec(fmt.Sprintf("CREATE SCHEMA %s", name)).Error
+	require.NoError(s.T(), err)
+	db, err := sql.Open("postgres", fmt.Sprintf("%s search_path=%s timezone=Europe/Paris", s.config.GetPostgresConfigString(), name))
+	require.NoError(s.T(), err)
+	return db, func() {
+		db.Close()
+		s.DB.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", name))
+	}
+}
+
+// constraintTables returns the tables on which the constraint with the given name is declared
+func constraintTables(t *testing.T, db *sql.DB, name string) []string {
+	rows, err := db.Query("select conrelid::regclass::text from pg_constraint where conname = $1 and connamespace = current_schema()::regnamespace", name)
+	require.NoError(t, err)
+	defer rows.Close()
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		require.NoError(t, rows.Scan(&table))
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+func (s *MigrationsTestSuite) TestMigrateNewDatabase() {
+	// given
+	db, drop := s.newSchema()
+	defer drop()
+	// when
+	version, err := model.Migrate(context.Background(), db)
+	// then
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), model.SchemaVersion, version)
+	assert.Equal(s.T(), []string{"lap"}, constraintTables(s.T(), db, "lap_team_fk"))
+
+	s.T().Run("again", func(t *testing.T) {
+		// when
+		version, err := model.Migrate(context.Background(), db)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, model.SchemaVersion, version)
+	})
+}
+
+func (s *MigrationsTestSuite) TestMigrateLegacyDatabase() {
+	// given
+	db, drop := s.newSchema()
+	defer drop()
+	_, err := db.Exec(legacySchema)
+	require.NoError(s.T(), err)
+	_, err = db.Exec(`insert into race(race_id, name, start_time) values (1, 'Bike & Run XS', '2019-11-16 10:00:00');
+insert into race(race_id, name, start_time, end_time) values (2, 'Bike & Run Jeunes', '0001-01-01 00:00:00', '0001-01-01 00:00:00');
+insert into team(team_id, race_id, bib_number, name, gender, challenge, age_category,
+    member1_first_name, member1_last_name, member1_date_of_birth, member1_age_category, member1_gender,
+    member2_first_name, member2_last_name, member2_date_of_birth, member2_age_category, member2_gender)
+    values (1, 1, 1, 'Foo', 'M', 'Open', 'Seniors', 'John', 'Doe', '1980-01-01', 'Seniors', 'H',
+    'Jane', 'Doe', '1981-01-01', 'Seniors', 'F');
+insert into lap(race_id, team_id, time) values (1, 1, '2019-11-16 10:05:00');`)
+	require.NoError(s.T(), err)
+	// when
+	version, err := model.Migrate(context.Background(), db)
+	// then
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), model.SchemaVersion, version)
+	var laps int
+	err = db.QueryRow("select count(*) from lap join team using (team_id) join race on race.race_id = lap.race_id where race.planned_start_time is null").Scan(&laps)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, laps)
+	assert.Equal(s.T(), []string{"lap"}, constraintTables(s.T(), db, "lap_race_fk"))
+	assert.Equal(s.T(), []string{"lap"}, constraintTables(s.T(), db, "lap_team_fk"))
+	// the event of the existing races is dated by the race which started
+	var date string
+	err = db.QueryRow("select date::text from event").Scan(&date)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "2019-11-16", date)
+	// the times are converted to UTC, and the races which did not start (or end) have no time
+	var started, notStarted model.Race
+	gormDB, err := gorm.Open("postgres", db)
+	require.NoError(s.T(), err)
+	started, err = model.NewRaceRepository(gormDB).Lookup(1)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), started.StartTime.Equal(time.Date(2019, 11, 16, 9, 0, 0, 0, time.UTC)))
+	notStarted, err = model.NewRaceRepository(gormDB).Lookup(2)
+	require.NoError(s.T(), err)
+	assert.False(s.T(), notStarted.IsStarted())
+	assert.False(s.T(), notStarted.IsEnded())
+	var utcLaps int
+	err = db.QueryRow("select count(*) from lap where time = '2019-11-16 09:05:00+00'::timestamptz").Scan(&utcLaps)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, utcLaps)
+	// the teams registered in "open" did not take part in a challenge
+	var challenges int
+	err = db.QueryRow("select count(*) from challenge").Scan(&challenges)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 0, challenges)
+	var challenge string
+	err = db.QueryRow("select challenge from team where team_id = 1").Scan(&challenge)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), challenge)
+	// the date of birth of the first member is checked on its own column
+	_, err = db.Exec(`update team set member1_date_of_birth = '0001-01-01' where team_id = 1`)
+	assert.Error(s.T(), err)
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -16,10 +17,16 @@ type Race struct {
 	EndTime        time.Time `gorm:"column:end_time"`
 	AllowsFirstLap bool      `gorm:"column:allows_first_lap"`
 	HasFirstLap    bool      `gorm:"column:has_first_lap"`
+	// PlannedStartTime the time at which the race is planned to start, or zero if unknown
+	PlannedStartTime time.Time `gorm:"column:planned_start_time"`
+	// EventID the ID of the event during which the race takes place
+	EventID int `gorm:"column:event_id"`
+	// Genders the codes of the gender categories in which the teams are ranked (default: men, women and mixed)
+	Genders pq.StringArray `gorm:"column:genders;type:varchar(1)[]"`
 }
 
 const (
-	raceTimeFmt = "2006-01-02 15:04:05"
+	raceTimeFmt = "2006-01-02 15:04:05 MST"
 )
 
 // UndefinedRace the "undefined" race
@@ -34,6 +41,19 @@ func (r *Race) TableName() string {
 	return racesTableName
 }
 
+// AfterFind expresses the times of the race in the time zone of the events (implements gorm callback)
+func (r *Race) AfterFind() error {
+	r.StartTime = LocalTime(r.StartTime)
+	r.EndTime = LocalTime(r.EndTime)
+	r.PlannedStartTime = LocalTime(r.PlannedStartTime)
+	return nil
+}
+
+// AfterSave expresses the times of the race in the time zone of the events (implements gorm callback)
+func (r *Race) AfterSave() error {
+	return r.AfterFind()
+}
+
 // IsStarted returns 'true' if the race has already started, false otherwise.
 func (r *Race) IsStarted() bool {
 	logrus.Debugf("race started at: %v (is zero=%t)", r.StartTime.Format(raceTimeFmt), r.StartTime.IsZero())
@@ -78,10 +98,11 @@ func (r Race) Equal(o Equaler) bool {
 type RaceRepository interface {
 	Create(race *Race) error
 	Lookup(id int) (Race, error)
-	FindByName(name string) (Race, error)
+	FindByName(eventID int, name string) (Race, error)
 	Save(race *Race) error
 	// End(race *Race) error
 	List() ([]Race, error)
+	ListByEvent(eventID int) ([]Race, error)
 }
 
 // NewRaceRepository creates a new GormRaceRepository
@@ -101,19 +122,38 @@ type GormRaceRepository struct {
 func (r *GormRaceRepository) Create(race *Race) error {
 	// check values
 	if race == nil {
-		return errors.New("missing race to create")
+		return NewValidationError(ErrCodeInvalidRace, "missing race to create")
 	}
 	if race.Name == "" {
-		return errors.New("race name is missing")
+		return NewValidationError(ErrCodeInvalidRace, "race name is missing")
+	}
+	if race.EventID == 0 {
+		return NewValidationError(ErrCodeInvalidRace, "race event is missing")
 	}
 	if race.IsStarted() {
-		return errors.New("race to create cannot be started yet")
+		return NewValidationError(ErrCodeInvalidRace, "race to create cannot be started yet")
 	}
 	if race.IsEnded() {
-		return errors.New("race to create cannot be ended yet")
+		return NewValidationError(ErrCodeInvalidRace, "race to create cannot be ended yet")
+	}
+	if len(race.Genders) == 0 {
+		race.Genders = append(pq.StringArray{}, DefaultGenders...)
+	}
+	seen := map[string]bool{}
+	for _, g := range race.Genders {
+		if !IsGender(g) {
+			return NewValidationError(ErrCodeInvalidRace, "unknown gender category '%s' (expected one of: %s, %s, %s)",
+				g, GenderMen, GenderWomen, GenderMixed)
+		}
+		if seen[g] {
+			return NewValidationError(ErrCodeInvalidRace, "duplicate gender category '%s'", g)
+		}
+		seen[g] = true
 	}
 	db := r.db.Create(race)
-	if err := db.Error; err != nil {
+	if err := db.Error; isUniqueViolation(err) {
+		return NewConflictError(ErrCodeDuplicateRace, "race '%s' already exists in event with id='%d'", race.Name, race.EventID)
+	} else if err != nil {
 		return errors.Wrap(err, "fail to store race in DB")
 	}
 	return nil
@@ -123,17 +163,21 @@ func (r *GormRaceRepository) Create(race *Race) error {
 func (r *GormRaceRepository) Lookup(id int) (Race, error) {
 	var result Race
 	db := r.db.First(&result, "race_id = ?", id)
-	if err := db.Error; err != nil {
+	if err := db.Error; isRecordNotFound(err) {
+		return result, NewNotFoundError("race", id)
+	} else if err != nil {
 		return result, err
 	}
 	return result, nil
 }
 
-// FindByName find the race with the given name. Returns an error if none was found
-func (r *GormRaceRepository) FindByName(name string) (Race, error) {
+// FindByName find the race with the given name in the given event. Returns an error if none was found
+func (r *GormRaceRepository) FindByName(eventID int, name string) (Race, error) {
 	var result Race
-	db := r.db.First(&result, "name = ?", name)
-	if err := db.Error; err != nil {
+	db := r.db.First(&result, "event_id = ? and name = ?", eventID, name)
+	if err := db.Error; isRecordNotFound(err) {
+		return result, NewNotFoundError("race", name)
+	} else if err != nil {
 		return result, err
 	}
 	return result, nil
@@ -142,7 +186,9 @@ func (r *GormRaceRepository) FindByName(name string) (Race, error) {
 // Save saves the given race, returns an error if something wrong happened
 func (r *GormRaceRepository) Save(race *Race) error {
 	db := r.db.Save(race)
-	if err := db.Error; err != nil {
+	if err := db.Error; isUniqueViolation(err) {
+		return NewConflictError(ErrCodeDuplicateRace, "race '%s' already exists in event with id='%d'", race.Name, race.EventID)
+	} else if err != nil {
 		return errors.Wrap(err, "fail to save race in DB")
 	}
 	return nil
@@ -157,3 +203,13 @@ func (r *GormRaceRepository) List() ([]Race, error) {
 	}
 	return result, nil
 }
+
+// ListByEvent lists all races of the given event
+func (r *GormRaceRepository) ListByEvent(eventID int) ([]Race, error) {
+	result := make([]Race, 0)
+	db := r.db.Where("event_id = ?", eventID).Order("name ASC").Find(&result)
+	if err := db.Error; err != nil {
+		return result, errors.Wrap(err, "fail to list races")
+	}
+	return result, nil
+}
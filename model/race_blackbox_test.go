@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/vatriathlon/stopwatch/configuration"
 	"github.com/vatriathlon/stopwatch/model"
+	testmodel "github.com/vatriathlon/stopwatch/test/model"
 	testsuite "github.com/vatriathlon/stopwatch/test/suite"
 )
 
@@ -27,21 +28,86 @@ type RaceRepositoryTestSuite struct {
 func (s *RaceRepositoryTestSuite) TestCreateRace() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 
 	s.T().Run("ok", func(t *testing.T) {
 		// given
 		race := model.Race{
-			Name: fmt.Sprintf("race %s", uuid.NewV4()),
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 		}
 		// when
 		err := raceRepo.Create(&race)
 		// then
 		require.NoError(t, err)
 		require.NotEqual(t, race.ID, 0)
+		// default gender categories
+		result, err := raceRepo.Lookup(race.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{model.GenderMen, model.GenderWomen, model.GenderMixed}, []string(result.Genders))
+	})
+
+	s.T().Run("custom gender categories", func(t *testing.T) {
+		// given
+		race := model.Race{
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+			Genders: []string{model.GenderMixed},
+		}
+		// when
+		err := raceRepo.Create(&race)
+		// then
+		require.NoError(t, err)
+		result, err := raceRepo.Lookup(race.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{model.GenderMixed}, []string(result.Genders))
+	})
+
+	s.T().Run("same name in another event", func(t *testing.T) {
+		// given
+		race := model.Race{
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+		}
+		err := raceRepo.Create(&race)
+		require.NoError(t, err)
+		other := testmodel.CreateEvent(t, s.DB)
+		// when
+		err = raceRepo.Create(&model.Race{EventID: other.ID, Name: race.Name})
+		// then
+		require.NoError(t, err)
 	})
 
 	s.T().Run("failure", func(t *testing.T) {
 
+		t.Run("unknown gender category", func(t *testing.T) {
+			// given
+			race := model.Race{
+				EventID: event.ID,
+				Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+				Genders: []string{model.GenderMen, "X"},
+			}
+			// when
+			err := raceRepo.Create(&race)
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("duplicate gender category", func(t *testing.T) {
+			// given
+			race := model.Race{
+				EventID: event.ID,
+				Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+				Genders: []string{model.GenderMen, model.GenderMen},
+			}
+			// when
+			err := raceRepo.Create(&race)
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
 		t.Run("missing name", func(t *testing.T) {
 			// given
 			race := model.Race{}
@@ -50,6 +116,34 @@ func (s *RaceRepositoryTestSuite) TestCreateRace() {
 			// then
 			require.Error(t, err)
 			require.Equal(t, race.ID, 0)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("missing event", func(t *testing.T) {
+			// given
+			race := model.Race{
+				Name: fmt.Sprintf("race %s", uuid.NewV4()),
+			}
+			// when
+			err := raceRepo.Create(&race)
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("duplicate name", func(t *testing.T) {
+			// given
+			race := model.Race{
+				EventID: event.ID,
+				Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+			}
+			err := raceRepo.Create(&race)
+			require.NoError(t, err)
+			// when
+			err = raceRepo.Create(&model.Race{EventID: event.ID, Name: race.Name})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsConflictError(err))
 		})
 	})
 
@@ -74,7 +168,7 @@ func (s *RaceRepositoryTestSuite) TestCreateRace() {
 // 		require.NoError(t, err)
 // 		require.True(t, race.IsEnded())
 // 		// verify the end time
-// 		result, err := raceRepo.FindByName(race.Name)
+// 		result, err := raceRepo.FindByName(event.ID, race.Name)
 // 		require.NoError(s.T(), err)
 // 		assert.False(s.T(), result.StartTime.IsZero())
 // 		assert.False(s.T(), result.EndTime.IsZero())
@@ -119,36 +213,41 @@ func (s *RaceRepositoryTestSuite) TestCreateRace() {
 func (s *RaceRepositoryTestSuite) TestFindByName() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 
 	s.T().Run("ok", func(t *testing.T) {
 		// given
 		race := model.Race{
-			Name: fmt.Sprintf("race %s", uuid.NewV4()),
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 		}
 		err := raceRepo.Create(&race)
 		require.NoError(t, err)
 		// when
-		_, err = raceRepo.FindByName(race.Name)
+		_, err = raceRepo.FindByName(event.ID, race.Name)
 		// then
 		require.NoError(t, err)
 	})
 
 	s.T().Run("no match", func(t *testing.T) {
 		// when
-		_, err := raceRepo.FindByName("foo")
+		_, err := raceRepo.FindByName(event.ID, "foo")
 		// then
 		require.Error(t, err)
+		assert.True(t, model.IsNotFoundError(err))
 	})
 }
 
 func (s *RaceRepositoryTestSuite) TestLookup() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 
 	s.T().Run("ok", func(t *testing.T) {
 		// given
 		race := model.Race{
-			Name: fmt.Sprintf("race %s", uuid.NewV4()),
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 		}
 		err := raceRepo.Create(&race)
 		require.NoError(t, err)
@@ -163,6 +262,7 @@ func (s *RaceRepositoryTestSuite) TestLookup() {
 		_, err := raceRepo.Lookup(0)
 		// then
 		require.Error(t, err)
+		assert.True(t, model.IsNotFoundError(err))
 	})
 }
 
@@ -179,8 +279,10 @@ func (s *RaceRepositoryTestSuite) TestListRacesNoResult() {
 func (s *RaceRepositoryTestSuite) TestListRacesSingleResult() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	race1 := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race1)
 	require.NoError(s.T(), err)
@@ -194,11 +296,14 @@ func (s *RaceRepositoryTestSuite) TestListRacesSingleResult() {
 func (s *RaceRepositoryTestSuite) TestListRacesMultipleResults() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	race1 := model.Race{
-		Name: fmt.Sprintf("race foo %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race foo %s", uuid.NewV4()),
 	}
 	race2 := model.Race{
-		Name: fmt.Sprintf("race bar %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race bar %s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race1)
 	require.NoError(s.T(), err)
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// SchemaVersion the version of the database schema expected by the application, i.e., the number of migrations
+const SchemaVersion = len(migrations)
+
+// LoadSchemaVersion returns the version of the schema of the given database
+func LoadSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, "select version from schema_version").Scan(&version)
+	if err != nil {
+		return -1, errors.Wrap(err, "fail to load schema version")
+	}
+	return version, nil
+}
@@ -1,7 +1,9 @@
 package model
 
 import (
+	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -36,6 +38,90 @@ const (
 	teamTableName = "team"
 )
 
+const (
+	// GenderMen the code of the men, and of the teams of 2 men
+	GenderMen = "H"
+	// GenderWomen the code of the women, and of the teams of 2 women
+	GenderWomen = "F"
+	// GenderMixed the code of the teams of a man and a woman
+	GenderMixed = "M"
+)
+
+// DefaultGenders the gender categories of a race, unless specified otherwise when the race is created
+var DefaultGenders = []string{GenderMen, GenderWomen, GenderMixed}
+
+// IsGender returns 'true' if the given value is the code of a gender category, 'false' otherwise
+func IsGender(value string) bool {
+	switch value {
+	case GenderMen, GenderWomen, GenderMixed:
+		return true
+	default:
+		return false
+	}
+}
+
+// TeamStatus the status of a team in a race
+type TeamStatus string
+
+const (
+	// TeamNotStarted the status of a team which has not recorded any lap yet
+	TeamNotStarted TeamStatus = "not_started"
+	// TeamRacing the status of a team which has recorded at least one lap, while the race is not ended
+	TeamRacing TeamStatus = "racing"
+	// TeamFinished the status of a team which has recorded at least one lap, once the race is ended
+	TeamFinished TeamStatus = "finished"
+)
+
+// ParseTeamStatus parses the given value into a TeamStatus
+func ParseTeamStatus(value string) (TeamStatus, error) {
+	switch status := TeamStatus(value); status {
+	case TeamNotStarted, TeamRacing, TeamFinished:
+		return status, nil
+	default:
+		return "", errors.Errorf("invalid team status: '%s'", value)
+	}
+}
+
+// Status returns the status of the team in the given race
+func (t Team) Status(race Race) TeamStatus {
+	if len(t.Laps) == 0 {
+		return TeamNotStarted
+	}
+	if race.IsEnded() {
+		return TeamFinished
+	}
+	return TeamRacing
+}
+
+// TeamFilter the criteria to filter and paginate the teams of a race. Empty criteria are ignored.
+type TeamFilter struct {
+	AgeCategory string
+	Gender      string
+	Challenge   string
+	Status      TeamStatus
+	// Page the page to return, starting at 1
+	Page int
+	// Size the number of teams per page, or 0 to return all teams
+	Size int
+}
+
+// TeamSummary a lightweight view of a team, with the number of laps and the time of the last lap instead
+// of all its laps
+type TeamSummary struct {
+	ID          int        `gorm:"column:team_id"`
+	Name        string     `gorm:"column:name"`
+	BibNumber   int        `gorm:"column:bib_number"`
+	Gender      string     `gorm:"column:gender"`
+	Challenge   string     `gorm:"column:challenge"`
+	AgeCategory string     `gorm:"column:age_category"`
+	Status      TeamStatus `gorm:"column:status"`
+	LapCount    int        `gorm:"column:lap_count"`
+	// LastLapTime the time of the last lap, or nil if the team has not recorded any lap yet
+	LastLapTime *time.Time `gorm:"column:last_lap_time"`
+	// Category the label of the age and gender category of the team (not stored, set in the language of the client)
+	Category string `gorm:"-"`
+}
+
 // TableName implements gorm.tabler
 func (t Team) TableName() string {
 	return teamTableName
@@ -60,6 +146,10 @@ type TeamRepository interface {
 	List(raceID int) ([]Team, error)
 	FindIDByBibNumber(raceID int, bibnumber int) (int, error)
 	LoadByBibNumber(raceID int, bibnumber int) (Team, error)
+	Search(raceID int, query string) ([]Team, error)
+	Find(raceID int, filter TeamFilter) ([]Team, error)
+	Summaries(raceID int, filter TeamFilter) ([]TeamSummary, error)
+	Rank(raceID int, bibnumber int) (int, error)
 }
 
 // NewTeamRepository creates a new GormTeamRepository
@@ -79,19 +169,21 @@ type GormTeamRepository struct {
 func (r *GormTeamRepository) Create(team *Team) error {
 	// check values
 	if team == nil {
-		return errors.New("missing team to persist")
+		return NewValidationError(ErrCodeInvalidTeam, "missing team to persist")
 	}
 	if team.BibNumber <= 0 {
-		return errors.Errorf("missing or invalid 'BibNumber': %d", team.BibNumber)
+		return NewValidationError(ErrCodeInvalidTeam, "missing or invalid 'BibNumber': %d", team.BibNumber)
 	}
 	if team.RaceID == 0 {
-		return errors.New("missing 'RaceID' field")
+		return NewValidationError(ErrCodeInvalidTeam, "missing 'RaceID' field")
 	}
 	if team.Name == "" {
-		return errors.New("missing 'Name' field")
+		return NewValidationError(ErrCodeInvalidTeam, "missing 'Name' field")
 	}
 	db := r.db.Create(team)
-	if err := db.Error; err != nil {
+	if err := db.Error; isUniqueViolation(err) {
+		return NewConflictError(ErrCodeDuplicateTeam, "team with bibnumber '%d' already exists in race with id='%d'", team.BibNumber, team.RaceID)
+	} else if err != nil {
 		return errors.Wrap(err, "fail to store team in DB")
 	}
 	return nil
@@ -113,7 +205,9 @@ func (r *GormTeamRepository) FindIDByBibNumber(raceID int, bibnumber int) (int,
 	err := r.db.Raw(
 		fmt.Sprintf("select team_id from %s where race_id = ? and bib_number = ?", team.TableName()),
 		raceID, bibnumber).Scan(&team).Error
-	if err != nil {
+	if isRecordNotFound(err) {
+		return -1, NewNotFoundError("team", bibnumber)
+	} else if err != nil {
 		return -1, errors.Wrapf(err, "fail to find team with bibnumber '%d' in race with id='%d'", bibnumber, raceID)
 	}
 	return team.ID, nil
@@ -123,9 +217,123 @@ func (r *GormTeamRepository) FindIDByBibNumber(raceID int, bibnumber int) (int,
 func (r *GormTeamRepository) LoadByBibNumber(raceID int, bibnumber int) (Team, error) {
 	result := Team{}
 	db := r.db.Preload("Laps").Where("race_id = ? and bib_number = ?", raceID, bibnumber).First(&result)
-	if err := db.Error; err != nil {
+	if err := db.Error; isRecordNotFound(err) {
+		return result, NewNotFoundError("team", bibnumber)
+	} else if err != nil {
 		return result, errors.Wrap(err, "fail to find team by bibnumber")
 	}
 	return result, nil
 
 }
+
+// Search lists all teams in the given race whose name, member names or clubs contain the given query (case-insensitive)
+func (r *GormTeamRepository) Search(raceID int, query string) ([]Team, error) {
+	result := make([]Team, 0)
+	pattern := "%" + likeEscaper.Replace(strings.TrimSpace(query)) + "%"
+	db := r.db.Preload("Laps").Where("race_id = ?", raceID).
+		Where(`name ILIKE ? 
+			OR member1_first_name ILIKE ? OR member1_last_name ILIKE ? OR member1_club ILIKE ? 
+			OR member2_first_name ILIKE ? OR member2_last_name ILIKE ? OR member2_club ILIKE ?`,
+			pattern, pattern, pattern, pattern, pattern, pattern, pattern).
+		Order("bib_number ASC").Find(&result)
+	if err := db.Error; err != nil {
+		return result, errors.Wrap(err, "fail to search teams")
+	}
+	return result, nil
+}
+
+// Find lists the teams in the given race which match the given filter, along with their laps
+func (r *GormTeamRepository) Find(raceID int, filter TeamFilter) ([]Team, error) {
+	result := make([]Team, 0)
+	query, args := teamSummariesQuery(raceID, filter)
+	db := r.db.Preload("Laps").
+		Where(fmt.Sprintf("team_id in (select team_id from (%s) f)", query), args...).
+		Order("bib_number ASC").Find(&result)
+	if err := db.Error; err != nil {
+		return result, errors.Wrap(err, "fail to find teams")
+	}
+	return result, nil
+}
+
+// Summaries lists the summaries of the teams in the given race which match the given filter
+func (r *GormTeamRepository) Summaries(raceID int, filter TeamFilter) ([]TeamSummary, error) {
+	result := make([]TeamSummary, 0)
+	query, args := teamSummariesQuery(raceID, filter)
+	db := r.db.Raw(query, args...).Scan(&result)
+	if err := db.Error; err != nil {
+		return result, errors.Wrap(err, "fail to list team summaries")
+	}
+	// the gorm callbacks are not invoked on the results of a raw query
+	for i, t := range result {
+		if t.LastLapTime != nil {
+			lastLapTime := LocalTime(*t.LastLapTime)
+			result[i].LastLapTime = &lastLapTime
+		}
+	}
+	return result, nil
+}
+
+// teamSummariesQuery returns the query (and its arguments) to select the summaries of the teams in the
+// given race which match the given filter. The status of the teams is computed from their laps and the end
+// of the race, so it can be filtered in the outer query. The race has not ended if its end time is null or
+// the zero time (in UTC, regardless of the time zone of the session).
+func teamSummariesQuery(raceID int, filter TeamFilter) (string, []interface{}) {
+	query := `select t.team_id, t.name, t.bib_number, t.gender, t.challenge, t.age_category,
+			count(l.lap_id) as lap_count, max(l.time) as last_lap_time,
+			case when count(l.lap_id) = 0 then 'not_started'
+				when r.end_time > '0001-01-01 00:00:00+00'::timestamptz then 'finished'
+				else 'racing' end as status
+		from team t join race r on r.race_id = t.race_id left join lap l on l.team_id = t.team_id
+		where t.race_id = ?`
+	args := []interface{}{raceID}
+	if filter.AgeCategory != "" {
+		query += " and t.age_category = ?"
+		args = append(args, filter.AgeCategory)
+	}
+	if filter.Gender != "" {
+		query += " and t.gender = ?"
+		args = append(args, filter.Gender)
+	}
+	if filter.Challenge != "" {
+		query += " and t.challenge = ?"
+		args = append(args, filter.Challenge)
+	}
+	query = fmt.Sprintf("select * from (%s group by t.team_id, r.end_time) s", query)
+	if filter.Status != "" {
+		query += " where s.status = ?"
+		args = append(args, string(filter.Status))
+	}
+	query += " order by s.bib_number asc"
+	if filter.Size > 0 {
+		page := filter.Page
+		if page < 1 {
+			page = 1
+		}
+		query += " limit ? offset ?"
+		args = append(args, filter.Size, (page-1)*filter.Size)
+	}
+	return query, args
+}
+
+// likeEscaper escapes the special characters in the patterns of the `LIKE` clauses
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+const rankQuery = `select rank from (
+		select t.bib_number, rank() over (order by count(l.lap_id) desc, max(l.time) asc) as rank
+		from team t join lap l on l.team_id = t.team_id 
+		where t.race_id = ? 
+		group by t.bib_number) r 
+	where r.bib_number = ?`
+
+// Rank returns the current (scratch) rank of the team with the given bibnumber in the given race,
+// or 0 if the team has not recorded any lap yet
+func (r *GormTeamRepository) Rank(raceID int, bibnumber int) (int, error) {
+	var rank int
+	err := r.db.Raw(rankQuery, raceID, bibnumber).Row().Scan(&rank)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return -1, errors.Wrapf(err, "fail to compute rank of team with bibnumber '%d' in race with id='%d'", bibnumber, raceID)
+	}
+	return rank, nil
+}
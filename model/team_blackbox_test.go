@@ -29,9 +29,11 @@ type TeamRepositoryTestSuite struct {
 func (s *TeamRepositoryTestSuite) TestCreateTeam() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	teamRepo := model.NewTeamRepository(s.DB)
 	race := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	// when
 	err := raceRepo.Create(&race)
@@ -96,9 +98,11 @@ func (s *TeamRepositoryTestSuite) TestCreateTeam() {
 func (s *TeamRepositoryTestSuite) TestListTeamsNoResult() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	teamRepo := model.NewTeamRepository(s.DB)
 	race := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race)
 	require.NoError(s.T(), err)
@@ -112,9 +116,11 @@ func (s *TeamRepositoryTestSuite) TestListTeamsNoResult() {
 func (s *TeamRepositoryTestSuite) TestListTeamsSingleResult() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	teamRepo := model.NewTeamRepository(s.DB)
 	race := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race)
 	require.NoError(s.T(), err)
@@ -132,10 +138,12 @@ func (s *TeamRepositoryTestSuite) TestListTeamsSingleResult() {
 func (s *TeamRepositoryTestSuite) TestListTeamsMultipleResults() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	teamRepo := model.NewTeamRepository(s.DB)
 	lapRepo := model.NewLapRepository(s.DB)
 	race := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race)
 	require.NoError(s.T(), err)
@@ -161,3 +169,202 @@ func (s *TeamRepositoryTestSuite) TestListTeamsMultipleResults() {
 	assert.Equal(s.T(), team1.ID, teams[1].ID)
 	assert.Len(s.T(), teams[1].Laps, 1)
 }
+
+func (s *TeamRepositoryTestSuite) TestSearchTeams() {
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	teamRepo := model.NewTeamRepository(s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	team1 := testmodel.NewTeam(race.ID, 1)
+	team1.Name = "Les Vétérans"
+	err = teamRepo.Create(&team1)
+	require.NoError(s.T(), err)
+	team2 := testmodel.NewTeam(race.ID, 2)
+	team2.Member2.LastName = "Martin"
+	team2.Member2.Club = "Vélo Club 100%"
+	err = teamRepo.Create(&team2)
+	require.NoError(s.T(), err)
+
+	s.T().Run("by team name", func(t *testing.T) {
+		// when
+		teams, err := teamRepo.Search(race.ID, "vétéran")
+		// then
+		require.NoError(t, err)
+		require.Len(t, teams, 1)
+		assert.Equal(t, team1.ID, teams[0].ID)
+	})
+
+	s.T().Run("by member name", func(t *testing.T) {
+		// when
+		teams, err := teamRepo.Search(race.ID, "MARTIN")
+		// then
+		require.NoError(t, err)
+		require.Len(t, teams, 1)
+		assert.Equal(t, team2.ID, teams[0].ID)
+	})
+
+	s.T().Run("by club", func(t *testing.T) {
+		// when
+		teams, err := teamRepo.Search(race.ID, "club 100%")
+		// then
+		require.NoError(t, err)
+		require.Len(t, teams, 1)
+		assert.Equal(t, team2.ID, teams[0].ID)
+	})
+
+	s.T().Run("wildcards are escaped", func(t *testing.T) {
+		// when
+		teams, err := teamRepo.Search(race.ID, "%")
+		// then
+		require.NoError(t, err)
+		require.Len(t, teams, 1)
+		assert.Equal(t, team2.ID, teams[0].ID)
+	})
+
+	s.T().Run("no match", func(t *testing.T) {
+		// when
+		teams, err := teamRepo.Search(race.ID, "foo")
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, teams)
+	})
+}
+
+func (s *TeamRepositoryTestSuite) TestRankTeam() {
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	teamRepo := model.NewTeamRepository(s.DB)
+	lapRepo := model.NewLapRepository(s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	now := time.Now()
+	// team 1: 1 lap, team 2: 2 laps, team 3: 2 laps (but slower than team 2), team 4: no lap
+	for bibnumber, lapTimes := range map[int][]time.Time{
+		1: {now.Add(10 * time.Minute)},
+		2: {now.Add(10 * time.Minute), now.Add(20 * time.Minute)},
+		3: {now.Add(11 * time.Minute), now.Add(21 * time.Minute)},
+		4: {},
+	} {
+		team := testmodel.NewTeam(race.ID, bibnumber)
+		err := teamRepo.Create(&team)
+		require.NoError(s.T(), err)
+		for _, lapTime := range lapTimes {
+			err := lapRepo.Create(&model.Lap{RaceID: race.ID, TeamID: team.ID, Time: lapTime})
+			require.NoError(s.T(), err)
+		}
+	}
+	for bibnumber, expected := range map[int]int{1: 3, 2: 1, 3: 2, 4: 0} {
+		// when
+		rank, err := teamRepo.Rank(race.ID, bibnumber)
+		// then
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), expected, rank, "invalid rank for team %d", bibnumber)
+	}
+}
+
+func (s *TeamRepositoryTestSuite) TestFindTeams() {
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	teamRepo := model.NewTeamRepository(s.DB)
+	lapRepo := model.NewLapRepository(s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	// 5 teams, the even ones are 'Veteran' and recorded 2 laps
+	lapTime := time.Now()
+	for i := 1; i < 6; i++ {
+		team := testmodel.NewTeam(race.ID, i)
+		if i%2 == 0 {
+			team.AgeCategory = "Veteran"
+		}
+		err := teamRepo.Create(&team)
+		require.NoError(s.T(), err)
+		for j := 0; i%2 == 0 && j < 2; j++ {
+			lapTime = lapTime.Add(time.Minute)
+			err := lapRepo.Create(&model.Lap{RaceID: race.ID, TeamID: team.ID, Time: lapTime})
+			require.NoError(s.T(), err)
+		}
+	}
+	bibNumbers := func(teams []model.Team) []int {
+		result := []int{}
+		for _, t := range teams {
+			result = append(result, t.BibNumber)
+		}
+		return result
+	}
+
+	s.T().Run("no filter", func(t *testing.T) {
+		// when
+		teams, err := teamRepo.Find(race.ID, model.TeamFilter{})
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, bibNumbers(teams))
+		assert.Len(t, teams[1].Laps, 2)
+	})
+
+	s.T().Run("by category", func(t *testing.T) {
+		// when
+		teams, err := teamRepo.Find(race.ID, model.TeamFilter{AgeCategory: "Veteran"})
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []int{2, 4}, bibNumbers(teams))
+	})
+
+	s.T().Run("by status", func(t *testing.T) {
+		// when
+		teams, err := teamRepo.Find(race.ID, model.TeamFilter{Status: model.TeamNotStarted})
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 3, 5}, bibNumbers(teams))
+	})
+
+	s.T().Run("paginated", func(t *testing.T) {
+		// when
+		teams, err := teamRepo.Find(race.ID, model.TeamFilter{Page: 2, Size: 2})
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []int{3, 4}, bibNumbers(teams))
+	})
+
+	s.T().Run("summaries", func(t *testing.T) {
+		// when
+		summaries, err := teamRepo.Summaries(race.ID, model.TeamFilter{Status: model.TeamRacing, Size: 1})
+		// then
+		require.NoError(t, err)
+		require.Len(t, summaries, 1)
+		assert.Equal(t, 2, summaries[0].BibNumber)
+		assert.Equal(t, model.TeamRacing, summaries[0].Status)
+		assert.Equal(t, 2, summaries[0].LapCount)
+		require.NotNil(t, summaries[0].LastLapTime)
+	})
+
+	s.T().Run("summaries in a session which is not in UTC", func(t *testing.T) {
+		// given a session in a time zone in which the zero time of the race which has not ended is not zero
+		tx := s.DB.Begin()
+		defer tx.Rollback()
+		err := tx.Exec("set local timezone = 'Europe/Paris'").Error
+		require.NoError(t, err)
+		// when
+		summaries, err := model.NewTeamRepository(tx).Summaries(race.ID, model.TeamFilter{Status: model.TeamRacing})
+		// then
+		require.NoError(t, err)
+		require.Len(t, summaries, 2)
+		assert.Equal(t, model.TeamRacing, summaries[0].Status)
+		assert.Equal(t, model.TeamRacing, summaries[1].Status)
+	})
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// timeZone the time zone of the events, in which the times read from the database are expressed.
+// The times are stored in UTC.
+var timeZone = time.Local
+
+// SetTimeZone sets the time zone of the events
+func SetTimeZone(loc *time.Location) {
+	timeZone = loc
+}
+
+// TimeZone returns the time zone of the events
+func TimeZone() *time.Location {
+	return timeZone
+}
+
+// Now returns the current time in the time zone of the events
+func Now() time.Time {
+	return time.Now().In(timeZone)
+}
+
+// LocalTime returns the given time in the time zone of the events. The zero time is returned as-is.
+func LocalTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Time{}
+	}
+	return t.In(timeZone)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCodeInternal the code of the unexpected errors
+const ErrCodeInternal model.ErrorCode = "internal_error"
+
+// internalErrorMessage the message of the server errors, whose details (eg: the SQL errors) are only logged
+const internalErrorMessage = "an unexpected error occurred"
+
+// drainingRetryAfter the delay (in seconds) after which the clients can retry the requests which were rejected
+// during the shutdown, once the server restarted
+const drainingRetryAfter = "5"
+
+// Error the body of the error responses
+type Error struct {
+	// Code a stable code which identifies the error, so the clients can translate it
+	Code    model.ErrorCode `json:"code"`
+	Message string          `json:"message"`
+}
+
+// HTTPErrorHandler writes the given error in the response, with a status that depends on its cause:
+// `404 Not Found` for the missing entities, `409 Conflict` for the duplicate entities, `422 Unprocessable Entity`
+// for the invalid entities or the operations which are not allowed in the current state,
+// `503 Service Unavailable` for the cancelled requests and (with a `Retry-After` header) for the requests rejected
+// during the shutdown, `504 Gateway Timeout` for the transactions which timed out, and
+// `500 Internal Server Error` for all other (unexpected) errors. The client errors (`4xx`) are logged as warnings.
+func HTTPErrorHandler(err error, c echo.Context) {
+	status, body := NewError(err)
+	log := logrus.WithField("code", status).
+		WithField("error_code", body.Code).
+		WithField("request_url", c.Request().RequestURI)
+	if status >= http.StatusInternalServerError {
+		log.Error(err.Error())
+	} else {
+		log.Warn(err.Error())
+	}
+	if c.Response().Committed {
+		return
+	}
+	if errors.Cause(err) == service.ErrDraining {
+		c.Response().Header().Set("Retry-After", drainingRetryAfter)
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, body)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("unable to write error response")
+	}
+}
+
+// NewError returns the HTTP status and the response body for the given error. The message of the server errors
+// (`5xx`) is generic, so the details of the unexpected errors are not sent to the clients.
+func NewError(err error) (int, Error) {
+	status, body := newError(err)
+	if status >= http.StatusInternalServerError {
+		body.Message = internalErrorMessage
+	}
+	return status, body
+}
+
+func newError(err error) (int, Error) {
+	switch errors.Cause(err) {
+	case service.ErrDraining, context.Canceled:
+		return http.StatusServiceUnavailable, Error{Code: httpErrorCode(http.StatusServiceUnavailable)}
+	case service.ErrTransactionTimeout, context.DeadlineExceeded:
+		return http.StatusGatewayTimeout, Error{Code: httpErrorCode(http.StatusGatewayTimeout)}
+	}
+	switch e := errors.Cause(err).(type) {
+	case *echo.HTTPError:
+		return e.Code, Error{
+			Code:    httpErrorCode(e.Code),
+			Message: fmt.Sprintf("%v", e.Message),
+		}
+	case model.NotFoundError:
+		return http.StatusNotFound, Error{Code: e.Code(), Message: err.Error()}
+	case model.ConflictError:
+		return http.StatusConflict, Error{Code: e.Code(), Message: err.Error()}
+	case model.ValidationError:
+		return http.StatusUnprocessableEntity, Error{Code: e.Code(), Message: err.Error()}
+	case model.StateError:
+		return http.StatusUnprocessableEntity, Error{Code: e.Code(), Message: err.Error()}
+	default:
+		return http.StatusInternalServerError, Error{Code: ErrCodeInternal}
+	}
+}
+
+// httpErrorCode returns the error code for the given HTTP status, eg: `bad_request` for `400 Bad Request`
+func httpErrorCode(status int) model.ErrorCode {
+	return model.ErrorCode(strings.Replace(strings.ToLower(http.StatusText(status)), " ", "_", -1))
+}
@@ -0,0 +1,175 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/server"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewError(t *testing.T) {
+
+	testcases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   model.ErrorCode
+		// expectedMessage the expected message, if different from the error
+		expectedMessage string
+	}{
+		{
+			name:           "not found",
+			err:            errors.Wrap(model.NewNotFoundError("team", 5), "unable to add laps to team"),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "team_not_found",
+		},
+		{
+			name:           "conflict",
+			err:            errors.WithStack(model.NewConflictError(model.ErrCodeDuplicateRace, "race 'foo' already exists")),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   model.ErrCodeDuplicateRace,
+		},
+		{
+			name:           "validation",
+			err:            model.NewValidationError(model.ErrCodeInvalidTeam, "missing 'Name' field"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   model.ErrCodeInvalidTeam,
+		},
+		{
+			name:           "state",
+			err:            errors.Wrap(model.NewStateError(model.ErrCodeRaceAlreadyStarted, "race already started"), "unable to start race"),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   model.ErrCodeRaceAlreadyStarted,
+		},
+		{
+			name:            "bad request",
+			err:             echo.NewHTTPError(http.StatusBadRequest, "invalid race id"),
+			expectedStatus:  http.StatusBadRequest,
+			expectedCode:    "bad_request",
+			expectedMessage: "invalid race id",
+		},
+		{
+			name:            "unexpected",
+			err:             errors.Wrap(errors.New(`pq: relation "lap" does not exist`), "unable to add laps to team"),
+			expectedStatus:  http.StatusInternalServerError,
+			expectedCode:    server.ErrCodeInternal,
+			expectedMessage: "an unexpected error occurred",
+		},
+		{
+			name:            "service unavailable",
+			err:             echo.NewHTTPError(http.StatusServiceUnavailable, "dial tcp 127.0.0.1:5432: connection refused"),
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedCode:    "service_unavailable",
+			expectedMessage: "an unexpected error occurred",
+		},
+		{
+			name:            "draining",
+			err:             errors.Wrap(service.ErrDraining, "unable to add lap"),
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedCode:    "service_unavailable",
+			expectedMessage: "an unexpected error occurred",
+		},
+		{
+			name:            "cancelled",
+			err:             errors.Wrap(context.Canceled, "database transaction cancelled"),
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedCode:    "service_unavailable",
+			expectedMessage: "an unexpected error occurred",
+		},
+		{
+			name:            "transaction timeout",
+			err:             errors.Wrap(service.ErrTransactionTimeout, "unable to list teams"),
+			expectedStatus:  http.StatusGatewayTimeout,
+			expectedCode:    "gateway_timeout",
+			expectedMessage: "an unexpected error occurred",
+		},
+		{
+			name:            "deadline exceeded",
+			err:             errors.Wrap(context.DeadlineExceeded, "unable to list teams"),
+			expectedStatus:  http.StatusGatewayTimeout,
+			expectedCode:    "gateway_timeout",
+			expectedMessage: "an unexpected error occurred",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			status, body := server.NewError(tc.err)
+			// then
+			assert.Equal(t, tc.expectedStatus, status)
+			assert.Equal(t, tc.expectedCode, body.Code)
+			if tc.expectedMessage != "" {
+				assert.Equal(t, tc.expectedMessage, body.Message)
+			} else {
+				assert.Equal(t, tc.err.Error(), body.Message)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	// given
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	// when
+	server.HTTPErrorHandler(errors.Wrap(model.NewNotFoundError("race", 1), "unable to get race with id=1"), c)
+	// then
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	require.NotNil(t, hook.LastEntry())
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+	body := server.Error{}
+	err := json.Unmarshal(rec.Body.Bytes(), &body)
+	require.NoError(t, err)
+	assert.Equal(t, model.ErrorCode("race_not_found"), body.Code)
+	assert.Equal(t, "unable to get race with id=1: race '1' not found", body.Message)
+}
+
+func TestHTTPErrorHandlerInternalError(t *testing.T) {
+	// given
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	// when
+	server.HTTPErrorHandler(errors.Wrap(errors.New(`pq: relation "lap" does not exist`), "unable to list laps"), c)
+	// then
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.NotNil(t, hook.LastEntry())
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+	body := server.Error{}
+	err := json.Unmarshal(rec.Body.Bytes(), &body)
+	require.NoError(t, err)
+	assert.Equal(t, server.ErrCodeInternal, body.Code)
+	assert.NotContains(t, rec.Body.String(), "pq:")
+}
+
+func TestHTTPErrorHandlerDraining(t *testing.T) {
+	// given
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	// when
+	server.HTTPErrorHandler(errors.WithStack(service.ErrDraining), c)
+	// then
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+}
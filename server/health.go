@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// BuildInfoPath the path to the build info of the server
+	BuildInfoPath = "/api/status"
+	// LivenessPath the path to check that the server is alive
+	LivenessPath = "/api/health/live"
+	// ReadinessPath the path to check that the server is ready to handle requests
+	ReadinessPath = "/api/health/ready"
+)
+
+// BuildInfo the build info of the server
+type BuildInfo struct {
+	BuildTime   string `json:"buildTime"`
+	BuildCommit string `json:"buildCommit"`
+	GoVersion   string `json:"goVersion"`
+	// SchemaVersion the version of the database schema expected by the server
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// ShowBuildInfo returns the build info of the server
+func ShowBuildInfo(c echo.Context) error {
+	return c.JSON(http.StatusOK, BuildInfo{
+		BuildTime:     configuration.BuildTime,
+		BuildCommit:   configuration.BuildCommit,
+		GoVersion:     runtime.Version(),
+		SchemaVersion: model.SchemaVersion,
+	})
+}
+
+// Liveness returns `200 OK` as long as the server is able to handle requests, regardless of the state of the database
+func Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]service.HealthStatus{
+		"status": service.HealthUp,
+	})
+}
+
+// Readiness returns a handler to check that the database is reachable and that its schema has the expected version.
+// Returns `503 Service Unavailable` if any check failed.
+func Readiness(svc service.ApplicationService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		readiness := svc.CheckReadiness(c.Request().Context())
+		if readiness.Status != service.HealthUp {
+			logrus.WithField("checks", readiness.Checks).Warn("server is not ready")
+			return c.JSON(http.StatusServiceUnavailable, readiness)
+		}
+		return c.JSON(http.StatusOK, readiness)
+	}
+}
@@ -0,0 +1,49 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/server"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowBuildInfo(t *testing.T) {
+	// given
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, server.BuildInfoPath, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	// when
+	err := server.ShowBuildInfo(c)
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assertConformsToSpec(t, http.MethodGet, server.BuildInfoPath, rec)
+	info := server.BuildInfo{}
+	err = json.Unmarshal(rec.Body.Bytes(), &info)
+	require.NoError(t, err)
+	assert.Equal(t, configuration.BuildCommit, info.BuildCommit)
+	assert.Equal(t, model.SchemaVersion, info.SchemaVersion)
+}
+
+func TestLiveness(t *testing.T) {
+	// given
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, server.LivenessPath, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	// when
+	err := server.Liveness(c)
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assertConformsToSpec(t, http.MethodGet, server.LivenessPath, rec)
+}
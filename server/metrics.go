@@ -0,0 +1,27 @@
+package server
+
+import (
+	"time"
+
+	"github.com/vatriathlon/stopwatch/metrics"
+
+	"github.com/labstack/echo"
+)
+
+// MetricsPath the path to the Prometheus metrics
+const MetricsPath = "/metrics"
+
+// observeRequests a middleware which records the duration of the requests, per route
+func observeRequests(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		status := c.Response().Status
+		if err != nil {
+			// the response is written afterwards, by the HTTP error handler
+			status, _ = NewError(err)
+		}
+		metrics.ObserveHTTPRequest(c.Request().Method, c.Path(), status, time.Since(start))
+		return err
+	}
+}
@@ -0,0 +1,29 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/server"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	// given
+	e := server.New(service.ApplicationService{})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, server.LivenessPath, nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/races/foo", nil))
+	// when
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, server.MetricsPath, nil))
+	// then
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `stopwatch_http_request_duration_seconds_count{code="200",method="GET",route="/api/health/live"} 1`)
+	// requests are recorded per route, not per path
+	assert.Contains(t, body, `stopwatch_http_request_duration_seconds_count{code="400",method="GET",route="/api/races/:raceID"} 1`)
+}
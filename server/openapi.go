@@ -0,0 +1,818 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// OpenAPIPath the path to the OpenAPI specification of the API
+const OpenAPIPath = "/api/openapi.json"
+
+// OpenAPI returns the OpenAPI 3 specification of the API
+func OpenAPI(c echo.Context) error {
+	return c.JSONBlob(http.StatusOK, []byte(OpenAPISpec))
+}
+
+// OpenAPISpec the OpenAPI 3 specification of the API. Must be kept in sync with the routes and payloads,
+// which is verified in the tests.
+const OpenAPISpec = `{
+  "openapi": "3.0.2",
+  "info": {
+    "title": "Stopwatch",
+    "description": "Records the laps of the teams during Bike & Run races. The times are expressed in the time zone of the events, with their offset from UTC (eg: '2019-11-17T10:00:00+01:00').",
+    "version": "1.0.0"
+  },
+  "paths": {
+    "/api/status": {
+      "get": {
+        "summary": "Returns the build info of the server",
+        "operationId": "showBuildInfo",
+        "responses": {
+          "200": {
+            "description": "The build info",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/BuildInfo" }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/api/health/live": {
+      "get": {
+        "summary": "Checks that the server is alive, regardless of the state of the database",
+        "operationId": "liveness",
+        "responses": {
+          "200": {
+            "description": "The server is alive",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": ["status"],
+                  "properties": {
+                    "status": { "$ref": "#/components/schemas/HealthStatus" }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/api/health/ready": {
+      "get": {
+        "summary": "Checks that the database is reachable and that its schema has the version expected by the server",
+        "operationId": "readiness",
+        "responses": {
+          "200": {
+            "description": "The server is ready",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Readiness" }
+              }
+            }
+          },
+          "503": {
+            "description": "At least one check failed",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Readiness" }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/api/openapi.json": {
+      "get": {
+        "summary": "Returns this specification",
+        "operationId": "openapi",
+        "responses": {
+          "200": {
+            "description": "The OpenAPI specification",
+            "content": {
+              "application/json": {
+                "schema": { "type": "object" }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metrics": {
+      "get": {
+        "summary": "Returns the metrics of the server, in the Prometheus text format",
+        "description": "Includes the duration of the HTTP requests per route, the duration and outcome of the database transactions, and the number of laps recorded and rejected per race.",
+        "operationId": "metrics",
+        "responses": {
+          "200": {
+            "description": "The metrics",
+            "content": {
+              "text/plain": {
+                "schema": { "type": "string" }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/api/events": {
+      "get": {
+        "summary": "Lists all events, from the most recent to the oldest one",
+        "operationId": "listEvents",
+        "responses": {
+          "200": {
+            "description": "The events",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": { "$ref": "#/components/schemas/Event" }
+                }
+              }
+            }
+          },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/events/{eventID}": {
+      "parameters": [
+        { "$ref": "#/components/parameters/eventID" }
+      ],
+      "get": {
+        "summary": "Returns a single event",
+        "operationId": "showEvent",
+        "responses": {
+          "200": {
+            "description": "The event",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Event" }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "404": { "$ref": "#/components/responses/NotFound" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/events/{eventID}/races": {
+      "parameters": [
+        { "$ref": "#/components/parameters/eventID" }
+      ],
+      "get": {
+        "summary": "Lists the races of an event, by name",
+        "operationId": "listEventRaces",
+        "responses": {
+          "200": {
+            "description": "The races of the event",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": { "$ref": "#/components/schemas/Race" }
+                }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "404": { "$ref": "#/components/responses/NotFound" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/races": {
+      "get": {
+        "summary": "Lists all races of all events, by name",
+        "operationId": "listRaces",
+        "responses": {
+          "200": {
+            "description": "The races",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": { "$ref": "#/components/schemas/Race" }
+                }
+              }
+            }
+          },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/races/{raceID}": {
+      "parameters": [
+        { "$ref": "#/components/parameters/raceID" }
+      ],
+      "get": {
+        "summary": "Returns a single race",
+        "operationId": "showRace",
+        "responses": {
+          "200": {
+            "description": "The race",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Race" }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "404": { "$ref": "#/components/responses/NotFound" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      },
+      "patch": {
+        "summary": "Updates the race, eg: to start or end it",
+        "description": "Only the given properties are changed. The changes are validated against the current state of the race: only the name of an ended race can be changed.",
+        "operationId": "updateRace",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": { "$ref": "#/components/schemas/RaceUpdate" }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated race",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Race" }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "404": { "$ref": "#/components/responses/NotFound" },
+          "422": { "$ref": "#/components/responses/Unprocessable" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/races/{raceID}/challenges": {
+      "parameters": [
+        { "$ref": "#/components/parameters/raceID" }
+      ],
+      "get": {
+        "summary": "Lists the challenges declared in a race, by name",
+        "operationId": "listChallenges",
+        "responses": {
+          "200": {
+            "description": "The challenges of the race",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": { "$ref": "#/components/schemas/Challenge" }
+                }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "404": { "$ref": "#/components/responses/NotFound" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/races/{raceID}/teams": {
+      "parameters": [
+        { "$ref": "#/components/parameters/raceID" }
+      ],
+      "get": {
+        "summary": "Lists the teams of a race, by bib number",
+        "description": "Returns the full teams, along with all their laps, unless the 'summary' view is requested.",
+        "operationId": "listTeams",
+        "parameters": [
+          { "$ref": "#/components/parameters/acceptLanguage" },
+          {
+            "name": "view",
+            "in": "query",
+            "description": "'summary' to return the number of laps and the time of the last lap of each team instead of all its laps",
+            "schema": { "type": "string", "enum": ["summary"] }
+          },
+          {
+            "name": "category",
+            "in": "query",
+            "description": "the age category of the teams",
+            "schema": { "type": "string" }
+          },
+          {
+            "name": "gender",
+            "in": "query",
+            "description": "the gender of the teams",
+            "schema": { "type": "string" }
+          },
+          {
+            "name": "challenge",
+            "in": "query",
+            "description": "the challenge of the teams (one of the challenges declared in the race)",
+            "schema": { "type": "string" }
+          },
+          {
+            "name": "status",
+            "in": "query",
+            "description": "the status of the teams",
+            "schema": { "$ref": "#/components/schemas/TeamStatus" }
+          },
+          {
+            "name": "page",
+            "in": "query",
+            "description": "the page to return, starting at 1",
+            "schema": { "type": "integer", "minimum": 1, "default": 1 }
+          },
+          {
+            "name": "size",
+            "in": "query",
+            "description": "the number of teams per page (all teams are returned if missing)",
+            "schema": { "type": "integer", "minimum": 1 }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The teams",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "oneOf": [
+                    {
+                      "type": "array",
+                      "items": { "$ref": "#/components/schemas/Team" }
+                    },
+                    {
+                      "type": "array",
+                      "items": { "$ref": "#/components/schemas/TeamSummary" }
+                    }
+                  ]
+                }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/races/{raceID}/teams/search": {
+      "parameters": [
+        { "$ref": "#/components/parameters/raceID" }
+      ],
+      "get": {
+        "summary": "Searches the teams of a race whose name, member names or clubs contain the query (case-insensitive)",
+        "operationId": "searchTeams",
+        "parameters": [
+          {
+            "name": "q",
+            "in": "query",
+            "required": true,
+            "schema": { "type": "string" }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The matching teams",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": { "$ref": "#/components/schemas/Team" }
+                }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/races/{raceID}/bibnumber/{bibnumber}": {
+      "parameters": [
+        { "$ref": "#/components/parameters/raceID" },
+        { "$ref": "#/components/parameters/bibnumber" }
+      ],
+      "get": {
+        "summary": "Returns the details of a team",
+        "operationId": "showTeam",
+        "parameters": [
+          { "$ref": "#/components/parameters/acceptLanguage" }
+        ],
+        "responses": {
+          "200": {
+            "description": "The team, along with its status, current rank and laps",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/TeamDetail" }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "404": { "$ref": "#/components/responses/NotFound" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/races/{raceID}/firstlap": {
+      "parameters": [
+        { "$ref": "#/components/parameters/raceID" }
+      ],
+      "post": {
+        "summary": "Records the first lap of all the teams at once, in the races which allow it",
+        "operationId": "addFirstLapForAll",
+        "responses": {
+          "201": {
+            "description": "The race",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Race" }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "404": { "$ref": "#/components/responses/NotFound" },
+          "422": { "$ref": "#/components/responses/Unprocessable" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/races/{raceID}/bibnumber/{bibnumber}/laps": {
+      "parameters": [
+        { "$ref": "#/components/parameters/raceID" },
+        { "$ref": "#/components/parameters/bibnumber" }
+      ],
+      "post": {
+        "summary": "Records a lap of a team at the current time",
+        "operationId": "addLap",
+        "responses": {
+          "201": {
+            "description": "The team, along with all its laps",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Team" }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "404": { "$ref": "#/components/responses/NotFound" },
+          "409": {
+            "description": "The lap was recorded too soon after the previous lap of the team",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/Error" }
+              }
+            }
+          },
+          "422": { "$ref": "#/components/responses/Unprocessable" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    },
+    "/api/races/{raceID}/bibnumber/{bibnumber}/analytics": {
+      "parameters": [
+        { "$ref": "#/components/parameters/raceID" },
+        { "$ref": "#/components/parameters/bibnumber" }
+      ],
+      "get": {
+        "summary": "Returns the analytics of the laps of a team",
+        "operationId": "showLapAnalytics",
+        "responses": {
+          "200": {
+            "description": "The lap analytics",
+            "content": {
+              "application/json": {
+                "schema": { "$ref": "#/components/schemas/LapAnalytics" }
+              }
+            }
+          },
+          "400": { "$ref": "#/components/responses/BadRequest" },
+          "404": { "$ref": "#/components/responses/NotFound" },
+          "500": { "$ref": "#/components/responses/InternalError" }
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "eventID": {
+        "name": "eventID",
+        "in": "path",
+        "required": true,
+        "schema": { "type": "integer" }
+      },
+      "raceID": {
+        "name": "raceID",
+        "in": "path",
+        "required": true,
+        "schema": { "type": "integer" }
+      },
+      "bibnumber": {
+        "name": "bibnumber",
+        "in": "path",
+        "required": true,
+        "schema": { "type": "integer" }
+      },
+      "acceptLanguage": {
+        "name": "Accept-Language",
+        "in": "header",
+        "description": "the preferred languages of the labels of the categories (eg: 'de-CH,de;q=0.9,en;q=0.8'), among 'fr', 'en' and 'de'",
+        "schema": { "type": "string" }
+      }
+    },
+    "responses": {
+      "BadRequest": {
+        "description": "Invalid path or query parameter",
+        "content": {
+          "application/json": {
+            "schema": { "$ref": "#/components/schemas/Error" }
+          }
+        }
+      },
+      "NotFound": {
+        "description": "The event, race or team does not exist",
+        "content": {
+          "application/json": {
+            "schema": { "$ref": "#/components/schemas/Error" }
+          }
+        }
+      },
+      "Unprocessable": {
+        "description": "The operation is invalid or not allowed in the current state of the race",
+        "content": {
+          "application/json": {
+            "schema": { "$ref": "#/components/schemas/Error" }
+          }
+        }
+      },
+      "InternalError": {
+        "description": "Unexpected error",
+        "content": {
+          "application/json": {
+            "schema": { "$ref": "#/components/schemas/Error" }
+          }
+        }
+      }
+    },
+    "schemas": {
+      "BuildInfo": {
+        "type": "object",
+        "required": ["buildTime", "buildCommit", "goVersion", "schemaVersion"],
+        "properties": {
+          "buildTime": { "type": "string" },
+          "buildCommit": { "type": "string" },
+          "goVersion": { "type": "string" },
+          "schemaVersion": {
+            "type": "integer",
+            "description": "the version of the database schema expected by the server"
+          }
+        }
+      },
+      "HealthStatus": {
+        "type": "string",
+        "enum": ["up", "down"]
+      },
+      "Readiness": {
+        "type": "object",
+        "required": ["status", "checks"],
+        "properties": {
+          "status": { "$ref": "#/components/schemas/HealthStatus" },
+          "checks": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["name", "status"],
+              "properties": {
+                "name": { "type": "string", "enum": ["database", "schema"] },
+                "status": { "$ref": "#/components/schemas/HealthStatus" },
+                "message": {
+                  "type": "string",
+                  "description": "the reason why the check failed"
+                }
+              }
+            }
+          }
+        }
+      },
+      "Challenge": {
+        "type": "object",
+        "required": ["ID", "RaceID", "Name"],
+        "properties": {
+          "ID": { "type": "integer" },
+          "RaceID": { "type": "integer" },
+          "Name": { "type": "string" }
+        }
+      },
+      "Event": {
+        "type": "object",
+        "required": ["ID", "Name", "Date", "Venue"],
+        "properties": {
+          "ID": { "type": "integer" },
+          "Name": { "type": "string" },
+          "Date": {
+            "type": "string",
+            "format": "date-time",
+            "description": "the day of the event, at midnight"
+          },
+          "Venue": { "type": "string" }
+        }
+      },
+      "Race": {
+        "type": "object",
+        "required": ["ID", "Name", "StartTime", "EndTime", "AllowsFirstLap", "HasFirstLap", "PlannedStartTime", "EventID", "Genders"],
+        "properties": {
+          "ID": { "type": "integer" },
+          "EventID": {
+            "type": "integer",
+            "description": "the ID of the event during which the race takes place"
+          },
+          "Name": { "type": "string" },
+          "StartTime": {
+            "type": "string",
+            "format": "date-time",
+            "description": "'0001-01-01T00:00:00Z' if the race has not started yet"
+          },
+          "EndTime": {
+            "type": "string",
+            "format": "date-time",
+            "description": "'0001-01-01T00:00:00Z' if the race has not ended yet"
+          },
+          "AllowsFirstLap": { "type": "boolean" },
+          "HasFirstLap": { "type": "boolean" },
+          "PlannedStartTime": {
+            "type": "string",
+            "format": "date-time",
+            "description": "'0001-01-01T00:00:00Z' if the planned start of the race is unknown"
+          },
+          "Genders": {
+            "type": "array",
+            "items": { "type": "string", "enum": ["H", "F", "M"] },
+            "description": "the gender categories in which the teams are ranked: 'H' (men), 'F' (women) and/or 'M' (mixed)"
+          }
+        }
+      },
+      "RaceUpdate": {
+        "type": "object",
+        "minProperties": 1,
+        "additionalProperties": false,
+        "properties": {
+          "status": {
+            "type": "string",
+            "enum": ["started", "ended"],
+            "description": "'started' to start the race now, 'ended' to end it now"
+          },
+          "name": { "type": "string", "minLength": 1 },
+          "allowsFirstLap": {
+            "type": "boolean",
+            "description": "cannot be changed once the first lap was recorded"
+          },
+          "plannedStartTime": {
+            "type": "string",
+            "format": "date-time",
+            "description": "cannot be changed once the race started"
+          }
+        }
+      },
+      "Team": {
+        "type": "object",
+        "required": ["ID", "Name", "Gender", "Challenge", "AgeCategory", "BibNumber", "Member1", "Member2", "RaceID", "Laps"],
+        "properties": {
+          "ID": { "type": "integer" },
+          "Name": { "type": "string" },
+          "Gender": { "type": "string" },
+          "Challenge": { "type": "string" },
+          "AgeCategory": { "type": "string" },
+          "BibNumber": { "type": "integer" },
+          "Member1": { "$ref": "#/components/schemas/TeamMember" },
+          "Member2": { "$ref": "#/components/schemas/TeamMember" },
+          "RaceID": { "type": "integer" },
+          "Laps": {
+            "type": "array",
+            "nullable": true,
+            "items": { "$ref": "#/components/schemas/Lap" }
+          }
+        }
+      },
+      "TeamMember": {
+        "type": "object",
+        "required": ["FirstName", "LastName", "DateOfBirth", "AgeCategory", "Gender", "Club"],
+        "properties": {
+          "FirstName": { "type": "string" },
+          "LastName": { "type": "string" },
+          "DateOfBirth": { "type": "string", "format": "date-time" },
+          "AgeCategory": { "type": "string" },
+          "Gender": { "type": "string" },
+          "Club": { "type": "string" }
+        }
+      },
+      "Lap": {
+        "type": "object",
+        "required": ["ID", "Time", "RaceID", "TeamID"],
+        "properties": {
+          "ID": { "type": "integer" },
+          "Time": { "type": "string", "format": "date-time" },
+          "RaceID": { "type": "integer" },
+          "TeamID": { "type": "integer" }
+        }
+      },
+      "TeamStatus": {
+        "type": "string",
+        "enum": ["not_started", "racing", "finished"]
+      },
+      "TeamSummary": {
+        "type": "object",
+        "required": ["ID", "Name", "BibNumber", "Gender", "Challenge", "AgeCategory", "Status", "LapCount", "LastLapTime", "Category"],
+        "properties": {
+          "ID": { "type": "integer" },
+          "Name": { "type": "string" },
+          "BibNumber": { "type": "integer" },
+          "Gender": { "type": "string" },
+          "Challenge": { "type": "string" },
+          "AgeCategory": { "type": "string" },
+          "Status": { "$ref": "#/components/schemas/TeamStatus" },
+          "LapCount": { "type": "integer" },
+          "LastLapTime": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true,
+            "description": "null if the team has not recorded any lap yet"
+          },
+          "Category": {
+            "type": "string",
+            "description": "the label of the age and gender category of the team, in the language of the client"
+          }
+        }
+      },
+      "TeamDetail": {
+        "type": "object",
+        "required": ["team", "status", "rank", "laps", "category"],
+        "properties": {
+          "team": { "$ref": "#/components/schemas/Team" },
+          "status": { "$ref": "#/components/schemas/TeamStatus" },
+          "rank": {
+            "type": "integer",
+            "description": "the current (scratch) rank of the team, or 0 if the team has not recorded any lap yet"
+          },
+          "laps": {
+            "type": "array",
+            "items": { "$ref": "#/components/schemas/LapSplit" }
+          },
+          "category": {
+            "type": "string",
+            "description": "the label of the age and gender category of the team, in the language of the client"
+          }
+        }
+      },
+      "LapSplit": {
+        "type": "object",
+        "required": ["number", "time", "duration"],
+        "properties": {
+          "number": { "type": "integer" },
+          "time": { "type": "string", "format": "date-time" },
+          "duration": {
+            "type": "number",
+            "description": "the duration of the lap in seconds, or 0 if unknown"
+          }
+        }
+      },
+      "LapAnalytics": {
+        "type": "object",
+        "required": ["bibNumber", "laps", "averagePace", "slowdown", "consistency"],
+        "properties": {
+          "bibNumber": { "type": "integer" },
+          "laps": {
+            "type": "array",
+            "items": { "$ref": "#/components/schemas/LapSplit" }
+          },
+          "fastestLap": { "$ref": "#/components/schemas/LapSplit" },
+          "averagePace": {
+            "type": "number",
+            "description": "the average duration of a lap, in seconds"
+          },
+          "slowdown": {
+            "type": "number",
+            "description": "the difference between the average duration of the laps in the second half and in the first half of the race, in seconds"
+          },
+          "consistency": {
+            "type": "number",
+            "description": "the standard deviation of the lap durations, in seconds"
+          }
+        }
+      },
+      "Error": {
+        "type": "object",
+        "required": ["code", "message"],
+        "properties": {
+          "code": {
+            "type": "string",
+            "description": "a stable code which identifies the error, eg: 'race_not_found' or 'race_already_started'"
+          },
+          "message": { "type": "string" }
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,278 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/server"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/labstack/echo"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+
+	t.Run("served", func(t *testing.T) {
+		// given
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, server.OpenAPIPath, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		// when
+		err := server.OpenAPI(c)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+		spec := map[string]interface{}{}
+		err = json.Unmarshal(rec.Body.Bytes(), &spec)
+		require.NoError(t, err)
+		assert.Equal(t, "3.0.2", spec["openapi"])
+	})
+
+	t.Run("all routes documented", func(t *testing.T) {
+		// given
+		spec := loadOpenAPISpec(t)
+		e := server.New(service.ApplicationService{})
+		paths := spec["paths"].(map[string]interface{})
+		// when
+		registered := map[string]bool{}
+		for _, r := range e.Routes() {
+			path := openAPIPath(r.Path)
+			registered[r.Method+" "+path] = true
+			// then
+			require.Contains(t, paths, path, "route '%s' is not documented", r.Path)
+			assert.Contains(t, paths[path], strings.ToLower(r.Method), "method '%s' of route '%s' is not documented", r.Method, r.Path)
+		}
+		for path, item := range paths {
+			for method := range item.(map[string]interface{}) {
+				if method == "parameters" {
+					continue
+				}
+				assert.True(t, registered[strings.ToUpper(method)+" "+path], "documented route '%s %s' is not registered", method, path)
+			}
+		}
+	})
+
+	t.Run("schemas", func(t *testing.T) {
+		// given
+		now := time.Now()
+		race := model.Race{ID: 1, EventID: 1, Name: "Bike & Run XS", StartTime: now.Add(-time.Hour), Genders: pq.StringArray(model.DefaultGenders)}
+		team := model.Team{
+			ID:        1,
+			Name:      "team 1",
+			BibNumber: 1,
+			RaceID:    race.ID,
+			Laps: []model.Lap{
+				{ID: 1, RaceID: race.ID, TeamID: 1, Time: now.Add(-30 * time.Minute)},
+				{ID: 2, RaceID: race.ID, TeamID: 1, Time: now},
+			},
+		}
+		analytics := service.NewLapAnalytics(race, team.BibNumber, team.Laps)
+		testcases := map[string]interface{}{
+			"Challenge":    model.Challenge{ID: 1, RaceID: 1, Name: "Challenge Entreprise"},
+			"Event":        model.Event{ID: 1, Name: "Bike & Run", Date: time.Date(2019, 11, 17, 0, 0, 0, 0, time.UTC), Venue: "Vatry"},
+			"Race":         race,
+			"RaceUpdate":   map[string]interface{}{"status": "ended", "name": "Bike & Run XL", "plannedStartTime": now},
+			"Team":         team,
+			"TeamSummary":  model.TeamSummary{ID: 1, Name: "team 1", BibNumber: 1, Status: model.TeamRacing, LapCount: 2, LastLapTime: &now},
+			"TeamDetail":   service.TeamDetail{Team: team, Status: model.TeamRacing, Rank: 1, Laps: analytics.Laps},
+			"LapAnalytics": analytics,
+			"Error":        server.Error{Code: "race_not_found", Message: "race '1' not found"},
+			"Readiness": service.Readiness{
+				Status: service.HealthDown,
+				Checks: []service.HealthCheck{
+					{Name: "database", Status: service.HealthUp},
+					{Name: "schema", Status: service.HealthDown, Message: "schema version mismatch: expected 2, got 1"},
+				},
+			},
+		}
+		for name, value := range testcases {
+			t.Run(name, func(t *testing.T) {
+				// when
+				data, err := json.Marshal(value)
+				require.NoError(t, err)
+				// then
+				assertConformsToSchema(t, fmt.Sprintf("#/components/schemas/%s", name), data)
+			})
+		}
+
+		t.Run("no lap", func(t *testing.T) {
+			// when
+			data, err := json.Marshal(model.TeamSummary{ID: 1, Status: model.TeamNotStarted})
+			require.NoError(t, err)
+			// then
+			assertConformsToSchema(t, "#/components/schemas/TeamSummary", data)
+		})
+
+		t.Run("undeclared property", func(t *testing.T) {
+			// when
+			data, err := json.Marshal(map[string]interface{}{"code": "foo", "message": "bar", "baz": 1})
+			require.NoError(t, err)
+			// then
+			spec := loadOpenAPISpec(t)
+			errs := validateSchema(spec, resolveRef(spec, "#/components/schemas/Error"), unmarshal(t, data), "$")
+			assert.Equal(t, []string{"$: undeclared property 'baz'"}, errs)
+		})
+	})
+}
+
+// assertConformsToSpec asserts that the recorded response of the route with the given method and
+// (echo) path template conforms to the OpenAPI specification
+func assertConformsToSpec(t *testing.T, method, pathTmpl string, rec *httptest.ResponseRecorder) {
+	spec := loadOpenAPISpec(t)
+	item, ok := spec["paths"].(map[string]interface{})[openAPIPath(pathTmpl)].(map[string]interface{})
+	require.True(t, ok, "route '%s' is not documented", pathTmpl)
+	operation, ok := item[strings.ToLower(method)].(map[string]interface{})
+	require.True(t, ok, "method '%s' of route '%s' is not documented", method, pathTmpl)
+	response, ok := operation["responses"].(map[string]interface{})[strconv.Itoa(rec.Code)].(map[string]interface{})
+	require.True(t, ok, "response '%d' of '%s %s' is not documented", rec.Code, method, pathTmpl)
+	if ref, ok := response["$ref"].(string); ok {
+		response = resolveRef(spec, ref)
+	}
+	content, ok := response["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	require.True(t, ok, "JSON content of response '%d' of '%s %s' is not documented", rec.Code, method, pathTmpl)
+	errs := validateSchema(spec, content["schema"].(map[string]interface{}), unmarshal(t, rec.Body.Bytes()), "$")
+	assert.Empty(t, errs, "response of '%s %s' does not conform to the specification", method, pathTmpl)
+}
+
+// assertConformsToSchema asserts that the given JSON document conforms to the schema with the given reference
+func assertConformsToSchema(t *testing.T, ref string, data []byte) {
+	spec := loadOpenAPISpec(t)
+	errs := validateSchema(spec, resolveRef(spec, ref), unmarshal(t, data), "$")
+	assert.Empty(t, errs, "'%s' does not conform to '%s'", string(data), ref)
+}
+
+func loadOpenAPISpec(t *testing.T) map[string]interface{} {
+	spec := map[string]interface{}{}
+	err := json.Unmarshal([]byte(server.OpenAPISpec), &spec)
+	require.NoError(t, err)
+	return spec
+}
+
+func unmarshal(t *testing.T, data []byte) interface{} {
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	require.NoError(t, err)
+	return value
+}
+
+var pathParamRegexp = regexp.MustCompile(`:(\w+)`)
+
+// openAPIPath converts the given echo path template into an OpenAPI path, eg: `/api/races/:raceID` into `/api/races/{raceID}`
+func openAPIPath(pathTmpl string) string {
+	return pathParamRegexp.ReplaceAllString(pathTmpl, "{$1}")
+}
+
+// resolveRef returns the node of the spec with the given (local) reference, eg: `#/components/schemas/Race`
+func resolveRef(spec map[string]interface{}, ref string) map[string]interface{} {
+	node := spec
+	for _, segment := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		node = node[segment].(map[string]interface{})
+	}
+	return node
+}
+
+// validateSchema validates the given value against the given schema, and returns the violations.
+// Only supports the subset of the OpenAPI schemas used in the specification. Also reports the properties
+// which are not declared in the schemas, so the specification does not drift from the payloads.
+func validateSchema(spec, schema map[string]interface{}, value interface{}, location string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return validateSchema(spec, resolveRef(spec, ref), value, location)
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		// empty arrays match all the alternatives of the team listing, so the value must match at least one
+		for _, s := range oneOf {
+			if len(validateSchema(spec, s.(map[string]interface{}), value, location)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: does not match any of the schemas", location)}
+	}
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: must not be null", location)}
+	}
+	errs := []string{}
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object", location)}
+		}
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if _, found := obj[r.(string)]; !found {
+					errs = append(errs, fmt.Sprintf("%s: missing property '%s'", location, r))
+				}
+			}
+		}
+		properties, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			return errs
+		}
+		for name, v := range obj {
+			s, declared := properties[name]
+			if !declared {
+				errs = append(errs, fmt.Sprintf("%s: undeclared property '%s'", location, name))
+				continue
+			}
+			errs = append(errs, validateSchema(spec, s.(map[string]interface{}), v, location+"."+name)...)
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array", location)}
+		}
+		for i, v := range arr {
+			errs = append(errs, validateSchema(spec, schema["items"].(map[string]interface{}), v, fmt.Sprintf("%s[%d]", location, i))...)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected a string", location)}
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: invalid date-time '%s'", location, s))
+			}
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok {
+			found := false
+			for _, e := range enum {
+				found = found || e == s
+			}
+			if !found {
+				errs = append(errs, fmt.Sprintf("%s: unexpected value '%s'", location, s))
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return []string{fmt.Sprintf("%s: expected an integer", location)}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: expected a number", location)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected a boolean", location)}
+		}
+	}
+	return errs
+}
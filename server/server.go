@@ -4,13 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
-	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/metrics"
+	"github.com/vatriathlon/stopwatch/model"
 	"github.com/vatriathlon/stopwatch/service"
 
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,48 +24,111 @@ func New(svc service.ApplicationService) *echo.Echo {
 	e := echo.New()
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
+	e.Use(observeRequests)
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
 		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept},
 	}))
-	// graceful handle of errors, i.e., just logging with the same logger as everywhere else in the app.
-	e.HTTPErrorHandler = func(err error, c echo.Context) {
-		if he, ok := err.(*echo.HTTPError); ok {
-			logrus.WithField("code", he.Code).WithField("request_url", c.Request().RequestURI).Error(he.Message)
-			if msg, ok := he.Message.(string); ok {
-				c.String(he.Code, msg)
-			} else {
-				c.NoContent(he.Code)
-			}
-		}
-	}
-	e.GET("/api/status", Status)
+	// errors are returned in a JSON body, with a stable code and a status which depends on the type of error
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.GET(BuildInfoPath, ShowBuildInfo)
+	e.GET(LivenessPath, Liveness)
+	e.GET(ReadinessPath, Readiness(svc))
+	e.GET(MetricsPath, echo.WrapHandler(metrics.Handler()))
+	e.GET(OpenAPIPath, OpenAPI)
+	e.GET(ListEventsPath, ListEvents(svc))
+	e.GET(ShowEventPathTmpl, ShowEvent(svc))
+	e.GET(ListEventRacesPathTmpl, ListEventRaces(svc))
 	e.GET("/api/races", ListRaces(svc))
 	e.GET(ShowRacePathTmpl, ShowRace(svc))
-	e.PATCH(StartRacePathTmpl, StartRace(svc))
+	e.PATCH(UpdateRacePathTmpl, UpdateRace(svc))
+	e.GET(ListChallengesPathTmpl, ListChallenges(svc))
 	e.GET(ListTeamsPathTmpl, ListTeams(svc))
+	e.GET(SearchTeamsPathTmpl, SearchTeams(svc))
+	e.GET(ShowTeamPathTmpl, ShowTeam(svc))
 	e.POST(AddFirstLapForAllTmpl, AddFirstLapForAll(svc))
 	e.POST(AddLapPathTmpl, AddLap(svc))
+	e.GET(ShowLapAnalyticsPathTmpl, ShowLapAnalytics(svc))
 	return e
 }
 
 const (
+	// ListEventsPath the path to list all events
+	ListEventsPath = "/api/events"
+	// ShowEventPathTmpl the path template to get a single event by its ID
+	ShowEventPathTmpl = "/api/events/:eventID"
+	// ListEventRacesPathTmpl the path template to list all races of an event
+	ListEventRacesPathTmpl = "/api/events/:eventID/races"
 	// ShowRacePathTmpl the path template to get a single race by its ID
 	ShowRacePathTmpl = "/api/races/:raceID"
-	// StartRacePathTmpl the path template to start a race
-	StartRacePathTmpl = "/api/races/:raceID"
+	// UpdateRacePathTmpl the path template to update a race, eg: to start or end it (same as ShowRacePathTmpl,
+	// with the PATCH method)
+	UpdateRacePathTmpl = "/api/races/:raceID"
+	// ListChallengesPathTmpl the path template to list all challenges declared in a race
+	ListChallengesPathTmpl = "/api/races/:raceID/challenges"
 	// ListTeamsPathTmpl the path template to list all teams in a race
 	ListTeamsPathTmpl = "/api/races/:raceID/teams"
+	// SearchTeamsPathTmpl the path template to search teams by name, member name or club in a race
+	SearchTeamsPathTmpl = "/api/races/:raceID/teams/search"
+	// ShowTeamPathTmpl the path template to get a single team by its bibnumber in a race
+	ShowTeamPathTmpl = "/api/races/:raceID/bibnumber/:bibnumber"
 	// AddFirstLapForAllTmpl the path template for add a lap to all teams in a race
 	AddFirstLapForAllTmpl = "/api/races/:raceID/firstlap"
 	// AddLapPathTmpl the path template for add a lap to a team in a race
 	AddLapPathTmpl = "/api/races/:raceID/bibnumber/:bibnumber/laps"
+	// ShowLapAnalyticsPathTmpl the path template to get the lap analytics of a team in a race
+	ShowLapAnalyticsPathTmpl = "/api/races/:raceID/bibnumber/:bibnumber/analytics"
 )
 
-// Status returns a basic `ping/pong` handler
-func Status(c echo.Context) error {
-	return c.String(http.StatusOK, fmt.Sprintf("build.time: %s - build.commit: %s 👷‍♂️", configuration.BuildTime, configuration.BuildCommit))
+// ListEvents returns a handler to list events
+func ListEvents(svc service.ApplicationService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		scheme := c.Scheme()
+		host := c.Request().Host
+		logrus.Debugf("Processing incoming request on %s://%s%s", scheme, host, c.Request().URL)
+		events, err := svc.ListEvents(c.Request().Context())
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, events)
+	}
+}
+
+// ShowEvent returns a handler to get a single event
+func ShowEvent(svc service.ApplicationService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		scheme := c.Scheme()
+		host := c.Request().Host
+		logrus.Debugf("Processing incoming request on %s://%s%s", scheme, host, c.Request().URL)
+		eventID, err := strconv.Atoi(c.Param("eventID"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert event id '%s' to integer", c.Param("eventID")))
+		}
+		event, err := svc.GetEvent(c.Request().Context(), eventID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, event)
+	}
+}
+
+// ListEventRaces returns a handler to list the races of an event
+func ListEventRaces(svc service.ApplicationService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		scheme := c.Scheme()
+		host := c.Request().Host
+		logrus.Debugf("Processing incoming request on %s://%s%s", scheme, host, c.Request().URL)
+		eventID, err := strconv.Atoi(c.Param("eventID"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert event id '%s' to integer", c.Param("eventID")))
+		}
+		races, err := svc.ListEventRaces(c.Request().Context(), eventID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, races)
+	}
 }
 
 // ShowRace returns a handler to list races
@@ -74,16 +141,16 @@ func ShowRace(svc service.ApplicationService) echo.HandlerFunc {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert race id '%s' to integer", c.Param("raceID")))
 		}
-		race, err := svc.GetRace(raceID)
+		race, err := svc.GetRace(c.Request().Context(), raceID)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			return err
 		}
 		return c.JSON(http.StatusOK, race)
 	}
 }
 
-// StartRace returns a handler to mark a race as started
-func StartRace(svc service.ApplicationService) echo.HandlerFunc {
+// UpdateRace returns a handler to apply a partial update on a race, eg: to start or end it
+func UpdateRace(svc service.ApplicationService) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		scheme := c.Scheme()
 		host := c.Request().Host
@@ -92,15 +159,16 @@ func StartRace(svc service.ApplicationService) echo.HandlerFunc {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert race id '%s' to integer", c.Param("raceID")))
 		}
-		var payload interface{}
-		err = json.NewDecoder(c.Request().Body).Decode(&payload)
+		var update service.RaceUpdate
+		decoder := json.NewDecoder(c.Request().Body)
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&update)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid race update: %v", err))
 		}
-		logrus.Infof("race patch payload: %v", payload)
-		race, err := svc.StartRace(raceID)
+		race, err := svc.UpdateRace(c.Request().Context(), raceID, update)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			return err
 		}
 		return c.JSON(http.StatusOK, race)
 	}
@@ -112,14 +180,32 @@ func ListRaces(svc service.ApplicationService) echo.HandlerFunc {
 		scheme := c.Scheme()
 		host := c.Request().Host
 		logrus.Debugf("Processing incoming request on %s://%s%s", scheme, host, c.Request().URL)
-		races, err := svc.ListRaces()
+		races, err := svc.ListRaces(c.Request().Context())
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			return err
 		}
 		return c.JSON(http.StatusOK, races)
 	}
 }
 
+// ListChallenges returns a handler to list the challenges declared in a given race
+func ListChallenges(svc service.ApplicationService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		scheme := c.Scheme()
+		host := c.Request().Host
+		logrus.Debugf("Processing incoming request on %s://%s%s", scheme, host, c.Request().URL)
+		raceID, err := strconv.Atoi(c.Param("raceID"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert race id '%s' to integer", c.Param("raceID")))
+		}
+		challenges, err := svc.ListChallenges(c.Request().Context(), raceID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, challenges)
+	}
+}
+
 // ListTeams returns a handler to list teams in a given race
 func ListTeams(svc service.ApplicationService) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -130,14 +216,142 @@ func ListTeams(svc service.ApplicationService) echo.HandlerFunc {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert race id '%s' to integer", c.Param("raceID")))
 		}
-		teams, err := svc.ListTeams(raceID)
+		filter, err := newTeamFilter(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if c.QueryParam("view") == "summary" {
+			summaries, err := svc.ListTeamSummaries(c.Request().Context(), raceID, filter)
+			if err != nil {
+				return err
+			}
+			locale := acceptedLocale(c)
+			for i, s := range summaries {
+				summaries[i].Category = service.CategoryLabel(locale, s.AgeCategory, s.Gender)
+			}
+			return c.JSON(http.StatusOK, summaries)
+		}
+		teams, err := svc.ListTeams(c.Request().Context(), raceID, filter)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, teams)
+	}
+}
+
+// acceptedLocale returns the locale of the labels in the response, ie: the supported locale with the highest
+// quality in the `Accept-Language` header of the request (eg: "de-CH,de;q=0.9,en;q=0.8"), or the locale of the
+// generated documents if none is supported. The locale is also set in the `Content-Language` header of the response.
+func acceptedLocale(c echo.Context) string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+	languages := []language{}
+	for _, value := range strings.Split(c.Request().Header.Get("Accept-Language"), ",") {
+		parts := strings.Split(strings.TrimSpace(value), ";")
+		l := language{tag: strings.TrimSpace(parts[0]), quality: 1}
+		for _, p := range parts[1:] {
+			if q := strings.TrimSpace(p); strings.HasPrefix(q, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(q, "q="), 64); err == nil {
+					l.quality = v
+				}
+			}
+		}
+		if l.tag != "" && l.tag != "*" && l.quality > 0 {
+			languages = append(languages, l)
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+	locale, _ := service.ParseLocale("")
+	for _, l := range languages {
+		// only the primary language matters, eg: "de" in "de-CH"
+		if result, err := service.ParseLocale(strings.SplitN(l.tag, "-", 2)[0]); err == nil {
+			locale = result
+			break
+		}
+	}
+	c.Response().Header().Set("Content-Language", locale)
+	return locale
+}
+
+// newTeamFilter returns the team filter from the `category`, `gender`, `challenge`, `status`, `page` and `size`
+// query params of the given request
+func newTeamFilter(c echo.Context) (model.TeamFilter, error) {
+	filter := model.TeamFilter{
+		AgeCategory: c.QueryParam("category"),
+		Gender:      c.QueryParam("gender"),
+		Challenge:   c.QueryParam("challenge"),
+	}
+	if status := c.QueryParam("status"); status != "" {
+		s, err := model.ParseTeamStatus(status)
+		if err != nil {
+			return filter, err
+		}
+		filter.Status = s
+	}
+	for name, value := range map[string]*int{
+		"page": &filter.Page,
+		"size": &filter.Size,
+	} {
+		if p := c.QueryParam(name); p != "" {
+			v, err := strconv.Atoi(p)
+			if err != nil || v < 1 {
+				return filter, errors.Errorf("invalid '%s' query parameter: '%s'", name, p)
+			}
+			*value = v
+		}
+	}
+	return filter, nil
+}
+
+// SearchTeams returns a handler to search teams by name, member name or club in a given race
+func SearchTeams(svc service.ApplicationService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		scheme := c.Scheme()
+		host := c.Request().Host
+		logrus.Debugf("Processing incoming request on %s://%s%s", scheme, host, c.Request().URL)
+		raceID, err := strconv.Atoi(c.Param("raceID"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert race id '%s' to integer", c.Param("raceID")))
+		}
+		query := strings.TrimSpace(c.QueryParam("q"))
+		if query == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "missing 'q' query parameter")
+		}
+		teams, err := svc.SearchTeams(c.Request().Context(), raceID, query)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			return err
 		}
 		return c.JSON(http.StatusOK, teams)
 	}
 }
 
+// ShowTeam returns a handler to get a single team by its bibnumber in a given race
+func ShowTeam(svc service.ApplicationService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		scheme := c.Scheme()
+		host := c.Request().Host
+		logrus.Debugf("Processing incoming request on %s://%s%s", scheme, host, c.Request().URL)
+		raceID, err := strconv.Atoi(c.Param("raceID"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert race id '%s' to integer", c.Param("raceID")))
+		}
+		bibnumber, err := strconv.Atoi(c.Param("bibnumber"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert bibnumber '%s' to integer", c.Param("bibnumber")))
+		}
+		team, err := svc.GetTeam(c.Request().Context(), raceID, bibnumber)
+		if err != nil {
+			return err
+		}
+		team.Category = service.CategoryLabel(acceptedLocale(c), team.Team.AgeCategory, team.Team.Gender)
+		return c.JSON(http.StatusOK, team)
+	}
+}
+
 // AddFirstLapForAll returns a handler to record the first lap for all teams at once
 func AddFirstLapForAll(svc service.ApplicationService) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -148,9 +362,9 @@ func AddFirstLapForAll(svc service.ApplicationService) echo.HandlerFunc {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert race id '%s' to integer", c.Param("raceID")))
 		}
-		race, err := svc.AddFirstLapForAll(raceID)
+		race, err := svc.AddFirstLapForAll(c.Request().Context(), raceID)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			return err
 		}
 		return c.JSON(http.StatusCreated, race)
 	}
@@ -168,12 +382,34 @@ func AddLap(svc service.ApplicationService) echo.HandlerFunc {
 		}
 		bibnumber, err := strconv.Atoi(c.Param("bibnumber"))
 		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert bidnumber '%s' to integer", c.Param("raceID")))
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert bibnumber '%s' to integer", c.Param("bibnumber")))
 		}
-		team, err := svc.AddLap(raceID, bibnumber)
+		team, err := svc.AddLap(c.Request().Context(), raceID, bibnumber)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			return err
 		}
 		return c.JSON(http.StatusCreated, team)
 	}
 }
+
+// ShowLapAnalytics returns a handler to get the lap analytics of a team in a given race
+func ShowLapAnalytics(svc service.ApplicationService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		scheme := c.Scheme()
+		host := c.Request().Host
+		logrus.Debugf("Processing incoming request on %s://%s%s", scheme, host, c.Request().URL)
+		raceID, err := strconv.Atoi(c.Param("raceID"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert race id '%s' to integer", c.Param("raceID")))
+		}
+		bibnumber, err := strconv.Atoi(c.Param("bibnumber"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to convert bibnumber '%s' to integer", c.Param("bibnumber")))
+		}
+		analytics, err := svc.GetLapAnalytics(c.Request().Context(), raceID, bibnumber)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, analytics)
+	}
+}
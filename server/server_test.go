@@ -1,11 +1,13 @@
 package server_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/vatriathlon/stopwatch/configuration"
@@ -43,26 +45,190 @@ func (s *ServerTestSuite) SetupTest() {
 	s.srv = server.New(s.svc)
 }
 
-func (s *ServerTestSuite) TestStatusEndpoint() {
+func (s *ServerTestSuite) TestReadiness() {
 
 	s.T().Run("ok", func(t *testing.T) {
+		// given
+		req := httptest.NewRequest(echo.GET, server.ReadinessPath, nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
 		// when
-		req := httptest.NewRequest(echo.GET, "http://localhost:8080/api/status", nil)
+		err := server.Readiness(s.svc)(c)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assertConformsToSpec(t, http.MethodGet, server.ReadinessPath, rec)
+		readiness := service.Readiness{}
+		err = json.Unmarshal(rec.Body.Bytes(), &readiness)
+		require.NoError(t, err)
+		assert.Equal(t, service.HealthUp, readiness.Status)
+		require.Len(t, readiness.Checks, 2)
+	})
+
+	s.T().Run("database unreachable", func(t *testing.T) {
+		// given a closed connection
+		config, err := configuration.New()
+		require.NoError(t, err)
+		db, err := gorm.Open("postgres", config.GetPostgresConfigString())
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+		req := httptest.NewRequest(echo.GET, server.ReadinessPath, nil)
 		rec := httptest.NewRecorder()
 		c := s.srv.NewContext(req, rec)
+		// when
+		err = server.Readiness(service.NewApplicationService(db))(c)
 		// then
-		assert.NoError(t, server.Status(c))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assertConformsToSpec(t, http.MethodGet, server.ReadinessPath, rec)
+		readiness := service.Readiness{}
+		err = json.Unmarshal(rec.Body.Bytes(), &readiness)
+		require.NoError(t, err)
+		assert.Equal(t, service.HealthDown, readiness.Status)
+		require.Len(t, readiness.Checks, 2)
+		// the details of the error are not exposed
+		assert.Equal(t, "database unavailable", readiness.Checks[0].Message)
 	})
 }
 
+func (s *ServerTestSuite) TestListEvents() {
+	// given
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	// when
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	rec := httptest.NewRecorder()
+	c := s.srv.NewContext(req, rec)
+	c.SetPath(server.ListEventsPath)
+	err := server.ListEvents(s.svc)(c)
+	// then
+	require.NoError(s.T(), err)
+	assertConformsToSpec(s.T(), http.MethodGet, server.ListEventsPath, rec)
+	var events []model.Event
+	err = json.Unmarshal(rec.Body.Bytes(), &events)
+	require.NoError(s.T(), err)
+	found := false
+	for _, e := range events {
+		if e.ID == event.ID {
+			found = true
+		}
+	}
+	assert.True(s.T(), found)
+}
+
+func (s *ServerTestSuite) TestShowEvent() {
+	// given
+	event := testmodel.CreateEvent(s.T(), s.DB)
+
+	s.T().Run("ok", func(t *testing.T) {
+		// when
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.ShowEventPathTmpl)
+		c.SetParamNames("eventID")
+		c.SetParamValues(strconv.Itoa(event.ID))
+		err := server.ShowEvent(s.svc)(c)
+		// then
+		require.NoError(t, err)
+		assertConformsToSpec(t, http.MethodGet, server.ShowEventPathTmpl, rec)
+		var result model.Event
+		err = json.Unmarshal(rec.Body.Bytes(), &result)
+		require.NoError(t, err)
+		assert.Equal(t, event.Name, result.Name)
+	})
+
+	s.T().Run("not found", func(t *testing.T) {
+		// when
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.ShowEventPathTmpl)
+		c.SetParamNames("eventID")
+		c.SetParamValues("0")
+		err := server.ShowEvent(s.svc)(c)
+		// then
+		require.Error(t, err)
+		assert.True(t, model.IsNotFoundError(err))
+	})
+}
+
+func (s *ServerTestSuite) TestListEventRaces() {
+	// given an event with 2 races and another event with 1 race
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	other := testmodel.CreateEvent(s.T(), s.DB)
+	for _, eventID := range []int{event.ID, event.ID, other.ID} {
+		race := model.Race{
+			EventID: eventID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+		}
+		err := raceRepo.Create(&race)
+		require.NoError(s.T(), err)
+	}
+	// when
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	rec := httptest.NewRecorder()
+	c := s.srv.NewContext(req, rec)
+	c.SetPath(server.ListEventRacesPathTmpl)
+	c.SetParamNames("eventID")
+	c.SetParamValues(strconv.Itoa(event.ID))
+	err := server.ListEventRaces(s.svc)(c)
+	// then
+	require.NoError(s.T(), err)
+	assertConformsToSpec(s.T(), http.MethodGet, server.ListEventRacesPathTmpl, rec)
+	var races []model.Race
+	err = json.Unmarshal(rec.Body.Bytes(), &races)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), races, 2)
+	for _, race := range races {
+		assert.Equal(s.T(), event.ID, race.EventID)
+	}
+}
+
+func (s *ServerTestSuite) TestListChallenges() {
+	// given a race with 2 challenges
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	challengeRepo := model.NewChallengeRepository(s.DB)
+	for _, name := range []string{"Challenge Entreprise", "Challenge Famille"} {
+		err := challengeRepo.Create(&model.Challenge{RaceID: race.ID, Name: name})
+		require.NoError(s.T(), err)
+	}
+	// when
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	rec := httptest.NewRecorder()
+	c := s.srv.NewContext(req, rec)
+	c.SetPath(server.ListChallengesPathTmpl)
+	c.SetParamNames("raceID")
+	c.SetParamValues(strconv.Itoa(race.ID))
+	err = server.ListChallenges(s.svc)(c)
+	// then
+	require.NoError(s.T(), err)
+	assertConformsToSpec(s.T(), http.MethodGet, server.ListChallengesPathTmpl, rec)
+	var challenges []model.Challenge
+	err = json.Unmarshal(rec.Body.Bytes(), &challenges)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), challenges, 2)
+	assert.Equal(s.T(), "Challenge Entreprise", challenges[0].Name)
+	assert.Equal(s.T(), "Challenge Famille", challenges[1].Name)
+}
+
 func (s *ServerTestSuite) TestListRaces() {
 
 	s.T().Run("ok", func(t *testing.T) {
 		// given 3 races
 		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
 		for i := 0; i < 5; i++ {
 			race := model.Race{
-				Name: fmt.Sprintf("race %s", uuid.NewV4()),
+				EventID: event.ID,
+				Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 			}
 			err := raceRepo.Create(&race)
 			require.NoError(t, err)
@@ -75,6 +241,7 @@ func (s *ServerTestSuite) TestListRaces() {
 		err := server.ListRaces(s.svc)(c)
 		// then
 		require.NoError(t, err)
+		assertConformsToSpec(t, http.MethodGet, "/api/races", rec)
 		var races interface{}
 		err = json.Unmarshal(rec.Body.Bytes(), &races)
 		require.NoError(t, err)
@@ -82,13 +249,71 @@ func (s *ServerTestSuite) TestListRaces() {
 		assert.True(t, len(races.([]interface{})) >= 5) // in case there are already other races in the DB
 	})
 }
+func (s *ServerTestSuite) TestUpdateRace() {
+
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.UpdateRacePathTmpl)
+		c.SetParamNames("raceID")
+		c.SetParamValues(strconv.Itoa(race.ID))
+		return c, rec
+	}
+
+	s.T().Run("start", func(t *testing.T) {
+		// when
+		c, rec := newContext(`{"status":"started"}`)
+		err := server.UpdateRace(s.svc)(c)
+		// then
+		require.NoError(t, err)
+		assertConformsToSpec(t, http.MethodPatch, server.UpdateRacePathTmpl, rec)
+		var result map[string]interface{}
+		err = json.Unmarshal(rec.Body.Bytes(), &result)
+		require.NoError(t, err)
+		assert.NotEqual(t, "0001-01-01T00:00:00Z", result["StartTime"])
+	})
+
+	s.T().Run("start twice", func(t *testing.T) {
+		// when
+		c, _ := newContext(`{"status":"started"}`)
+		err := server.UpdateRace(s.svc)(c)
+		// then
+		require.Error(t, err)
+		status, body := server.NewError(err)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, model.ErrCodeRaceAlreadyStarted, body.Code)
+	})
+
+	s.T().Run("unknown field", func(t *testing.T) {
+		// when
+		c, _ := newContext(`{"state":"ended"}`)
+		err := server.UpdateRace(s.svc)(c)
+		// then
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	})
+}
+
 func (s *ServerTestSuite) TestListTeams() {
 
 	s.T().Run("ok", func(t *testing.T) {
 		// given 3 teams in a race
 		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
 		race := model.Race{
-			Name: fmt.Sprintf("race %s", uuid.NewV4()),
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 		}
 		err := raceRepo.Create(&race)
 		require.NoError(t, err)
@@ -109,6 +334,7 @@ func (s *ServerTestSuite) TestListTeams() {
 		err = server.ListTeams(s.svc)(c)
 		// then
 		require.NoError(t, err)
+		assertConformsToSpec(t, http.MethodGet, server.ListTeamsPathTmpl, rec)
 		var teams interface{}
 		err = json.Unmarshal(rec.Body.Bytes(), &teams)
 		require.NoError(t, err)
@@ -116,6 +342,56 @@ func (s *ServerTestSuite) TestListTeams() {
 		teams = teams.([]interface{})
 		require.Len(t, teams, 5)
 	})
+
+	s.T().Run("summaries", func(t *testing.T) {
+		// given
+		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
+		race := model.Race{
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+		}
+		err := raceRepo.Create(&race)
+		require.NoError(t, err)
+		teamRepo := model.NewTeamRepository(s.DB)
+		for i := 1; i < 6; i++ {
+			team := testmodel.NewTeam(race.ID, i)
+			err := teamRepo.Create(&team)
+			require.NoError(t, err)
+		}
+		// when
+		req := httptest.NewRequest(echo.GET, "/?view=summary&status=not_started&page=2&size=3", nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.ListTeamsPathTmpl)
+		c.SetParamNames("raceID")
+		c.SetParamValues(strconv.Itoa(race.ID))
+		err = server.ListTeams(s.svc)(c)
+		// then
+		require.NoError(t, err)
+		assertConformsToSpec(t, http.MethodGet, server.ListTeamsPathTmpl, rec)
+		var teams []map[string]interface{}
+		err = json.Unmarshal(rec.Body.Bytes(), &teams)
+		require.NoError(t, err)
+		require.Len(t, teams, 2)
+		assert.Equal(t, float64(4), teams[0]["BibNumber"])
+		assert.Equal(t, float64(0), teams[0]["LapCount"])
+		assert.NotContains(t, teams[0], "Laps")
+	})
+
+	s.T().Run("invalid filter", func(t *testing.T) {
+		// when
+		req := httptest.NewRequest(echo.GET, "/?status=foo", nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.ListTeamsPathTmpl)
+		c.SetParamNames("raceID")
+		c.SetParamValues("1")
+		err := server.ListTeams(s.svc)(c)
+		// then
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	})
 }
 
 func (s *ServerTestSuite) TestAddLap() {
@@ -123,8 +399,10 @@ func (s *ServerTestSuite) TestAddLap() {
 	s.T().Run("ok", func(t *testing.T) {
 		// given
 		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
 		race := model.Race{
-			Name: "foo",
+			EventID: event.ID,
+			Name:    "foo",
 		}
 		err := raceRepo.Create(&race)
 		require.NoError(t, err)
@@ -142,7 +420,196 @@ func (s *ServerTestSuite) TestAddLap() {
 		err = server.AddLap(s.svc)(c)
 		// then
 		require.NoError(t, err)
+		assertConformsToSpec(t, http.MethodPost, server.AddLapPathTmpl, rec)
 		assert.Equal(t, http.StatusCreated, rec.Code)
 	})
 
+	s.T().Run("invalid bibnumber", func(t *testing.T) {
+		// when
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.AddLapPathTmpl)
+		c.SetParamNames("raceID", "bibnumber")
+		c.SetParamValues("1", "foo")
+		err := server.AddLap(s.svc)(c)
+		// then
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+		assert.Equal(t, "unable to convert bibnumber 'foo' to integer", err.(*echo.HTTPError).Message)
+	})
+}
+
+func (s *ServerTestSuite) TestShowLapAnalytics() {
+
+	s.T().Run("ok", func(t *testing.T) {
+		// given
+		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
+		race := model.Race{
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+		}
+		err := raceRepo.Create(&race)
+		require.NoError(t, err)
+		teamRepo := model.NewTeamRepository(s.DB)
+		team := testmodel.NewTeam(race.ID, 1)
+		err = teamRepo.Create(&team)
+		require.NoError(t, err)
+		_, err = s.svc.StartRace(context.Background(), race.ID)
+		require.NoError(t, err)
+		_, err = s.svc.AddLap(context.Background(), race.ID, team.BibNumber)
+		require.NoError(t, err)
+		// when
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.ShowLapAnalyticsPathTmpl)
+		c.SetParamNames("raceID", "bibnumber")
+		c.SetParamValues(strconv.Itoa(race.ID), strconv.Itoa(team.BibNumber))
+		err = server.ShowLapAnalytics(s.svc)(c)
+		// then
+		require.NoError(t, err)
+		assertConformsToSpec(t, http.MethodGet, server.ShowLapAnalyticsPathTmpl, rec)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var analytics map[string]interface{}
+		err = json.Unmarshal(rec.Body.Bytes(), &analytics)
+		require.NoError(t, err)
+		assert.Equal(t, float64(team.BibNumber), analytics["bibNumber"])
+		assert.Len(t, analytics["laps"], 1)
+	})
+}
+
+func (s *ServerTestSuite) TestShowTeam() {
+
+	s.T().Run("ok", func(t *testing.T) {
+		// given
+		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
+		race := model.Race{
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+		}
+		err := raceRepo.Create(&race)
+		require.NoError(t, err)
+		teamRepo := model.NewTeamRepository(s.DB)
+		team := testmodel.NewTeam(race.ID, 1)
+		err = teamRepo.Create(&team)
+		require.NoError(t, err)
+		// when
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.ShowTeamPathTmpl)
+		c.SetParamNames("raceID", "bibnumber")
+		c.SetParamValues(strconv.Itoa(race.ID), strconv.Itoa(team.BibNumber))
+		err = server.ShowTeam(s.svc)(c)
+		// then
+		require.NoError(t, err)
+		assertConformsToSpec(t, http.MethodGet, server.ShowTeamPathTmpl, rec)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var detail map[string]interface{}
+		err = json.Unmarshal(rec.Body.Bytes(), &detail)
+		require.NoError(t, err)
+		assert.Equal(t, string(model.TeamNotStarted), detail["status"])
+		assert.Equal(t, float64(0), detail["rank"])
+		assert.Equal(t, "Seniors / Mixte", detail["category"])
+	})
+
+	s.T().Run("accept language", func(t *testing.T) {
+		// given
+		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
+		race := model.Race{
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+		}
+		err := raceRepo.Create(&race)
+		require.NoError(t, err)
+		team := testmodel.NewTeam(race.ID, 1)
+		err = model.NewTeamRepository(s.DB).Create(&team)
+		require.NoError(t, err)
+
+		testcases := map[string]struct {
+			acceptLanguage string
+			locale         string
+			category       string
+		}{
+			"supported language":           {acceptLanguage: "en", locale: "en", category: "Seniors / Mixed"},
+			"regional variant":             {acceptLanguage: "de-CH", locale: "de", category: "Hauptklasse / Mixed"},
+			"preferred supported language": {acceptLanguage: "it;q=1.0, en;q=0.5, de;q=0.8", locale: "de", category: "Hauptklasse / Mixed"},
+			"no supported language":        {acceptLanguage: "it, es;q=0.5", locale: "fr", category: "Seniors / Mixte"},
+		}
+		for testname, testdata := range testcases {
+			t.Run(testname, func(t *testing.T) {
+				// when
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Accept-Language", testdata.acceptLanguage)
+				rec := httptest.NewRecorder()
+				c := s.srv.NewContext(req, rec)
+				c.SetPath(server.ShowTeamPathTmpl)
+				c.SetParamNames("raceID", "bibnumber")
+				c.SetParamValues(strconv.Itoa(race.ID), strconv.Itoa(team.BibNumber))
+				err = server.ShowTeam(s.svc)(c)
+				// then
+				require.NoError(t, err)
+				assertConformsToSpec(t, http.MethodGet, server.ShowTeamPathTmpl, rec)
+				assert.Equal(t, testdata.locale, rec.Header().Get("Content-Language"))
+				var detail map[string]interface{}
+				err = json.Unmarshal(rec.Body.Bytes(), &detail)
+				require.NoError(t, err)
+				assert.Equal(t, testdata.category, detail["category"])
+			})
+		}
+	})
+}
+
+func (s *ServerTestSuite) TestSearchTeams() {
+
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	teamRepo := model.NewTeamRepository(s.DB)
+	team := testmodel.NewTeam(race.ID, 1)
+	team.Name = "Les Vétérans"
+	err = teamRepo.Create(&team)
+	require.NoError(s.T(), err)
+
+	s.T().Run("ok", func(t *testing.T) {
+		// when
+		req := httptest.NewRequest(http.MethodGet, "/?q=V%C3%A9t%C3%A9ran", nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.SearchTeamsPathTmpl)
+		c.SetParamNames("raceID")
+		c.SetParamValues(strconv.Itoa(race.ID))
+		err = server.SearchTeams(s.svc)(c)
+		// then
+		require.NoError(t, err)
+		assertConformsToSpec(t, http.MethodGet, server.SearchTeamsPathTmpl, rec)
+		var teams []interface{}
+		err = json.Unmarshal(rec.Body.Bytes(), &teams)
+		require.NoError(t, err)
+		require.Len(t, teams, 1)
+	})
+
+	s.T().Run("missing query", func(t *testing.T) {
+		// when
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := s.srv.NewContext(req, rec)
+		c.SetPath(server.SearchTeamsPathTmpl)
+		c.SetParamNames("raceID")
+		c.SetParamValues(strconv.Itoa(race.ID))
+		err = server.SearchTeams(s.svc)(c)
+		// then
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	})
 }
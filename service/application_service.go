@@ -1,10 +1,14 @@
 package service
 
 import (
+	"context"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
+	"github.com/vatriathlon/stopwatch/metrics"
 	"github.com/vatriathlon/stopwatch/model"
 )
 
@@ -21,9 +25,9 @@ func NewApplicationService(db *gorm.DB) ApplicationService {
 }
 
 // ListRaces list the races.
-func (s *ApplicationService) ListRaces() ([]model.Race, error) {
+func (s *ApplicationService) ListRaces(ctx context.Context) ([]model.Race, error) {
 	var result []model.Race
-	err := Transactional(s.baseService, func(app Repositories) error {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
 		var err error
 		result, err = app.Races().List()
 		return err
@@ -34,10 +38,154 @@ func (s *ApplicationService) ListRaces() ([]model.Race, error) {
 	return result, nil
 }
 
+// ListEvents lists the events, from the most recent to the oldest one
+func (s *ApplicationService) ListEvents(ctx context.Context) ([]model.Event, error) {
+	var result []model.Event
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		result, err = app.Events().List()
+		return err
+	})
+	if err != nil {
+		return result, errors.Wrap(err, "unable to list events")
+	}
+	return result, nil
+}
+
+// GetEvent returns the event with the given ID
+func (s *ApplicationService) GetEvent(ctx context.Context, id int) (model.Event, error) {
+	var result model.Event
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		result, err = app.Events().Lookup(id)
+		return err
+	})
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to get event with id=%d", id)
+	}
+	return result, nil
+}
+
+// CreateEvent creates the given event
+func (s *ApplicationService) CreateEvent(ctx context.Context, event model.Event) (model.Event, error) {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		return app.Events().Create(&event)
+	})
+	if err != nil {
+		return event, errors.Wrapf(err, "unable to create event '%s'", event.Name)
+	}
+	return event, nil
+}
+
+// ListEventRaces lists the races of the event with the given ID
+func (s *ApplicationService) ListEventRaces(ctx context.Context, eventID int) ([]model.Race, error) {
+	var result []model.Race
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		_, err := app.Events().Lookup(eventID)
+		if err != nil {
+			return err
+		}
+		result, err = app.Races().ListByEvent(eventID)
+		return err
+	})
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to list races of event with id=%d", eventID)
+	}
+	return result, nil
+}
+
+// ListChampionships lists the championships, by name
+func (s *ApplicationService) ListChampionships(ctx context.Context) ([]model.Championship, error) {
+	var result []model.Championship
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		result, err = app.Championships().List()
+		return err
+	})
+	if err != nil {
+		return result, errors.Wrap(err, "unable to list championships")
+	}
+	return result, nil
+}
+
+// CreateChampionship creates the given championship
+func (s *ApplicationService) CreateChampionship(ctx context.Context, championship model.Championship) (model.Championship, error) {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		return app.Championships().Create(&championship)
+	})
+	if err != nil {
+		return championship, errors.Wrapf(err, "unable to create championship '%s'", championship.Name)
+	}
+	return championship, nil
+}
+
+// AddChampionshipRace adds the race with the given ID to the championship with the given ID
+func (s *ApplicationService) AddChampionshipRace(ctx context.Context, championshipID, raceID int) error {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		championship, err := app.Championships().Lookup(championshipID)
+		if err != nil {
+			return err
+		}
+		race, err := app.Races().Lookup(raceID)
+		if err != nil {
+			return err
+		}
+		return app.Championships().AddRace(championship, race)
+	})
+	return errors.Wrapf(err, "unable to add race with id=%d to championship with id=%d", raceID, championshipID)
+}
+
+// CreateRace creates the given race in its event
+func (s *ApplicationService) CreateRace(ctx context.Context, race model.Race) (model.Race, error) {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		if race.EventID != 0 {
+			if _, err := app.Events().Lookup(race.EventID); err != nil {
+				return err
+			}
+		}
+		return app.Races().Create(&race)
+	})
+	if err != nil {
+		return race, errors.Wrapf(err, "unable to create race '%s'", race.Name)
+	}
+	return race, nil
+}
+
+// ListChallenges lists the challenges declared in the race with the given ID
+func (s *ApplicationService) ListChallenges(ctx context.Context, raceID int) ([]model.Challenge, error) {
+	var result []model.Challenge
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		_, err := app.Races().Lookup(raceID)
+		if err != nil {
+			return err
+		}
+		result, err = app.Challenges().List(raceID)
+		return err
+	})
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to list challenges of race with id=%d", raceID)
+	}
+	return result, nil
+}
+
+// CreateChallenge declares the given challenge in its race
+func (s *ApplicationService) CreateChallenge(ctx context.Context, challenge model.Challenge) (model.Challenge, error) {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		if _, err := app.Races().Lookup(challenge.RaceID); err != nil {
+			return err
+		}
+		return app.Challenges().Create(&challenge)
+	})
+	if err != nil {
+		return challenge, errors.Wrapf(err, "unable to create challenge '%s'", challenge.Name)
+	}
+	return challenge, nil
+}
+
 // GetRace get the race given its ID
-func (s *ApplicationService) GetRace(id int) (model.Race, error) {
+func (s *ApplicationService) GetRace(ctx context.Context, id int) (model.Race, error) {
 	var result model.Race
-	err := Transactional(s.baseService, func(app Repositories) error {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
 		var err error
 		result, err = app.Races().Lookup(id)
 		return err
@@ -49,57 +197,118 @@ func (s *ApplicationService) GetRace(id int) (model.Race, error) {
 }
 
 // StartRace set the current race to the one matching the given name
-func (s *ApplicationService) StartRace(raceID int) (model.Race, error) {
+func (s *ApplicationService) StartRace(ctx context.Context, raceID int) (model.Race, error) {
+	started := RaceStarted
+	return s.UpdateRace(ctx, raceID, RaceUpdate{Status: &started})
+}
+
+// EndRace ends the race with the given ID
+func (s *ApplicationService) EndRace(ctx context.Context, raceID int) (model.Race, error) {
+	ended := RaceEnded
+	return s.UpdateRace(ctx, raceID, RaceUpdate{Status: &ended})
+}
+
+// RaceStatus the status of a race, as requested in a RaceUpdate
+type RaceStatus string
+
+const (
+	// RaceStarted the status to start a race
+	RaceStarted RaceStatus = "started"
+	// RaceEnded the status to end a race
+	RaceEnded RaceStatus = "ended"
+)
+
+// RaceUpdate a partial update of a race. Only the non-nil fields are changed.
+type RaceUpdate struct {
+	// Status the new status of the race: `started` to start it now, `ended` to end it now
+	Status *RaceStatus `json:"status"`
+	Name   *string     `json:"name"`
+	// AllowsFirstLap 'true' if the first lap can be recorded for all teams at once. Cannot be changed once the
+	// first lap was recorded
+	AllowsFirstLap *bool `json:"allowsFirstLap"`
+	// PlannedStartTime the time at which the race is planned to start. Cannot be changed once the race started
+	PlannedStartTime *time.Time `json:"plannedStartTime"`
+}
+
+// UpdateRace applies the given update on the race with the given ID. The changes are validated against the current
+// state of the race: only the name of an ended race can be changed.
+func (s *ApplicationService) UpdateRace(ctx context.Context, raceID int, update RaceUpdate) (model.Race, error) {
 	var race model.Race
-	err := Transactional(s.baseService, func(app Repositories) error {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
 		var err error
 		race, err = app.Races().Lookup(raceID)
 		if err != nil {
 			return err
 		}
-		if race.IsStarted() {
-			return errors.Errorf("current race already started at %v", race.StartTimeStr())
+		if err = applyRaceUpdate(&race, update, model.Now()); err != nil {
+			return err
 		}
-		race.StartTime = time.Now()
 		return app.Races().Save(&race)
 	})
 	if err != nil {
-		return race, errors.Wrap(err, "unable to start race")
+		return race, errors.Wrap(err, "unable to update race")
 	}
 	return race, nil
 }
 
-// TODO: is it usefull? (+needs to be updated to the service code)
-// End marks the given race as ended (now)
-// func (r *GormRaceRepository) End(race *Race) error {
-// 	// check values
-// 	if !race.IsStarted() {
-// 		return errors.New("race has not started yet")
-// 	}
-// 	if race.IsEnded() {
-// 		return errors.Errorf("race already ended at %v", race.EndTime.Format(raceTimeFmt))
-// 	}
-// 	race.EndTime = time.Now()
-// 	db := r.db.Save(race)
-// 	if err := db.Error; err != nil {
-// 		return errors.Wrap(err, "fail to save race in DB")
-// 	}
-// 	return nil
-// }
+// applyRaceUpdate applies the given update on the given race, at the given time
+func applyRaceUpdate(race *model.Race, update RaceUpdate, now time.Time) error {
+	if update.Status == nil && update.Name == nil && update.AllowsFirstLap == nil && update.PlannedStartTime == nil {
+		return model.NewValidationError(model.ErrCodeInvalidRace, "nothing to update")
+	}
+	if update.Name != nil {
+		if strings.TrimSpace(*update.Name) == "" {
+			return model.NewValidationError(model.ErrCodeInvalidRace, "race name is missing")
+		}
+		race.Name = strings.TrimSpace(*update.Name)
+	}
+	if race.IsEnded() && (update.Status != nil || update.AllowsFirstLap != nil || update.PlannedStartTime != nil) {
+		return model.NewStateError(model.ErrCodeRaceAlreadyEnded, "race already ended at %v", race.EndTimeStr())
+	}
+	if update.AllowsFirstLap != nil {
+		if race.HasFirstLap {
+			return model.NewStateError(model.ErrCodeFirstLapAlreadyRecorded, "first lap already recorded")
+		}
+		race.AllowsFirstLap = *update.AllowsFirstLap
+	}
+	if update.PlannedStartTime != nil {
+		if race.IsStarted() {
+			return model.NewStateError(model.ErrCodeRaceAlreadyStarted, "race already started at %v", race.StartTimeStr())
+		}
+		race.PlannedStartTime = *update.PlannedStartTime
+	}
+	if update.Status != nil {
+		switch *update.Status {
+		case RaceStarted:
+			if race.IsStarted() {
+				return model.NewStateError(model.ErrCodeRaceAlreadyStarted, "race already started at %v", race.StartTimeStr())
+			}
+			race.StartTime = now
+		case RaceEnded:
+			if !race.IsStarted() {
+				return model.NewStateError(model.ErrCodeRaceNotStarted, "race has not started yet")
+			}
+			race.EndTime = now
+		default:
+			return model.NewValidationError(model.ErrCodeInvalidRace, "invalid race status: '%s'", *update.Status)
+		}
+	}
+	return nil
+}
 
 // AddFirstLapForAll set the current race to the one matching the given name
-func (s *ApplicationService) AddFirstLapForAll(raceID int) (model.Race, error) {
+func (s *ApplicationService) AddFirstLapForAll(ctx context.Context, raceID int) (model.Race, error) {
 	var race model.Race
-	err := Transactional(s.baseService, func(app Repositories) error {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
 		var err error
 		race, err = app.Races().Lookup(raceID)
 		if err != nil {
 			return err
 		}
 		if !race.AllowsFirstLap || race.HasFirstLap {
-			return errors.New("first lap already recorded")
+			return model.NewStateError(model.ErrCodeFirstLapNotAllowed, "first lap already recorded")
 		}
-		lapTime := time.Now()
+		lapTime := model.Now()
 		teams, err := app.Teams().List(race.ID)
 		if err != nil {
 			return err
@@ -123,12 +332,12 @@ func (s *ApplicationService) AddFirstLapForAll(raceID int) (model.Race, error) {
 	return race, nil
 }
 
-// ListTeams list the teams for the current race.
-func (s *ApplicationService) ListTeams(raceID int) ([]model.Team, error) {
+// ListTeams list the teams for the current race which match the given filter.
+func (s *ApplicationService) ListTeams(ctx context.Context, raceID int, filter model.TeamFilter) ([]model.Team, error) {
 	var result []model.Team
-	err := Transactional(s.baseService, func(app Repositories) error {
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
 		var err error
-		result, err = app.Teams().List(raceID)
+		result, err = app.Teams().Find(raceID, filter)
 		return err
 	})
 	if err != nil {
@@ -137,28 +346,181 @@ func (s *ApplicationService) ListTeams(raceID int) ([]model.Team, error) {
 	return result, nil
 }
 
+// ListTeamSummaries list the summaries of the teams for the current race which match the given filter.
+func (s *ApplicationService) ListTeamSummaries(ctx context.Context, raceID int, filter model.TeamFilter) ([]model.TeamSummary, error) {
+	var result []model.TeamSummary
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		result, err = app.Teams().Summaries(raceID, filter)
+		return err
+	})
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to list team summaries in race")
+	}
+	return result, nil
+}
+
+var minLapInterval time.Duration
+
+// SetMinLapInterval sets the minimum duration between 2 laps of a team. A lap recorded sooner after the previous one
+// is rejected as a duplicate. A zero duration disables the check.
+func SetMinLapInterval(d time.Duration) {
+	minLapInterval = d
+}
+
 // AddLap record a new lap at the current time for the teams with given bib numbers
-func (s *ApplicationService) AddLap(raceID int, bibnumber int) (model.Team, error) {
+func (s *ApplicationService) AddLap(ctx context.Context, raceID int, bibnumber int) (model.Team, error) {
 	var team model.Team
-	err := Transactional(s.baseService, func(app Repositories) error {
-		teamID, err := app.Teams().FindIDByBibNumber(raceID, bibnumber)
+	// the race of the metrics, once it is known to exist
+	race := metrics.UnknownRace
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		team, err = app.Teams().LoadByBibNumber(raceID, bibnumber)
 		if err != nil {
 			return err
 		}
-		err = app.Laps().Create(&model.Lap{
+		// the race exists, since the team was found in it
+		race = strconv.Itoa(raceID)
+		lap := model.Lap{
 			RaceID: raceID,
-			TeamID: teamID,
-			Time:   time.Now(),
-		})
+			TeamID: team.ID,
+			Time:   model.Now(),
+		}
+		for _, l := range team.Laps {
+			if minLapInterval > 0 && lap.Time.Sub(l.Time) < minLapInterval {
+				return model.NewConflictError(model.ErrCodeDuplicateLap, "lap of team with bibnumber '%d' already recorded at %s",
+					bibnumber, l.Time.Format("15:04:05 MST"))
+			}
+		}
+		err = app.Laps().Create(&lap)
 		if err != nil {
 			return err
 		}
-		team, err = app.Teams().LoadByBibNumber(raceID, bibnumber)
-		return err
+		team.Laps = append(team.Laps, lap)
+		return nil
 	})
 	if err != nil {
+		reason := "unknown"
+		if e, ok := errors.Cause(err).(model.CodedError); ok {
+			reason = string(e.Code())
+		}
+		metrics.RejectLap(race, reason)
 		return team, errors.Wrapf(err, "unable to add laps to team")
 	}
+	metrics.RecordLap(raceID)
+	return team, nil
+}
 
+// DeleteLap deletes the lap with the given ID of the team with the given bibnumber in the given race,
+// or the last lap of the team if lapID is 0. Returns the team with its remaining laps
+func (s *ApplicationService) DeleteLap(ctx context.Context, raceID int, bibnumber int, lapID int) (model.Team, error) {
+	var team model.Team
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		team, err = app.Teams().LoadByBibNumber(raceID, bibnumber)
+		if err != nil {
+			return err
+		}
+		index := -1
+		for i, l := range team.Laps {
+			switch {
+			case lapID != 0 && l.ID == lapID:
+				index = i
+			case lapID == 0 && (index < 0 || l.Time.After(team.Laps[index].Time)):
+				index = i
+			}
+		}
+		if index < 0 {
+			if lapID == 0 {
+				return model.NewStateError(model.ErrCodeNoLap, "team with bibnumber '%d' has no lap", bibnumber)
+			}
+			return model.NewNotFoundError("lap", lapID)
+		}
+		err = app.Laps().Delete(team.Laps[index])
+		if err != nil {
+			return err
+		}
+		team.Laps = append(team.Laps[:index], team.Laps[index+1:]...)
+		return nil
+	})
+	if err != nil {
+		return team, errors.Wrapf(err, "unable to delete lap of team")
+	}
 	return team, nil
 }
+
+// GetLapAnalytics computes the analytics of the laps of the team with the given bib number in the given race
+func (s *ApplicationService) GetLapAnalytics(ctx context.Context, raceID int, bibnumber int) (LapAnalytics, error) {
+	var result LapAnalytics
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		race, err := app.Races().Lookup(raceID)
+		if err != nil {
+			return err
+		}
+		team, err := app.Teams().LoadByBibNumber(raceID, bibnumber)
+		if err != nil {
+			return err
+		}
+		result = NewLapAnalytics(race, team.BibNumber, team.Laps)
+		return nil
+	})
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to compute lap analytics of team")
+	}
+	return result, nil
+}
+
+// TeamDetail the details of a team in a race: its members, status, current rank and laps
+type TeamDetail struct {
+	Team   model.Team       `json:"team"`
+	Status model.TeamStatus `json:"status"`
+	// Rank the current (scratch) rank of the team, or 0 if the team has not recorded any lap yet
+	Rank int        `json:"rank"`
+	Laps []LapSplit `json:"laps"`
+	// Category the label of the age and gender category of the team, in the language of the client
+	Category string `json:"category"`
+}
+
+// GetTeam returns the details of the team with the given bib number in the given race
+func (s *ApplicationService) GetTeam(ctx context.Context, raceID int, bibnumber int) (TeamDetail, error) {
+	var result TeamDetail
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		race, err := app.Races().Lookup(raceID)
+		if err != nil {
+			return err
+		}
+		team, err := app.Teams().LoadByBibNumber(raceID, bibnumber)
+		if err != nil {
+			return err
+		}
+		rank, err := app.Teams().Rank(raceID, bibnumber)
+		if err != nil {
+			return err
+		}
+		result = TeamDetail{
+			Team:   team,
+			Status: team.Status(race),
+			Rank:   rank,
+			Laps:   NewLapAnalytics(race, team.BibNumber, team.Laps).Laps,
+		}
+		return nil
+	})
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to get team with bibnumber=%d", bibnumber)
+	}
+	return result, nil
+}
+
+// SearchTeams list the teams in the given race whose name, member names or clubs match the given query
+func (s *ApplicationService) SearchTeams(ctx context.Context, raceID int, query string) ([]model.Team, error) {
+	var result []model.Team
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		result, err = app.Teams().Search(raceID, query)
+		return err
+	})
+	if err != nil {
+		return result, errors.Wrapf(err, "unable to search teams in race")
+	}
+	return result, nil
+}
@@ -1,8 +1,10 @@
 package service_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/vatriathlon/stopwatch/configuration"
 	"github.com/vatriathlon/stopwatch/model"
@@ -29,14 +31,16 @@ type AppServiceTestSuite struct {
 func (s *AppServiceTestSuite) TestListRacesNoResult() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	race1 := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race1)
 	require.NoError(s.T(), err)
 	svc := service.NewApplicationService(s.DB)
 	// when
-	races, err := svc.ListRaces()
+	races, err := svc.ListRaces(context.Background())
 	// then
 	require.NoError(s.T(), err)
 	assert.Len(s.T(), races, 1)
@@ -45,19 +49,22 @@ func (s *AppServiceTestSuite) TestListRacesNoResult() {
 func (s *AppServiceTestSuite) TestListRacesMultipleResults() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	race1 := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race1)
 	require.NoError(s.T(), err)
 	race2 := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	err = raceRepo.Create(&race2)
 	require.NoError(s.T(), err)
 	svc := service.NewApplicationService(s.DB)
 	// when
-	races, err := svc.ListRaces()
+	races, err := svc.ListRaces(context.Background())
 	// then
 	require.NoError(s.T(), err)
 	assert.Len(s.T(), races, 2)
@@ -67,14 +74,16 @@ func (s *AppServiceTestSuite) TestGetRace() {
 	s.T().Run("ok", func(t *testing.T) {
 		// given
 		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
 		race := model.Race{
-			Name: fmt.Sprintf("race %s", uuid.NewV4()),
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 		}
 		err := raceRepo.Create(&race)
 		require.NoError(t, err)
 		svc := service.NewApplicationService(s.DB)
 		// when
-		result, err := svc.GetRace(race.ID)
+		result, err := svc.GetRace(context.Background(), race.ID)
 		// then
 		require.NoError(t, err)
 		assert.Equal(t, race.Name, result.Name)
@@ -84,9 +93,10 @@ func (s *AppServiceTestSuite) TestGetRace() {
 		// given
 		svc := service.NewApplicationService(s.DB)
 		// when
-		_, err := svc.GetRace(-1)
+		_, err := svc.GetRace(context.Background(), -1)
 		// then
 		require.Error(t, err)
+		assert.True(t, model.IsNotFoundError(err))
 	})
 
 }
@@ -96,8 +106,10 @@ func (s *AppServiceTestSuite) TestListTeams() {
 	s.T().Run("ok", func(t *testing.T) {
 		// given
 		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
 		race := model.Race{
-			Name: fmt.Sprintf("race %s", uuid.NewV4()),
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 		}
 		err := raceRepo.Create(&race)
 		require.NoError(t, err)
@@ -110,7 +122,7 @@ func (s *AppServiceTestSuite) TestListTeams() {
 		require.NoError(t, err)
 		svc := service.NewApplicationService(s.DB)
 		// when
-		teams, err := svc.ListTeams(race.ID)
+		teams, err := svc.ListTeams(context.Background(), race.ID, model.TeamFilter{})
 		// then
 		require.NoError(t, err)
 		assert.Len(t, teams, 5)
@@ -120,21 +132,23 @@ func (s *AppServiceTestSuite) TestListTeams() {
 func (s *AppServiceTestSuite) TestStartRace() {
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	svc := service.NewApplicationService(s.DB)
 
 	s.T().Run("ok", func(t *testing.T) {
 		// given
 		race := model.Race{
-			Name: fmt.Sprintf("race %s", uuid.NewV4()),
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 		}
 		err := raceRepo.Create(&race)
 		require.NoError(t, err)
 		// when
-		_, err = svc.StartRace(race.ID)
+		_, err = svc.StartRace(context.Background(), race.ID)
 		// then
 		require.NoError(t, err)
 		// verify the start time
-		result, err := raceRepo.FindByName(race.Name)
+		result, err := raceRepo.FindByName(event.ID, race.Name)
 		require.NoError(s.T(), err)
 		require.True(t, result.IsStarted())
 		assert.False(s.T(), result.StartTime.IsZero())
@@ -146,16 +160,164 @@ func (s *AppServiceTestSuite) TestStartRace() {
 		t.Run("already started", func(t *testing.T) {
 			// given
 			race := model.Race{
-				Name: fmt.Sprintf("race %s", uuid.NewV4()),
+				EventID: event.ID,
+				Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 			}
 			err := raceRepo.Create(&race)
 			require.NoError(t, err)
-			_, err = svc.StartRace(race.ID)
+			_, err = svc.StartRace(context.Background(), race.ID)
 			require.NoError(t, err)
 			// when
-			_, err = svc.StartRace(race.ID)
+			_, err = svc.StartRace(context.Background(), race.ID)
 			// then
 			require.Error(t, err)
+			assert.True(t, model.IsStateError(err))
+		})
+	})
+}
+
+func (s *AppServiceTestSuite) TestUpdateRace() {
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	svc := service.NewApplicationService(s.DB)
+	newRace := func(t *testing.T) model.Race {
+		race := model.Race{
+			EventID: event.ID,
+			Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+		}
+		err := raceRepo.Create(&race)
+		require.NoError(t, err)
+		return race
+	}
+	started := service.RaceStarted
+	ended := service.RaceEnded
+	yes := true
+
+	s.T().Run("ok", func(t *testing.T) {
+
+		t.Run("lifecycle", func(t *testing.T) {
+			// given
+			race := newRace(t)
+			plannedStartTime := time.Date(2019, 3, 17, 10, 0, 0, 0, time.UTC)
+			name := race.Name + " (modifiée)"
+			// when
+			_, err := svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{
+				Name:             &name,
+				AllowsFirstLap:   &yes,
+				PlannedStartTime: &plannedStartTime,
+			})
+			require.NoError(t, err)
+			_, err = svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &started})
+			require.NoError(t, err)
+			result, err := svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &ended})
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, name, result.Name)
+			assert.True(t, result.AllowsFirstLap)
+			assert.True(t, result.PlannedStartTime.Equal(plannedStartTime))
+			assert.True(t, result.IsStarted())
+			assert.True(t, result.IsEnded())
+			// verify the changes were saved
+			saved, err := raceRepo.Lookup(race.ID)
+			require.NoError(t, err)
+			assert.Equal(t, name, saved.Name)
+			assert.True(t, saved.IsEnded())
+		})
+
+		t.Run("rename ended race", func(t *testing.T) {
+			// given
+			race := newRace(t)
+			_, err := svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &started})
+			require.NoError(t, err)
+			_, err = svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &ended})
+			require.NoError(t, err)
+			name := race.Name + " (terminée)"
+			// when
+			result, err := svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Name: &name})
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, name, result.Name)
+		})
+	})
+
+	s.T().Run("failure", func(t *testing.T) {
+
+		t.Run("nothing to update", func(t *testing.T) {
+			// given
+			race := newRace(t)
+			// when
+			_, err := svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("invalid status", func(t *testing.T) {
+			// given
+			race := newRace(t)
+			status := service.RaceStatus("paused")
+			// when
+			_, err := svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &status})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("end race not started", func(t *testing.T) {
+			// given
+			race := newRace(t)
+			// when
+			_, err := svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &ended})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsStateError(err))
+		})
+
+		t.Run("start ended race", func(t *testing.T) {
+			// given
+			race := newRace(t)
+			_, err := svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &started})
+			require.NoError(t, err)
+			_, err = svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &ended})
+			require.NoError(t, err)
+			// when
+			_, err = svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &started})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsStateError(err))
+		})
+
+		t.Run("planned start of started race", func(t *testing.T) {
+			// given
+			race := newRace(t)
+			_, err := svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{Status: &started})
+			require.NoError(t, err)
+			plannedStartTime := time.Now()
+			// when
+			_, err = svc.UpdateRace(context.Background(), race.ID, service.RaceUpdate{PlannedStartTime: &plannedStartTime})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsStateError(err))
+		})
+
+		t.Run("duplicate name", func(t *testing.T) {
+			// given
+			race1 := newRace(t)
+			race2 := newRace(t)
+			// when
+			_, err := svc.UpdateRace(context.Background(), race2.ID, service.RaceUpdate{Name: &race1.Name})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsConflictError(err))
+		})
+
+		t.Run("unknown race", func(t *testing.T) {
+			// when
+			_, err := svc.UpdateRace(context.Background(), -1, service.RaceUpdate{Status: &started})
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsNotFoundError(err))
 		})
 	})
 }
@@ -164,8 +326,10 @@ func (s *AppServiceTestSuite) TestAddLap() {
 
 	// given
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	race := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race)
 	require.NoError(s.T(), err)
@@ -183,7 +347,7 @@ func (s *AppServiceTestSuite) TestAddLap() {
 
 		t.Run("team 1 lap 1", func(t *testing.T) {
 			// when
-			team, err := svc.AddLap(race.ID, 1)
+			team, err := svc.AddLap(context.Background(), race.ID, 1)
 			// then
 			require.NoError(t, err)
 			require.Equal(t, teams[0].Name, team.Name)
@@ -192,22 +356,125 @@ func (s *AppServiceTestSuite) TestAddLap() {
 
 		t.Run("team 2 lap 1+2", func(t *testing.T) {
 			// when
-			_, err := svc.AddLap(race.ID, 2)
+			_, err := svc.AddLap(context.Background(), race.ID, 2)
 			require.NoError(t, err)
-			team, err := svc.AddLap(race.ID, 2)
+			team, err := svc.AddLap(context.Background(), race.ID, 2)
 			// then
 			require.NoError(t, err)
 			require.Equal(t, teams[1].Name, team.Name)
 			assert.Len(t, team.Laps, 2)
 		})
 	})
+
+	s.T().Run("failure", func(t *testing.T) {
+
+		t.Run("unknown team", func(t *testing.T) {
+			// when
+			_, err := svc.AddLap(context.Background(), race.ID, 10)
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsNotFoundError(err))
+		})
+
+		t.Run("duplicate lap", func(t *testing.T) {
+			// given
+			service.SetMinLapInterval(time.Minute)
+			defer service.SetMinLapInterval(0)
+			_, err := svc.AddLap(context.Background(), race.ID, 3)
+			require.NoError(t, err)
+			// when
+			_, err = svc.AddLap(context.Background(), race.ID, 3)
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsConflictError(err))
+			team, err := teamRepo.LoadByBibNumber(race.ID, 3)
+			require.NoError(t, err)
+			assert.Len(t, team.Laps, 1)
+		})
+	})
+}
+
+func (s *AppServiceTestSuite) TestDeleteLap() {
+
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	svc := service.NewApplicationService(s.DB)
+	teamRepo := model.NewTeamRepository(s.DB)
+	for i := 1; i < 3; i++ {
+		team := testmodel.NewTeam(race.ID, i)
+		err := teamRepo.Create(&team)
+		require.NoError(s.T(), err)
+	}
+	lapRepo := model.NewLapRepository(s.DB)
+	team, err := teamRepo.LoadByBibNumber(race.ID, 1)
+	require.NoError(s.T(), err)
+	now := time.Now()
+	laps := []model.Lap{}
+	for i := 0; i < 3; i++ {
+		lap := model.Lap{RaceID: race.ID, TeamID: team.ID, Time: now.Add(time.Duration(i) * time.Minute)}
+		err := lapRepo.Create(&lap)
+		require.NoError(s.T(), err)
+		laps = append(laps, lap)
+	}
+
+	s.T().Run("ok", func(t *testing.T) {
+
+		t.Run("last lap", func(t *testing.T) {
+			// when
+			team, err := svc.DeleteLap(context.Background(), race.ID, 1, 0)
+			// then
+			require.NoError(t, err)
+			require.Len(t, team.Laps, 2)
+			for _, l := range team.Laps {
+				assert.NotEqual(t, laps[2].ID, l.ID)
+			}
+		})
+
+		t.Run("given lap", func(t *testing.T) {
+			// when
+			team, err := svc.DeleteLap(context.Background(), race.ID, 1, laps[0].ID)
+			// then
+			require.NoError(t, err)
+			require.Len(t, team.Laps, 1)
+			assert.Equal(t, laps[1].ID, team.Laps[0].ID)
+		})
+	})
+
+	s.T().Run("failure", func(t *testing.T) {
+
+		t.Run("unknown lap", func(t *testing.T) {
+			// when
+			_, err := svc.DeleteLap(context.Background(), race.ID, 1, laps[0].ID)
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsNotFoundError(err))
+		})
+
+		t.Run("no lap", func(t *testing.T) {
+			// when
+			_, err := svc.DeleteLap(context.Background(), race.ID, 2, 0)
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsStateError(err))
+		})
+	})
 }
+
 func (s *AppServiceTestSuite) TestFirstAddLapForAll() {
 
 	s.T().Run("enabled", func(t *testing.T) {
 		// given
 		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
 		race := model.Race{
+			EventID:        event.ID,
 			Name:           fmt.Sprintf("race %s", uuid.NewV4()),
 			AllowsFirstLap: true,
 			HasFirstLap:    false,
@@ -226,7 +493,7 @@ func (s *AppServiceTestSuite) TestFirstAddLapForAll() {
 
 		t.Run("can add first lap", func(t *testing.T) {
 			// when
-			race, err := svc.AddFirstLapForAll(race.ID)
+			race, err := svc.AddFirstLapForAll(context.Background(), race.ID)
 			// then
 			require.NoError(t, err)
 			assert.True(t, race.AllowsFirstLap)
@@ -241,7 +508,7 @@ func (s *AppServiceTestSuite) TestFirstAddLapForAll() {
 
 		s.T().Run("cannot add first lap again", func(t *testing.T) {
 			// when
-			_, err := svc.AddFirstLapForAll(race.ID)
+			_, err := svc.AddFirstLapForAll(context.Background(), race.ID)
 			// then
 			require.Error(t, err)
 		})
@@ -250,7 +517,9 @@ func (s *AppServiceTestSuite) TestFirstAddLapForAll() {
 	s.T().Run("disabled", func(t *testing.T) {
 		// given
 		raceRepo := model.NewRaceRepository(s.DB)
+		event := testmodel.CreateEvent(s.T(), s.DB)
 		race := model.Race{
+			EventID:        event.ID,
 			Name:           fmt.Sprintf("race %s", uuid.NewV4()),
 			AllowsFirstLap: false,
 			HasFirstLap:    false,
@@ -259,9 +528,65 @@ func (s *AppServiceTestSuite) TestFirstAddLapForAll() {
 		require.NoError(t, err)
 		svc := service.NewApplicationService(s.DB)
 		// when
-		_, err = svc.AddFirstLapForAll(race.ID)
+		_, err = svc.AddFirstLapForAll(context.Background(), race.ID)
 		// then
 		require.Error(t, err)
 	})
 
 }
+
+func (s *AppServiceTestSuite) TestGetTeam() {
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	svc := service.NewApplicationService(s.DB)
+	teamRepo := model.NewTeamRepository(s.DB)
+	for i := 1; i < 3; i++ {
+		team := testmodel.NewTeam(race.ID, i)
+		err := teamRepo.Create(&team)
+		require.NoError(s.T(), err)
+	}
+	_, err = svc.StartRace(context.Background(), race.ID)
+	require.NoError(s.T(), err)
+
+	s.T().Run("not started", func(t *testing.T) {
+		// when
+		team, err := svc.GetTeam(context.Background(), race.ID, 1)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, team.Team.BibNumber)
+		assert.Equal(t, model.TeamNotStarted, team.Status)
+		assert.Equal(t, 0, team.Rank)
+		assert.Empty(t, team.Laps)
+	})
+
+	s.T().Run("racing", func(t *testing.T) {
+		// given
+		_, err := svc.AddLap(context.Background(), race.ID, 2)
+		require.NoError(t, err)
+		_, err = svc.AddLap(context.Background(), race.ID, 2)
+		require.NoError(t, err)
+		// when
+		team, err := svc.GetTeam(context.Background(), race.ID, 2)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, model.TeamRacing, team.Status)
+		assert.Equal(t, 1, team.Rank)
+		require.Len(t, team.Laps, 2)
+		assert.True(t, team.Laps[0].Duration > 0)
+	})
+
+	s.T().Run("unknown team", func(t *testing.T) {
+		// when
+		_, err := svc.GetTeam(context.Background(), race.ID, 3)
+		// then
+		require.Error(t, err)
+		assert.True(t, model.IsNotFoundError(err))
+	})
+}
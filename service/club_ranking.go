@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vatriathlon/stopwatch/model"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ClubScoring the way the clubs are ranked
+type ClubScoring string
+
+const (
+	// ClubScoringParticipation the clubs are ranked by number of teams, then by total laps
+	ClubScoringParticipation ClubScoring = "participation"
+	// ClubScoringBestRanks the clubs are ranked by the sum of the scratch ranks of their best teams (the lowest
+	// sum wins). The clubs which have less teams than the number of ranks counted are ranked after the others.
+	ClubScoringBestRanks ClubScoring = "best-ranks"
+	// ClubScoringLaps the clubs are ranked by total laps, then by number of teams
+	ClubScoringLaps ClubScoring = "laps"
+)
+
+// ParseClubScoring parses the given value into a ClubScoring
+func ParseClubScoring(value string) (ClubScoring, error) {
+	switch scoring := ClubScoring(value); scoring {
+	case ClubScoringParticipation, ClubScoringBestRanks, ClubScoringLaps:
+		return scoring, nil
+	default:
+		return "", errors.Errorf("invalid club scoring: '%s' (expected one of: %s, %s, %s)", value,
+			ClubScoringParticipation, ClubScoringBestRanks, ClubScoringLaps)
+	}
+}
+
+// ClubRankingOptions the options to compute the ranking of the clubs
+type ClubRankingOptions struct {
+	Scoring ClubScoring
+	// BestRanks the number of ranks counted for each club with the `best-ranks` scoring (default: 3)
+	BestRanks int
+	// Locale the locale of the generated documents (default: the locale set with `SetLocale`)
+	Locale string
+}
+
+// ClubRanking the ranking of the clubs in a race
+type ClubRanking struct {
+	Race    model.Race
+	Options ClubRankingOptions
+	Results []ClubResult
+}
+
+// ClubResult the result of a club in a race. A team whose members belong to different clubs counts as a share
+// of a team for each club (eg: half a team, with half of its laps), while its rank counts for each club.
+type ClubResult struct {
+	Rank int
+	Club string
+	// Teams the number of teams of the club
+	Teams float64
+	// Ranks the scratch ranks of the teams of the club which completed at least a lap, from the best to the worst
+	Ranks []int
+	// Laps the total number of laps of the teams of the club
+	Laps float64
+	// Score the score of the club: the number of teams, the sum of the best ranks or the number of laps,
+	// depending on the scoring
+	Score float64
+}
+
+// ClubRanking computes the ranking of the clubs in the given race, from its teams and its scratch ranking
+func (s *ResultService) ClubRanking(ctx context.Context, raceID int, opts ClubRankingOptions) (ClubRanking, error) {
+	var rankings []Ranking
+	var teams []model.Team
+	// the teams and the rankings are loaded in the same transaction, so they match
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		rankings, err = loadRankings(app, raceID, opts.Locale)
+		if err != nil {
+			return err
+		}
+		teams, err = app.Teams().List(raceID)
+		return err
+	})
+	if err != nil {
+		return ClubRanking{}, errors.Wrap(err, "unable to compute club ranking")
+	}
+	for _, ranking := range rankings {
+		if ranking.Name == ScratchRankingName {
+			return NewClubRanking(ranking.Race, teams, ranking.Results, opts), nil
+		}
+	}
+	return ClubRanking{}, errors.New("unable to compute club ranking: missing scratch ranking")
+}
+
+// GenerateClubRanking generates the ranking of the clubs in the given race, in the given output directory,
+// in all the given formats
+func (s *ResultService) GenerateClubRanking(ctx context.Context, raceID int, outputDir string, formats []OutputFormat, opts ClubRankingOptions) error {
+	ranking, err := s.ClubRanking(ctx, raceID, opts)
+	if err != nil {
+		return errors.Wrap(err, "unable to generate club ranking")
+	}
+	if len(ranking.Results) == 0 {
+		logrus.WithField("race_name", ranking.Race.Name).
+			Warn("skipping: no club in this race")
+		return nil
+	}
+	for _, format := range formats {
+		err := writeClubRanking(outputDir, ranking, format)
+		if err != nil {
+			return errors.Wrap(err, "unable to generate club ranking")
+		}
+	}
+	return nil
+}
+
+// NewClubRanking returns the ranking of the clubs of the given teams, with their laps and ranks in the given
+// scratch results. The teams without any club are ignored, while the teams without any lap (hence, not in the
+// scratch results) count in the number of teams of their clubs.
+func NewClubRanking(race model.Race, teams []model.Team, results []TeamResult, opts ClubRankingOptions) ClubRanking {
+	if opts.Scoring == "" {
+		opts.Scoring = ClubScoringParticipation
+	}
+	if opts.BestRanks <= 0 {
+		opts.BestRanks = 3
+	}
+	ranking := ClubRanking{
+		Race:    race,
+		Options: opts,
+		Results: []ClubResult{},
+	}
+	resultsByTeam := map[int]TeamResult{}
+	for _, r := range results {
+		resultsByTeam[r.TeamID] = r
+	}
+	clubs := map[string]int{}
+	for _, team := range teams {
+		r, ranked := resultsByTeam[team.ID]
+		teamClubs := distinctClubs([]string{team.Member1.Club, team.Member2.Club})
+		for _, club := range teamClubs {
+			key := normalizeName(club)
+			c, found := clubs[key]
+			if !found {
+				c = len(ranking.Results)
+				clubs[key] = c
+				ranking.Results = append(ranking.Results, ClubResult{Club: club, Ranks: []int{}})
+			}
+			share := 1 / float64(len(teamClubs))
+			ranking.Results[c].Teams += share
+			if ranked {
+				ranking.Results[c].Laps += share * float64(r.Laps)
+				ranking.Results[c].Ranks = append(ranking.Results[c].Ranks, r.Rank)
+			}
+		}
+	}
+	for i := range ranking.Results {
+		c := &ranking.Results[i]
+		sort.Ints(c.Ranks)
+		switch opts.Scoring {
+		case ClubScoringBestRanks:
+			c.Score = 0
+			for j := 0; j < len(c.Ranks) && j < opts.BestRanks; j++ {
+				c.Score += float64(c.Ranks[j])
+			}
+		case ClubScoringLaps:
+			c.Score = c.Laps
+		default:
+			c.Score = c.Teams
+		}
+	}
+	sort.SliceStable(ranking.Results, func(i, j int) bool {
+		if c := compareClubs(ranking.Results[i], ranking.Results[j], opts); c != 0 {
+			return c > 0
+		}
+		return ranking.Results[i].Club < ranking.Results[j].Club
+	})
+	for i := range ranking.Results {
+		if i > 0 && compareClubs(ranking.Results[i], ranking.Results[i-1], opts) == 0 {
+			ranking.Results[i].Rank = ranking.Results[i-1].Rank
+		} else {
+			ranking.Results[i].Rank = i + 1
+		}
+	}
+	return ranking
+}
+
+// distinctClubs returns the distinct (non-empty) clubs of the members of a team
+func distinctClubs(memberClubs []string) []string {
+	result := []string{}
+	keys := map[string]bool{}
+	for _, club := range memberClubs {
+		club = strings.TrimSpace(club)
+		key := normalizeName(club)
+		if key == "" || keys[key] {
+			continue
+		}
+		keys[key] = true
+		result = append(result, club)
+	}
+	return result
+}
+
+// compareClubs compares the given clubs according to the given options. Returns a positive value if `c1` is
+// ahead of `c2`, a negative value if `c2` is ahead of `c1`, and zero if they are tied.
+func compareClubs(c1, c2 ClubResult, opts ClubRankingOptions) int {
+	switch opts.Scoring {
+	case ClubScoringBestRanks:
+		// the clubs with enough teams are ahead, then the lowest sum of ranks wins
+		n1, n2 := len(c1.Ranks), len(c2.Ranks)
+		if n1 > opts.BestRanks {
+			n1 = opts.BestRanks
+		}
+		if n2 > opts.BestRanks {
+			n2 = opts.BestRanks
+		}
+		if n1 != n2 {
+			return n1 - n2
+		}
+		return compareFloats(c2.Score, c1.Score)
+	case ClubScoringLaps:
+		if c := compareFloats(c1.Laps, c2.Laps); c != 0 {
+			return c
+		}
+		return compareFloats(c1.Teams, c2.Teams)
+	default:
+		if c := compareFloats(c1.Teams, c2.Teams); c != 0 {
+			return c
+		}
+		return compareFloats(c1.Laps, c2.Laps)
+	}
+}
+
+func compareFloats(f1, f2 float64) int {
+	switch {
+	case f1 > f2:
+		return 1
+	case f1 < f2:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// fmtShare formats the given number of teams or laps, which may be a fraction because of the mixed-club teams
+func fmtShare(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// fmtRanks formats the given ranks as a comma-separated list
+func fmtRanks(ranks []int) string {
+	result := make([]string, len(ranks))
+	for i, r := range ranks {
+		result[i] = strconv.Itoa(r)
+	}
+	return strings.Join(result, ", ")
+}
+
+// clubRankingDocumentName the name of the club ranking in the generated files
+const clubRankingDocumentName = "Clubs"
+
+// writeClubRanking writes the given club ranking in a file in the given output directory, using the given format
+func writeClubRanking(outputDir string, ranking ClubRanking, format OutputFormat) error {
+	filename := outputFilename(outputDir, ranking.Race.Name, clubRankingDocumentName, format)
+	logrus.WithField("race_name", ranking.Race.Name).
+		WithField("clubs", len(ranking.Results)).
+		WithField("output_file", filename).
+		Info("generating club ranking...")
+	err := writeTable(filename, clubRankingTable(ranking), format)
+	return errors.Wrapf(err, "unable to generate club ranking in %s", format)
+}
+
+// clubRankingTable returns the table of the club ranking
+func clubRankingTable(ranking ClubRanking) table {
+	msg := newTranslator(ranking.Options.Locale)
+	t := table{
+		title: ranking.Race.Name,
+		columns: []tableColumn{
+			{title: "#", csvTitle: msg.T("Classement"), width: 2, align: "R"},
+			{title: msg.T("Club"), width: 12, align: "L"},
+			{title: msg.T("Equipes"), width: 3, align: "R"},
+			{title: msg.T("Places"), width: 12, align: "L"},
+			{title: msg.T("Tours"), width: 3, align: "R"},
+		},
+		data:   newJSONClubRanking(ranking),
+		locale: msg.locale,
+	}
+	switch ranking.Options.Scoring {
+	case ClubScoringBestRanks:
+		t.subtitle = msg.Tf("Classement des clubs (somme des %d meilleures places)", ranking.Options.BestRanks)
+		t.columns = append(t.columns, tableColumn{title: msg.T("Total"), width: 3, align: "R"})
+	case ClubScoringLaps:
+		t.subtitle = msg.T("Classement des clubs (nombre de tours)")
+	default:
+		t.subtitle = msg.T("Classement des clubs (nombre d'équipes)")
+	}
+	rows := make([][]string, len(ranking.Results))
+	for i, c := range ranking.Results {
+		rows[i] = []string{strconv.Itoa(c.Rank), c.Club, fmtShare(c.Teams), fmtRanks(c.Ranks), fmtShare(c.Laps)}
+		if ranking.Options.Scoring == ClubScoringBestRanks {
+			rows[i] = append(rows[i], fmtShare(c.Score))
+		}
+	}
+	t.sections = []tableSection{{rows: rows}}
+	return t
+}
+
+type jsonClubRanking struct {
+	Race    string           `json:"race"`
+	Scoring ClubScoring      `json:"scoring"`
+	Results []jsonClubResult `json:"results"`
+}
+
+type jsonClubResult struct {
+	Rank  int     `json:"rank"`
+	Club  string  `json:"club"`
+	Teams float64 `json:"teams"`
+	Ranks []int   `json:"ranks"`
+	Laps  float64 `json:"laps"`
+	Score float64 `json:"score"`
+}
+
+func newJSONClubRanking(ranking ClubRanking) jsonClubRanking {
+	result := jsonClubRanking{
+		Race:    ranking.Race.Name,
+		Scoring: ranking.Options.Scoring,
+		Results: make([]jsonClubResult, len(ranking.Results)),
+	}
+	for i, c := range ranking.Results {
+		result.Results[i] = jsonClubResult{
+			Rank:  c.Rank,
+			Club:  c.Club,
+			Teams: c.Teams,
+			Ranks: c.Ranks,
+			Laps:  c.Laps,
+			Score: c.Score,
+		}
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClubRanking(t *testing.T) {
+
+	race := model.Race{ID: 1, Name: "Bike & Run XS"}
+	// the teams of the race and their scratch results
+	teams := []model.Team{}
+	results := []service.TeamResult{}
+	for i, r := range []struct {
+		laps  int
+		clubs [2]string
+	}{
+		{laps: 12, clubs: [2]string{"VA", "VA"}},
+		{laps: 12, clubs: [2]string{"Triathlon Club", "triathlon  club"}},
+		{laps: 11, clubs: [2]string{"VA", "Running 51"}},
+		{laps: 10, clubs: [2]string{"Triathlon Club", "Triathlon Club"}},
+		{laps: 10, clubs: [2]string{"", ""}},
+		{laps: 9, clubs: [2]string{"Running 51", ""}},
+		{laps: 8, clubs: [2]string{"Triathlon Club", "Triathlon Club"}},
+	} {
+		teams = append(teams, model.Team{ID: i + 1, Member1: model.TeamMember{Club: r.clubs[0]}, Member2: model.TeamMember{Club: r.clubs[1]}})
+		results = append(results, service.TeamResult{Rank: i + 1, TeamID: i + 1, Laps: r.laps})
+	}
+	club := func(r service.ClubRanking, name string) service.ClubResult {
+		for _, c := range r.Results {
+			if c.Club == name {
+				return c
+			}
+		}
+		require.FailNow(t, "club not found", name)
+		return service.ClubResult{}
+	}
+
+	t.Run("participation", func(t *testing.T) {
+		// when
+		ranking := service.NewClubRanking(race, teams, results, service.ClubRankingOptions{Scoring: service.ClubScoringParticipation})
+		// then the teams without club are ignored
+		require.Len(t, ranking.Results, 3)
+		assert.Equal(t, "Triathlon Club", ranking.Results[0].Club)
+		assert.Equal(t, 3.0, ranking.Results[0].Teams)
+		assert.Equal(t, []int{2, 4, 7}, ranking.Results[0].Ranks)
+		// the mixed-club team counts as half a team, with half of its laps, for each club
+		va := club(ranking, "VA")
+		assert.Equal(t, 2, va.Rank)
+		assert.Equal(t, 1.5, va.Teams)
+		assert.Equal(t, 17.5, va.Laps)
+		assert.Equal(t, []int{1, 3}, va.Ranks)
+		// same number of teams as VA, but less laps
+		running := club(ranking, "Running 51")
+		assert.Equal(t, 3, running.Rank)
+		assert.Equal(t, 1.5, running.Teams)
+		assert.Equal(t, 14.5, running.Laps)
+	})
+
+	t.Run("best 2 ranks", func(t *testing.T) {
+		// when
+		ranking := service.NewClubRanking(race, teams, results, service.ClubRankingOptions{Scoring: service.ClubScoringBestRanks, BestRanks: 2})
+		// then
+		require.Len(t, ranking.Results, 3)
+		assert.Equal(t, "VA", ranking.Results[0].Club)
+		assert.Equal(t, 4.0, ranking.Results[0].Score)
+		assert.Equal(t, "Triathlon Club", ranking.Results[1].Club)
+		assert.Equal(t, 6.0, ranking.Results[1].Score)
+		assert.Equal(t, "Running 51", ranking.Results[2].Club)
+		assert.Equal(t, 9.0, ranking.Results[2].Score)
+	})
+
+	t.Run("best 3 ranks", func(t *testing.T) {
+		// when
+		ranking := service.NewClubRanking(race, teams, results, service.ClubRankingOptions{Scoring: service.ClubScoringBestRanks, BestRanks: 3})
+		// then the clubs with less than 3 teams are ranked after the others
+		require.Len(t, ranking.Results, 3)
+		assert.Equal(t, "Triathlon Club", ranking.Results[0].Club)
+		assert.Equal(t, 13.0, ranking.Results[0].Score)
+		assert.Equal(t, "VA", ranking.Results[1].Club)
+		assert.Equal(t, "Running 51", ranking.Results[2].Club)
+	})
+
+	t.Run("total laps", func(t *testing.T) {
+		// when
+		ranking := service.NewClubRanking(race, teams, results, service.ClubRankingOptions{Scoring: service.ClubScoringLaps})
+		// then
+		require.Len(t, ranking.Results, 3)
+		assert.Equal(t, "Triathlon Club", ranking.Results[0].Club)
+		assert.Equal(t, 30.0, ranking.Results[0].Score)
+		assert.Equal(t, "VA", ranking.Results[1].Club)
+		assert.Equal(t, "Running 51", ranking.Results[2].Club)
+	})
+
+	t.Run("ties", func(t *testing.T) {
+		// when
+		ranking := service.NewClubRanking(race, []model.Team{
+			{ID: 1, Member1: model.TeamMember{Club: "Foo"}, Member2: model.TeamMember{Club: "Bar"}},
+		}, []service.TeamResult{
+			{Rank: 1, TeamID: 1, Laps: 10},
+		}, service.ClubRankingOptions{})
+		// then
+		require.Len(t, ranking.Results, 2)
+		assert.Equal(t, 1, ranking.Results[0].Rank)
+		assert.Equal(t, 1, ranking.Results[1].Rank)
+	})
+
+	t.Run("teams without laps", func(t *testing.T) {
+		// given 2 more teams of Running 51 which did not complete any lap
+		teams := append(teams,
+			model.Team{ID: 8, Member1: model.TeamMember{Club: "Running 51"}, Member2: model.TeamMember{Club: "Running 51"}},
+			model.Team{ID: 9, Member1: model.TeamMember{Club: "Running 51"}, Member2: model.TeamMember{Club: "Running 51"}})
+		// when
+		ranking := service.NewClubRanking(race, teams, results, service.ClubRankingOptions{Scoring: service.ClubScoringParticipation})
+		// then they count in the participation of their club, without laps nor ranks
+		require.Len(t, ranking.Results, 3)
+		assert.Equal(t, "Running 51", ranking.Results[0].Club)
+		assert.Equal(t, 3.5, ranking.Results[0].Teams)
+		assert.Equal(t, 14.5, ranking.Results[0].Laps)
+		assert.Equal(t, []int{3, 6}, ranking.Results[0].Ranks)
+	})
+}
+
+func TestParseClubScoring(t *testing.T) {
+	for _, value := range []string{"participation", "best-ranks", "laps"} {
+		scoring, err := service.ParseClubScoring(value)
+		require.NoError(t, err)
+		assert.Equal(t, service.ClubScoring(value), scoring)
+	}
+	_, err := service.ParseClubScoring("podiums")
+	assert.EqualError(t, err, "invalid club scoring: 'podiums' (expected one of: participation, best-ranks, laps)")
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vatriathlon/stopwatch/model"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportService the service to export the registrations
+type ExportService struct {
+	baseService *GormService
+}
+
+// NewExportService returns a new ExportService
+func NewExportService(db *gorm.DB) ExportService {
+	return ExportService{
+		baseService: NewGormService(db),
+	}
+}
+
+// StartListOrder the order of the teams in the start list
+type StartListOrder string
+
+const (
+	// OrderByBibNumber sorts the teams by bib number
+	OrderByBibNumber StartListOrder = "bib"
+	// OrderByName sorts the teams by name
+	OrderByName StartListOrder = "name"
+)
+
+// ParseStartListOrder parses the given start list order
+func ParseStartListOrder(value string) (StartListOrder, error) {
+	switch StartListOrder(strings.ToLower(value)) {
+	case OrderByBibNumber, "":
+		return OrderByBibNumber, nil
+	case OrderByName:
+		return OrderByName, nil
+	default:
+		return "", errors.Errorf("unsupported start list order: '%s' (expected one of: %s, %s)", value, OrderByBibNumber, OrderByName)
+	}
+}
+
+// StartListEntry an entry in the start list
+type StartListEntry struct {
+	BibNumber int    `json:"bibNumber"`
+	TeamName  string `json:"teamName"`
+	Members   string `json:"members"`
+	Category  string `json:"category"`
+	Club      string `json:"club"`
+}
+
+// ExportStartList exports the start list of the given race in the given output directory, in all the given formats
+// and in the given locale (or in the locale set with `SetLocale` if empty)
+func (s *ExportService) ExportStartList(ctx context.Context, raceID int, outputDir string, order StartListOrder, formats []OutputFormat, locale string) error {
+	var race model.Race
+	var teams []model.Team
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		race, err = app.Races().Lookup(raceID)
+		if err != nil {
+			return err
+		}
+		teams, err = app.Teams().List(raceID)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to export start list")
+	}
+	msg := newTranslator(locale)
+	entries := newStartList(teams, order, msg)
+	if len(entries) == 0 {
+		logrus.WithField("race_name", race.Name).Warn("skipping start list export: no team in this race")
+		return nil
+	}
+	t := startListTable(race, entries, msg)
+	for _, format := range formats {
+		filename := outputFilename(outputDir, race.Name, "Liste-de-départ", format)
+		logrus.WithField("race_name", race.Name).
+			WithField("teams", len(entries)).
+			WithField("output_file", filename).
+			Info("exporting start list...")
+		if err := writeTable(filename, t, format); err != nil {
+			return errors.Wrapf(err, "unable to export start list in %s", format)
+		}
+	}
+	return nil
+}
+
+func newStartList(teams []model.Team, order StartListOrder, msg translator) []StartListEntry {
+	result := make([]StartListEntry, len(teams))
+	for i, t := range teams {
+		result[i] = StartListEntry{
+			BibNumber: t.BibNumber,
+			TeamName:  t.Name,
+			Members:   getMemberFullNames(t.Member1, t.Member2),
+			Category:  CategoryLabel(msg.locale, t.AgeCategory, t.Gender),
+			Club:      getMemberClubs(t.Member1.Club, t.Member2.Club),
+		}
+	}
+	// teams are already sorted by bib number
+	if order == OrderByName {
+		sort.SliceStable(result, func(i, j int) bool {
+			return strings.ToLower(result[i].TeamName) < strings.ToLower(result[j].TeamName)
+		})
+	}
+	return result
+}
+
+func getMemberFullNames(member1, member2 model.TeamMember) string {
+	return fmt.Sprintf("%s %s - %s %s", member1.FirstName, member1.LastName, member2.FirstName, member2.LastName)
+}
+
+// startListTable returns the table of the given start list
+func startListTable(race model.Race, entries []StartListEntry, msg translator) table {
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		rows[i] = []string{strconv.Itoa(e.BibNumber), e.TeamName, e.Members, e.Category, e.Club}
+	}
+	return table{
+		title: msg.Tf("Liste de départ %s", race.Name),
+		columns: []tableColumn{
+			{title: msg.T("Dossard"), width: 2, align: "R"},
+			{title: msg.T("Equipe"), width: 5, align: "L"},
+			{title: msg.T("Coureurs"), width: 8, align: "L"},
+			{title: msg.T("Catégorie"), width: 5, align: "C"},
+			{title: msg.T("Club"), width: 8, align: "L"},
+		},
+		sections: []tableSection{{rows: rows}},
+		data:     entries,
+		locale:   msg.locale,
+	}
+}
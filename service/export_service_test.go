@@ -0,0 +1,113 @@
+package service_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+	testmodel "github.com/vatriathlon/stopwatch/test/model"
+	testsuite "github.com/vatriathlon/stopwatch/test/suite"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestExportService(t *testing.T) {
+	config, err := configuration.New()
+	require.NoError(t, err)
+	suite.Run(t, &ExportServiceTestSuite{DBTestSuite: testsuite.NewDBTestSuite(config)})
+}
+
+type ExportServiceTestSuite struct {
+	testsuite.DBTestSuite
+}
+
+func (s *ExportServiceTestSuite) TestExportStartList() {
+	// given
+	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	teamRepo := model.NewTeamRepository(s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err := raceRepo.Create(&race)
+	require.NoError(s.T(), err)
+	for i, name := range []string{"zorro", "Alpha", "bravo"} {
+		team := testmodel.NewTeam(race.ID, i+1)
+		team.Name = name
+		err := teamRepo.Create(&team)
+		require.NoError(s.T(), err)
+	}
+	svc := service.NewExportService(s.DB)
+	outputDir, err := ioutil.TempDir("", "stopwatch")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(outputDir)
+	filename := func(format service.OutputFormat) string {
+		return filepath.Join(outputDir, fmt.Sprintf("%s-Liste-de-départ.%s", strings.Replace(race.Name, " ", "-", -1), format))
+	}
+
+	s.T().Run("sorted by bib number", func(t *testing.T) {
+		// when
+		err := svc.ExportStartList(context.Background(), race.ID, outputDir, service.OrderByBibNumber, []service.OutputFormat{service.CSVFormat, service.AsciidocFormat}, "")
+		// then
+		require.NoError(t, err)
+		f, err := os.Open(filename(service.CSVFormat))
+		require.NoError(t, err)
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, []string{"Dossard", "Equipe", "Coureurs", "Catégorie", "Club"}, records[0])
+		assert.Equal(t, []string{"1", "zorro", "john doe - jane doe", "Seniors / Mixte", ""}, records[1])
+		assert.Equal(t, "Alpha", records[2][1])
+		assert.Equal(t, "bravo", records[3][1])
+		assert.FileExists(t, filename(service.AsciidocFormat))
+	})
+
+	s.T().Run("print-ready formats", func(t *testing.T) {
+		// when
+		err := svc.ExportStartList(context.Background(), race.ID, outputDir, service.OrderByBibNumber, []service.OutputFormat{service.HTMLFormat, service.PDFFormat}, "")
+		// then
+		require.NoError(t, err)
+		content, err := ioutil.ReadFile(filename(service.HTMLFormat))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "<td>Alpha</td>")
+		assert.FileExists(t, filename(service.PDFFormat))
+	})
+
+	s.T().Run("sorted by name", func(t *testing.T) {
+		// when
+		err := svc.ExportStartList(context.Background(), race.ID, outputDir, service.OrderByName, []service.OutputFormat{service.JSONFormat}, "")
+		// then
+		require.NoError(t, err)
+		content, err := ioutil.ReadFile(filename(service.JSONFormat))
+		require.NoError(t, err)
+		entries := []service.StartListEntry{}
+		err = json.Unmarshal(content, &entries)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, "Alpha", entries[0].TeamName)
+		assert.Equal(t, 2, entries[0].BibNumber)
+		assert.Equal(t, "bravo", entries[1].TeamName)
+		assert.Equal(t, "zorro", entries[2].TeamName)
+	})
+
+	s.T().Run("unknown race", func(t *testing.T) {
+		// when
+		err := svc.ExportStartList(context.Background(), -1, outputDir, service.OrderByName, []service.OutputFormat{service.JSONFormat}, "")
+		// then
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,9 @@
+package service
+
+// ResumeTransactions accepts the new transactions again after a call to `WaitForTransactions`, so the tests
+// can keep using the database
+func ResumeTransactions() {
+	drainingLock.Lock()
+	defer drainingLock.Unlock()
+	draining = false
+}
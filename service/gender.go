@@ -0,0 +1,136 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/vatriathlon/stopwatch/model"
+
+	"github.com/pkg/errors"
+)
+
+// GenderMapping maps the gender values found in the imported files (eg: "Homme", "Female") to the gender codes
+// of the team members, regardless of the case and accents
+type GenderMapping map[string]string
+
+// defaultGenderMapping the values sent by the registration platforms, in French and in English
+var defaultGenderMapping = GenderMapping{
+	"h":        model.GenderMen,
+	"homme":    model.GenderMen,
+	"hommes":   model.GenderMen,
+	"masculin": model.GenderMen,
+	"male":     model.GenderMen,
+	"man":      model.GenderMen,
+	"men":      model.GenderMen,
+	"f":        model.GenderWomen,
+	"femme":    model.GenderWomen,
+	"femmes":   model.GenderWomen,
+	"feminin":  model.GenderWomen,
+	"dame":     model.GenderWomen,
+	"female":   model.GenderWomen,
+	"w":        model.GenderWomen,
+	"woman":    model.GenderWomen,
+	"women":    model.GenderWomen,
+}
+
+// ParseGenderMapping parses the given comma-separated list of `value=code` entries (eg: "M=H,W=F") into a mapping
+// which extends (and overrides) the built-in values. The codes of the team members are `H` and `F`.
+func ParseGenderMapping(value string) (GenderMapping, error) {
+	result := GenderMapping{}
+	for k, v := range defaultGenderMapping {
+		result[k] = v
+	}
+	entries, err := parseEntries(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid gender mapping")
+	}
+	for k, code := range entries {
+		if code != model.GenderMen && code != model.GenderWomen {
+			return nil, errors.Errorf("invalid gender mapping: unknown gender code '%s' for '%s' (expected one of: %s, %s)",
+				code, k, model.GenderMen, model.GenderWomen)
+		}
+		result[normalizeName(k)] = code
+	}
+	return result, nil
+}
+
+// Gender returns the gender code of the given value, regardless of the case and accents.
+// Returns an error if the value is not mapped to any code.
+func (m GenderMapping) Gender(value string) (string, error) {
+	code, found := m[normalizeName(value)]
+	if !found {
+		return "", model.NewValidationError(model.ErrCodeInvalidTeam, "unknown gender '%s'", value)
+	}
+	return code, nil
+}
+
+var genderMapping = defaultGenderMapping
+
+// SetGenderMapping sets the mapping of the gender values found in the imported files
+func SetGenderMapping(mapping GenderMapping) {
+	genderMapping = mapping
+}
+
+// genderLabels the labels of the gender categories, by locale then by gender code
+var genderLabels = map[string]map[string]string{
+	"fr": {
+		model.GenderMen:   "Hommes",
+		model.GenderWomen: "Femmes",
+		model.GenderMixed: "Mixte",
+	},
+	"en": {
+		model.GenderMen:   "Men",
+		model.GenderWomen: "Women",
+		model.GenderMixed: "Mixed",
+	},
+	"de": {
+		model.GenderMen:   "Männer",
+		model.GenderWomen: "Frauen",
+		model.GenderMixed: "Mixed",
+	},
+}
+
+// SetGenderLabels overrides the labels of the gender categories in the given locale with the given comma-separated
+// list of `code=label` entries (eg: "H=Messieurs,F=Dames"). The codes which are not listed keep their label.
+func SetGenderLabels(locale, value string) error {
+	locale = strings.ToLower(locale)
+	labels, found := genderLabels[locale]
+	if !found {
+		return errors.Errorf("unsupported locale: '%s' (expected one of: %s)", locale, strings.Join(Locales(), ", "))
+	}
+	entries, err := parseEntries(value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid gender labels in locale '%s'", locale)
+	}
+	for code, label := range entries {
+		if !model.IsGender(code) {
+			return errors.Errorf("invalid gender labels in locale '%s': unknown gender code '%s'", locale, code)
+		}
+		labels[code] = label
+	}
+	return nil
+}
+
+// genderLabel returns the label of the given gender category in the given locale, or the code itself if
+// there is no such label
+func genderLabel(locale, code string) string {
+	if label, found := genderLabels[locale][code]; found {
+		return label
+	}
+	return code
+}
+
+// parseEntries parses the given comma-separated list of `key=value` entries. Returns an empty map if the value is empty.
+func parseEntries(value string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, errors.Errorf("invalid entry: '%s' (expected format: key=value)", entry)
+		}
+		result[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return result, nil
+}
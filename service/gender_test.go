@@ -0,0 +1,97 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGenderMapping(t *testing.T) {
+
+	t.Run("ok", func(t *testing.T) {
+
+		testcases := map[string]struct {
+			mapping  string
+			value    string
+			expected string
+		}{
+			"default code":          {mapping: "", value: "H", expected: model.GenderMen},
+			"default french value":  {mapping: "", value: "Femme", expected: model.GenderWomen},
+			"default english value": {mapping: "", value: "male", expected: model.GenderMen},
+			"accents":               {mapping: "", value: "Féminin", expected: model.GenderWomen},
+			"custom value":          {mapping: "M=H, Dames=F", value: "m", expected: model.GenderMen},
+			"overridden value":      {mapping: "W=H", value: "W", expected: model.GenderMen},
+		}
+		for testname, testdata := range testcases {
+			t.Run(testname, func(t *testing.T) {
+				// given
+				mapping, err := service.ParseGenderMapping(testdata.mapping)
+				require.NoError(t, err)
+				// when
+				result, err := mapping.Gender(testdata.value)
+				// then
+				require.NoError(t, err)
+				assert.Equal(t, testdata.expected, result)
+			})
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+
+		t.Run("unknown value", func(t *testing.T) {
+			// given
+			mapping, err := service.ParseGenderMapping("")
+			require.NoError(t, err)
+			// when
+			_, err = mapping.Gender("M")
+			// then
+			require.Error(t, err)
+			assert.True(t, model.IsValidationError(err))
+		})
+
+		t.Run("invalid code", func(t *testing.T) {
+			// when
+			_, err := service.ParseGenderMapping("X=M")
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "unknown gender code 'M'")
+		})
+
+		t.Run("invalid entry", func(t *testing.T) {
+			// when
+			_, err := service.ParseGenderMapping("M:H")
+			// then
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestCategoryLabel(t *testing.T) {
+
+	t.Run("built-in labels", func(t *testing.T) {
+		assert.Equal(t, "Seniors / Mixte", service.CategoryLabel("fr", service.Senior, model.GenderMixed))
+		assert.Equal(t, "Seniors / Women", service.CategoryLabel("en", service.Senior, model.GenderWomen))
+		assert.Equal(t, "U21 / Männer", service.CategoryLabel("DE", service.Junior, model.GenderMen))
+	})
+
+	t.Run("custom labels", func(t *testing.T) {
+		// given
+		err := service.SetGenderLabels("fr", "H=Messieurs,F=Dames")
+		require.NoError(t, err)
+		defer service.SetGenderLabels("fr", "H=Hommes,F=Femmes")
+		// when/then
+		assert.Equal(t, "Seniors / Dames", service.CategoryLabel("fr", service.Senior, model.GenderWomen))
+		assert.Equal(t, "Seniors / Mixte", service.CategoryLabel("fr", service.Senior, model.GenderMixed))
+		assert.Equal(t, "Seniors / Women", service.CategoryLabel("en", service.Senior, model.GenderWomen))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		assert.Error(t, service.SetGenderLabels("it", "H=Uomini"))
+		assert.Error(t, service.SetGenderLabels("fr", "X=Autres"))
+		assert.Error(t, service.SetLocale("it"))
+	})
+}
@@ -1,8 +1,10 @@
 package service
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"strconv"
+	"strings"
 
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
@@ -33,13 +35,42 @@ const (
 	TXIsoLevelSerializable
 )
 
+// ParseTXIsoLevel parses the given isolation level (`default`, `read committed`, `repeatable read` or `serializable`)
+func ParseTXIsoLevel(value string) (TXIsoLevel, error) {
+	switch strings.Replace(strings.ToLower(strings.TrimSpace(value)), "_", " ", -1) {
+	case "", "default":
+		return TXIsoLevelDefault, nil
+	case "read committed":
+		return TXIsoLevelReadCommitted, nil
+	case "repeatable read":
+		return TXIsoLevelRepeatableRead, nil
+	case "serializable":
+		return TXIsoLevelSerializable, nil
+	default:
+		return TXIsoLevelDefault, errors.Errorf("unknown transaction isolation level: '%s'", value)
+	}
+}
+
+// defaultTxIsoLevel the isolation level of the transactions of the new GormServices
+var defaultTxIsoLevel = TXIsoLevelDefault
+
+// SetDefaultTransactionIsolationLevel sets the isolation level of the transactions of the GormServices created afterwards
+func SetDefaultTransactionIsolationLevel(level TXIsoLevel) error {
+	if _, err := sqlIsolationLevel(level); err != nil {
+		return err
+	}
+	defaultTxIsoLevel = level
+	return nil
+}
+
 var _ Service = &GormService{}
 
 var _ TransactionManager = &GormService{}
 
 // NewGormService returns a new GormService object that supports transactions
 func NewGormService(db *gorm.DB) *GormService {
-	return &GormService{db: db, txIsoLevel: ""}
+	txIsoLevel, _ := sqlIsolationLevel(defaultTxIsoLevel)
+	return &GormService{db: db, txIsoLevel: txIsoLevel}
 }
 
 type Service interface {
@@ -47,7 +78,7 @@ type Service interface {
 
 type GormService struct {
 	TransactionManager
-	txIsoLevel string
+	txIsoLevel sql.IsolationLevel
 	db         *gorm.DB
 }
 
@@ -60,9 +91,14 @@ type Transaction interface {
 
 // Repositories the repositories accessor
 type Repositories interface {
+	Events() model.EventRepository
 	Races() model.RaceRepository
+	Challenges() model.ChallengeRepository
 	Teams() model.TeamRepository
 	Laps() model.LapRepository
+	Championships() model.ChampionshipRepository
+	// DB the underlying database (or transaction), for the queries which are not covered by the repositories
+	DB() *gorm.DB
 }
 
 type GormTransaction struct {
@@ -76,35 +112,51 @@ func (g *GormService) Repositories() Repositories {
 // SetTransactionIsolationLevel sets the isolation level for
 // See also https://www.postgresql.org/docs/9.3/static/sql-set-transaction.html
 func (g *GormService) SetTransactionIsolationLevel(level TXIsoLevel) error {
+	txIsoLevel, err := sqlIsolationLevel(level)
+	if err != nil {
+		return err
+	}
+	g.txIsoLevel = txIsoLevel
+	return nil
+}
+
+func sqlIsolationLevel(level TXIsoLevel) (sql.IsolationLevel, error) {
 	switch level {
 	case TXIsoLevelReadCommitted:
-		g.txIsoLevel = "READ COMMITTED"
+		return sql.LevelReadCommitted, nil
 	case TXIsoLevelRepeatableRead:
-		g.txIsoLevel = "REPEATABLE READ"
+		return sql.LevelRepeatableRead, nil
 	case TXIsoLevelSerializable:
-		g.txIsoLevel = "SERIALIZABLE"
+		return sql.LevelSerializable, nil
 	case TXIsoLevelDefault:
-		g.txIsoLevel = ""
+		return sql.LevelDefault, nil
 	default:
-		return fmt.Errorf("Unknown transaction isolation level: " + strconv.FormatInt(int64(level), 10))
+		return sql.LevelDefault, fmt.Errorf("Unknown transaction isolation level: %d", level)
 	}
-	return nil
 }
 
-// BeginTransaction implements TransactionSupport
-func (g *GormService) BeginTransaction() (Transaction, error) {
-	tx := g.db.Begin()
-	if tx.Error != nil {
-		return nil, tx.Error
+// dbLogsEnabled whether the SQL statements executed in transactions are logged
+var dbLogsEnabled = false
+
+// SetDBLogsEnabled enables (or disables) the logs of the SQL statements executed in transactions
+func SetDBLogsEnabled(enabled bool) {
+	dbLogsEnabled = enabled
+}
+
+// BeginTransaction implements TransactionSupport. The transaction is bound to the given context: it is rolled back
+// and its pending statement is cancelled when the context is done.
+func (g *GormService) BeginTransaction(ctx context.Context) (Transaction, error) {
+	sqlTx, err := g.db.DB().BeginTx(ctx, &sql.TxOptions{Isolation: g.txIsoLevel})
+	if err != nil {
+		return nil, err
 	}
-	if len(g.txIsoLevel) != 0 {
-		tx := tx.Exec(fmt.Sprintf("set transaction isolation level %s", g.txIsoLevel))
-		if tx.Error != nil {
-			return nil, tx.Error
-		}
-		return &GormTransaction{GormRepositories{tx}}, nil
+	// gorm does not support contexts, so it runs its statements on the `sql.Tx`, which is bound to the context
+	tx, err := gorm.Open(g.db.Dialect().GetName(), sqlTx)
+	if err != nil {
+		sqlTx.Rollback()
+		return nil, err
 	}
-	return &GormTransaction{GormRepositories{tx}}, nil
+	return &GormTransaction{GormRepositories{tx.LogMode(dbLogsEnabled)}}, nil
 }
 
 // GormRepositories is a base struct for gorm implementations of db & transaction
@@ -112,10 +164,18 @@ type GormRepositories struct {
 	db *gorm.DB
 }
 
+func (g *GormRepositories) Events() model.EventRepository {
+	return model.NewEventRepository(g.db)
+}
+
 func (g *GormRepositories) Races() model.RaceRepository {
 	return model.NewRaceRepository(g.db)
 }
 
+func (g *GormRepositories) Challenges() model.ChallengeRepository {
+	return model.NewChallengeRepository(g.db)
+}
+
 func (g *GormRepositories) Teams() model.TeamRepository {
 	return model.NewTeamRepository(g.db)
 }
@@ -124,6 +184,10 @@ func (g *GormRepositories) Laps() model.LapRepository {
 	return model.NewLapRepository(g.db)
 }
 
+func (g *GormRepositories) Championships() model.ChampionshipRepository {
+	return model.NewChampionshipRepository(g.db)
+}
+
 func (g *GormRepositories) DB() *gorm.DB {
 	return g.db
 }
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+// readinessTimeout the maximum duration of the readiness checks
+var readinessTimeout = 5 * time.Second
+
+// databaseUnavailable the message of the failed checks when the database cannot be queried. The details of the
+// error (eg: the address of the database) are only logged.
+const databaseUnavailable = "database unavailable"
+
+// HealthStatus the status of a health check
+type HealthStatus string
+
+const (
+	// HealthUp the status of a successful health check
+	HealthUp HealthStatus = "up"
+	// HealthDown the status of a failed health check
+	HealthDown HealthStatus = "down"
+)
+
+// HealthCheck the result of a health check
+type HealthCheck struct {
+	Name   string       `json:"name"`
+	Status HealthStatus `json:"status"`
+	// Message the reason why the check failed
+	Message string `json:"message,omitempty"`
+}
+
+// Readiness the result of the readiness checks
+type Readiness struct {
+	// Status `up` if all checks succeeded, `down` otherwise
+	Status HealthStatus  `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// CheckReadiness verifies that the database is reachable and that its schema has the version expected by
+// the application. The checks are aborted when the given context is done.
+func (s *ApplicationService) CheckReadiness(ctx context.Context) Readiness {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	db := s.baseService.db.DB()
+	result := Readiness{
+		Status: HealthUp,
+		Checks: make([]HealthCheck, 0, 2),
+	}
+	check := func(name, message string) {
+		c := HealthCheck{
+			Name:   name,
+			Status: HealthUp,
+		}
+		if message != "" {
+			c.Status = HealthDown
+			c.Message = message
+			result.Status = HealthDown
+		}
+		result.Checks = append(result.Checks, c)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		logrus.WithError(err).Error("readiness check failed: database is unreachable")
+		check("database", databaseUnavailable)
+		check("schema", databaseUnavailable)
+		return result
+	}
+	check("database", "")
+	version, err := model.LoadSchemaVersion(ctx, db)
+	switch {
+	case err != nil:
+		logrus.WithError(err).Error("readiness check failed: unable to load the schema version")
+		check("schema", databaseUnavailable)
+	case version != model.SchemaVersion:
+		check("schema", fmt.Sprintf("schema version mismatch: expected %d, got %d", model.SchemaVersion, version))
+	default:
+		check("schema", "")
+	}
+	return result
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// catalog the translations of the texts of the generated documents, by locale then by French text.
+// The French texts are used as they are in the `fr` locale, as well as when a translation is missing.
+var catalog = map[string]map[string]string{
+	"en": {
+		// age categories
+		"Poussins":  "U11",
+		"Pupilles":  "U13",
+		"Benjamins": "U15",
+		"Minimes":   "U17",
+		"Cadets":    "U19",
+		"Juniors":   "U21",
+		"Seniors":   "Seniors",
+		"Vétérans":  "Veterans",
+		// rankings
+		"Classement %s":                                         "%s Ranking",
+		"Meilleurs tours":                                       "Fastest laps",
+		"Podiums %s":                                            "%s Podiums",
+		"Catégories sans équipe classée":                        "Categories without any ranked team",
+		"Liste de départ %s":                                    "%s Start List",
+		"Classement général":                                    "Overall Standings",
+		"Classement des clubs (nombre d'équipes)":               "Club Ranking (number of teams)",
+		"Classement des clubs (nombre de tours)":                "Club Ranking (number of laps)",
+		"Classement des clubs (somme des %d meilleures places)": "Club Ranking (sum of the %d best places)",
+		// columns
+		"Classement":    "Rank",
+		"Dossard":       "Bib",
+		"Equipe":        "Team",
+		"Equipes":       "Teams",
+		"Catégorie":     "Category",
+		"Coureurs":      "Athletes",
+		"Club":          "Club",
+		"Places":        "Places",
+		"Tours":         "Laps",
+		"Temps Total":   "Total Time",
+		"Meilleur Tour": "Fastest Lap",
+		"Moyenne":       "Average",
+		"Total":         "Total",
+		// dates
+		"02/01/2006": "2006-01-02",
+	},
+	"de": {
+		// age categories
+		"Poussins":  "U11",
+		"Pupilles":  "U13",
+		"Benjamins": "U15",
+		"Minimes":   "U17",
+		"Cadets":    "U19",
+		"Juniors":   "U21",
+		"Seniors":   "Hauptklasse",
+		"Vétérans":  "Senioren",
+		// rankings
+		"Classement %s":                                         "Wertung %s",
+		"Meilleurs tours":                                       "Schnellste Runden",
+		"Podiums %s":                                            "Siegerehrung %s",
+		"Catégories sans équipe classée":                        "Wertungsklassen ohne gewertetes Team",
+		"Liste de départ %s":                                    "Startliste %s",
+		"Classement général":                                    "Gesamtwertung",
+		"Classement des clubs (nombre d'équipes)":               "Vereinswertung (Anzahl der Teams)",
+		"Classement des clubs (nombre de tours)":                "Vereinswertung (Anzahl der Runden)",
+		"Classement des clubs (somme des %d meilleures places)": "Vereinswertung (Summe der %d besten Platzierungen)",
+		// columns
+		"Classement":    "Platz",
+		"Dossard":       "Startnummer",
+		"Equipe":        "Team",
+		"Equipes":       "Teams",
+		"Catégorie":     "Klasse",
+		"Coureurs":      "Teilnehmer",
+		"Club":          "Verein",
+		"Places":        "Platzierungen",
+		"Tours":         "Runden",
+		"Temps Total":   "Gesamtzeit",
+		"Meilleur Tour": "Schnellste Runde",
+		"Moyenne":       "Durchschnitt",
+		"Total":         "Gesamt",
+		// dates
+		"02/01/2006": "02.01.2006",
+	},
+}
+
+// Locales returns the locales in which the documents can be generated, sorted
+func Locales() []string {
+	result := []string{"fr"}
+	for l := range catalog {
+		result = append(result, l)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ParseLocale parses the given locale (eg: "en" or "EN"). Returns the locale of the generated documents
+// if the value is empty, and an error if the documents cannot be generated in this locale.
+func ParseLocale(value string) (string, error) {
+	if value == "" {
+		return defaultLocale, nil
+	}
+	locale := strings.ToLower(value)
+	for _, l := range Locales() {
+		if l == locale {
+			return locale, nil
+		}
+	}
+	return "", errors.Errorf("unsupported locale: '%s' (expected one of: %s)", value, strings.Join(Locales(), ", "))
+}
+
+// defaultLocale the locale of the generated documents, unless specified otherwise
+var defaultLocale = "fr"
+
+// SetLocale sets the locale of the generated documents, unless specified otherwise.
+// Returns an error if the documents cannot be generated in this locale.
+func SetLocale(locale string) error {
+	l, err := ParseLocale(locale)
+	if err != nil {
+		return err
+	}
+	defaultLocale = l
+	return nil
+}
+
+// translator translates the texts of the generated documents in a given locale
+type translator struct {
+	locale string
+}
+
+// newTranslator returns a translator for the given locale, or for the locale of the generated documents if
+// the given one is empty or unsupported
+func newTranslator(locale string) translator {
+	l, err := ParseLocale(locale)
+	if err != nil {
+		l = defaultLocale
+	}
+	return translator{locale: l}
+}
+
+// T returns the translation of the given French text, or the text itself if there is none
+func (t translator) T(text string) string {
+	if translation, found := catalog[t.locale][text]; found {
+		return translation
+	}
+	return text
+}
+
+// Tf returns the translation of the given French format, filled with the given arguments
+func (t translator) Tf(format string, args ...interface{}) string {
+	return fmt.Sprintf(t.T(format), args...)
+}
+
+// CategoryLabel returns the label of the given age and gender category in the given locale, eg: "Seniors / Mixte"
+// (or in the locale of the generated documents if the given one is empty)
+func CategoryLabel(locale, ageCategory, gender string) string {
+	msg := newTranslator(locale)
+	return fmt.Sprintf("%s / %s", msg.T(ageCategory+"s"), genderLabel(msg.locale, gender))
+}
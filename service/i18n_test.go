@@ -0,0 +1,40 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLocale(t *testing.T) {
+
+	t.Run("ok", func(t *testing.T) {
+
+		testcases := map[string]string{
+			"":   "fr",
+			"fr": "fr",
+			"EN": "en",
+			"de": "de",
+		}
+		for value, expected := range testcases {
+			t.Run(value, func(t *testing.T) {
+				// when
+				result, err := service.ParseLocale(value)
+				// then
+				require.NoError(t, err)
+				assert.Equal(t, expected, result)
+			})
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		// when
+		_, err := service.ParseLocale("it")
+		// then
+		require.Error(t, err)
+		assert.Equal(t, "unsupported locale: 'it' (expected one of: de, en, fr)", err.Error())
+	})
+}
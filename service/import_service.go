@@ -1,10 +1,9 @@
 package service
 
 import (
-	"encoding/csv"
+	"context"
 	"io"
 	"math"
-	"os"
 	"strconv"
 	"time"
 
@@ -26,17 +25,26 @@ func NewImportService(db *gorm.DB) ImportService {
 	}
 }
 
-// ImportFromFile imports the data from the given file
-func (s *ImportService) ImportFromFile(filename string) error {
-	// list races once for all and map by name
+// ImportFromFile imports the teams of the races of the given event from the given file (CSV, XLSX or ODS)
+func (s *ImportService) ImportFromFile(ctx context.Context, eventID int, filename string) error {
+	// list the races of the event and their challenges once for all and map by name
 	races := map[string]model.Race{}
-	err := Transactional(s.baseService, func(app Repositories) error {
-		all, err := app.Races().List()
+	challenges := map[int][]model.Challenge{}
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		_, err := app.Events().Lookup(eventID)
+		if err != nil {
+			return err
+		}
+		all, err := app.Races().ListByEvent(eventID)
 		if err != nil {
 			return err
 		}
 		for _, r := range all {
 			races[r.Name] = r
+			challenges[r.ID], err = app.Challenges().List(r.ID)
+			if err != nil {
+				return err
+			}
 		}
 		return nil
 	})
@@ -44,85 +52,142 @@ func (s *ImportService) ImportFromFile(filename string) error {
 		return errors.Wrapf(err, "unable to load data")
 	}
 
+	return Transactional(ctx, s.baseService, func(app Repositories) error {
+		// (re)open the source of records, in case the transaction is retried
+		src, err := NewRecordSource(filename)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		return importRecords(app, races, challenges, src)
+	})
+}
+
+// importRecords imports the teams from the given source of records. The first record contains the headers,
+// then each team is described by 2 consecutive records (one per team member)
+func importRecords(app Repositories, races map[string]model.Race, challenges map[int][]model.Challenge, src RecordSource) error {
 	var headers []string
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	r := csv.NewReader(file)
 	undefinedMember := model.TeamMember{}
 	teamMember1 := undefinedMember
 	teamMember2 := undefinedMember
-
-	return Transactional(s.baseService, func(app Repositories) error {
-		for {
-			record, err := r.Read()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return err
-			}
-			if headers == nil {
-				headers = record
+	for {
+		record, err := src.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if headers == nil {
+			headers = record
+		} else {
+			if teamMember1 == undefinedMember {
+				teamMember1, err = newTeamMember(record)
+				if err != nil {
+					return errors.Wrapf(err, "unable to create team member from %v", record)
+				}
 			} else {
-				if teamMember1 == undefinedMember {
-					teamMember1, err = newTeamMember(record)
-					if err != nil {
-						return errors.Wrapf(err, "unable to create team member from %v", record)
-					}
-				} else {
-					teamMember2, err = newTeamMember(record)
-					if err != nil {
-						return errors.Wrapf(err, "unable to create team member from %v", record)
-					}
-					var err error
-					bibNumber, err := strconv.Atoi(record[1])
-					if err != nil {
-						return errors.Wrapf(err, "unable to convert bibnumber '%s' to a number", record[10])
-					}
-					team := model.Team{
-						Name:        record[2], // team name
-						AgeCategory: GetTeamAgeCategory(teamMember1.AgeCategory, teamMember2.AgeCategory),
-						Challenge:   record[3], // race choice (open/entreprise)
-						BibNumber:   bibNumber,
-						Member1:     teamMember1,
-						Member2:     teamMember2,
-						Gender:      genderFrom(teamMember1, teamMember2),
-						RaceID:      races[record[0]].ID,
-					}
-					err = app.Teams().Create(&team)
-					if err != nil {
-						return errors.Wrapf(err, "unable to create team from %v", team)
-					}
-					// reset
-					teamMember1 = undefinedMember
-					teamMember2 = undefinedMember
+				teamMember2, err = newTeamMember(record)
+				if err != nil {
+					return errors.Wrapf(err, "unable to create team member from %v", record)
+				}
+				var err error
+				race, found := races[record[0]]
+				if !found {
+					return model.NewValidationError(model.ErrCodeInvalidTeam, "unknown race '%s' in the event", record[0])
+				}
+				bibNumber, err := strconv.Atoi(record[1])
+				if err != nil {
+					return errors.Wrapf(err, "unable to convert bibnumber '%s' to a number", record[1])
+				}
+				challenge, err := challengeOf(race, challenges[race.ID], record[3])
+				if err != nil {
+					return err
+				}
+				gender, err := genderOf(race, teamMember1, teamMember2)
+				if err != nil {
+					return err
+				}
+				team := model.Team{
+					Name:        record[2], // team name
+					AgeCategory: GetTeamAgeCategory(teamMember1.AgeCategory, teamMember2.AgeCategory),
+					Challenge:   challenge,
+					BibNumber:   bibNumber,
+					Member1:     teamMember1,
+					Member2:     teamMember2,
+					Gender:      gender,
+					RaceID:      race.ID,
 				}
+				err = app.Teams().Create(&team)
+				if err != nil {
+					return errors.Wrapf(err, "unable to create team from %v", team)
+				}
+				// reset
+				teamMember1 = undefinedMember
+				teamMember2 = undefinedMember
 			}
 		}
-		return nil
-	})
+	}
+	return nil
+}
+
+// noChallenge the value of the challenge of the teams which do not take part in a challenge, in the registration
+// files of the first races (where the choice was "open" or "entreprise")
+const noChallenge = "open"
+
+// challengeOf returns the name of the challenge of the given race which matches the given value, regardless of
+// the case and accents, or an empty string if the value is empty or "open" (ie: the team does not take part in a
+// challenge), unless a challenge with this name was declared in the race.
+// Returns an error if no such challenge was declared in the race.
+func challengeOf(race model.Race, challenges []model.Challenge, value string) (string, error) {
+	key := normalizeName(value)
+	if key == "" {
+		return "", nil
+	}
+	for _, c := range challenges {
+		if normalizeName(c.Name) == key {
+			return c.Name, nil
+		}
+	}
+	if key == noChallenge {
+		return "", nil
+	}
+	return "", model.NewValidationError(model.ErrCodeInvalidTeam, "unknown challenge '%s' in race '%s'", value, race.Name)
 }
 
-func genderFrom(teamMember1, teamMember2 model.TeamMember) string {
+// genderOf returns the gender category of the team with the given members, ie: men, women or mixed.
+// Returns an error if this gender category is not one of the given race.
+func genderOf(race model.Race, teamMember1, teamMember2 model.TeamMember) (string, error) {
+	gender := model.GenderMixed
 	if teamMember1.Gender == teamMember2.Gender {
-		return teamMember1.Gender
+		gender = teamMember1.Gender
 	}
-	return "M"
+	genders := race.Genders
+	if len(genders) == 0 {
+		genders = model.DefaultGenders
+	}
+	for _, g := range genders {
+		if g == gender {
+			return gender, nil
+		}
+	}
+	return "", model.NewValidationError(model.ErrCodeInvalidTeam, "no gender category '%s' in race '%s'", gender, race.Name)
 }
 
 func newTeamMember(record []string) (model.TeamMember, error) {
-	dateOfBirth, err := time.Parse("02/01/2006", record[6])
+	dateOfBirth, err := time.Parse(dateOfBirthLayout, record[6])
+	if err != nil {
+		return model.TeamMember{}, errors.Wrapf(err, "unable to parse date '%s'", record[6])
+	}
+	gender, err := genderMapping.Gender(record[7])
 	if err != nil {
-		return model.TeamMember{}, errors.Wrapf(err, "unable to parse date '%s'", record[3])
+		return model.TeamMember{}, err
 	}
 	return model.TeamMember{
 		LastName:    record[4],
 		FirstName:   record[5],
 		DateOfBirth: dateOfBirth,
-		Gender:      record[7],
+		Gender:      gender,
 		AgeCategory: GetAgeCategory(dateOfBirth),
 		Club:        record[10],
 	}, nil
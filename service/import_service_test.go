@@ -1,15 +1,175 @@
 package service_test
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/vatriathlon/stopwatch/configuration"
+	"github.com/vatriathlon/stopwatch/model"
 	"github.com/vatriathlon/stopwatch/service"
+	testmodel "github.com/vatriathlon/stopwatch/test/model"
+	testsuite "github.com/vatriathlon/stopwatch/test/suite"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
 )
 
+func TestImportService(t *testing.T) {
+	config, err := configuration.New()
+	require.NoError(t, err)
+	suite.Run(t, &ImportServiceTestSuite{DBTestSuite: testsuite.NewDBTestSuite(config)})
+}
+
+type ImportServiceTestSuite struct {
+	testsuite.DBTestSuite
+}
+
+func (s *ImportServiceTestSuite) TestImportChallenges() {
+	// given a race with a declared challenge
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    "Bike & Run XS",
+	}
+	err := model.NewRaceRepository(s.DB).Create(&race)
+	require.NoError(s.T(), err)
+	err = model.NewChallengeRepository(s.DB).Create(&model.Challenge{RaceID: race.ID, Name: "Challenge Entreprise"})
+	require.NoError(s.T(), err)
+	svc := service.NewImportService(s.DB)
+	records := func(bibNumber int, challenge string) string {
+		return "course,dossard,equipe,challenge,nom,prenom,naissance,sexe,,,club\n" +
+			fmt.Sprintf("Bike & Run XS,%[1]d,team %[1]d,%[2]s,Doe,John,12/05/1980,H,,,VA\n", bibNumber, challenge) +
+			fmt.Sprintf("Bike & Run XS,%[1]d,team %[1]d,%[2]s,Doe,Jane,03/11/1982,F,,,VA\n", bibNumber, challenge)
+	}
+
+	s.T().Run("declared challenge", func(t *testing.T) {
+		// given
+		filename := writeFile(t, "teams.csv", map[string]string{"": records(1, "challenge entreprise")})
+		// when
+		err := svc.ImportFromFile(context.Background(), event.ID, filename)
+		// then the name of the challenge is the declared one
+		require.NoError(t, err)
+		team, err := model.NewTeamRepository(s.DB).LoadByBibNumber(race.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Challenge Entreprise", team.Challenge)
+	})
+
+	s.T().Run("no challenge", func(t *testing.T) {
+		// given
+		filename := writeFile(t, "teams.csv", map[string]string{"": records(2, "")})
+		// when
+		err := svc.ImportFromFile(context.Background(), event.ID, filename)
+		// then
+		require.NoError(t, err)
+		team, err := model.NewTeamRepository(s.DB).LoadByBibNumber(race.ID, 2)
+		require.NoError(t, err)
+		assert.Empty(t, team.Challenge)
+	})
+
+	s.T().Run("open", func(t *testing.T) {
+		// given the value of the teams which did not take part in a challenge in the first races
+		filename := writeFile(t, "teams.csv", map[string]string{"": records(4, "Open")})
+		// when
+		err := svc.ImportFromFile(context.Background(), event.ID, filename)
+		// then
+		require.NoError(t, err)
+		team, err := model.NewTeamRepository(s.DB).LoadByBibNumber(race.ID, 4)
+		require.NoError(t, err)
+		assert.Empty(t, team.Challenge)
+		challenges, err := model.NewChallengeRepository(s.DB).List(race.ID)
+		require.NoError(t, err)
+		require.Len(t, challenges, 1)
+		assert.Equal(t, "Challenge Entreprise", challenges[0].Name)
+	})
+
+	s.T().Run("unknown challenge", func(t *testing.T) {
+		// given
+		filename := writeFile(t, "teams.csv", map[string]string{"": records(3, "Challenge Famille")})
+		// when
+		err := svc.ImportFromFile(context.Background(), event.ID, filename)
+		// then
+		require.Error(t, err)
+		assert.True(t, model.IsValidationError(err))
+		assert.Contains(t, err.Error(), "unknown challenge 'Challenge Famille' in race 'Bike & Run XS'")
+	})
+}
+
+func (s *ImportServiceTestSuite) TestImportGenders() {
+	// given a race of mixed teams only
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    "Bike & Run Famille",
+		Genders: []string{model.GenderMixed},
+	}
+	err := model.NewRaceRepository(s.DB).Create(&race)
+	require.NoError(s.T(), err)
+	svc := service.NewImportService(s.DB)
+	records := func(bibNumber int, gender1, gender2 string) string {
+		return "course,dossard,equipe,challenge,nom,prenom,naissance,sexe,,,club\n" +
+			fmt.Sprintf("Bike & Run Famille,%[1]d,team %[1]d,,Doe,John,12/05/1980,%[2]s,,,VA\n", bibNumber, gender1) +
+			fmt.Sprintf("Bike & Run Famille,%[1]d,team %[1]d,,Doe,Jane,03/11/1982,%[2]s,,,VA\n", bibNumber, gender2)
+	}
+
+	s.T().Run("values of the registration platform", func(t *testing.T) {
+		// given
+		filename := writeFile(t, "teams.csv", map[string]string{"": records(1, "Homme", "female")})
+		// when
+		err := svc.ImportFromFile(context.Background(), event.ID, filename)
+		// then the genders are normalized
+		require.NoError(t, err)
+		team, err := model.NewTeamRepository(s.DB).LoadByBibNumber(race.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, model.GenderMixed, team.Gender)
+		assert.Equal(t, model.GenderMen, team.Member1.Gender)
+		assert.Equal(t, model.GenderWomen, team.Member2.Gender)
+	})
+
+	s.T().Run("custom mapping", func(t *testing.T) {
+		// given
+		mapping, err := service.ParseGenderMapping("M=H")
+		require.NoError(t, err)
+		service.SetGenderMapping(mapping)
+		defer func() {
+			mapping, _ := service.ParseGenderMapping("")
+			service.SetGenderMapping(mapping)
+		}()
+		filename := writeFile(t, "teams.csv", map[string]string{"": records(2, "M", "F")})
+		// when
+		err = svc.ImportFromFile(context.Background(), event.ID, filename)
+		// then
+		require.NoError(t, err)
+		team, err := model.NewTeamRepository(s.DB).LoadByBibNumber(race.ID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, model.GenderMixed, team.Gender)
+	})
+
+	s.T().Run("unknown gender", func(t *testing.T) {
+		// given
+		filename := writeFile(t, "teams.csv", map[string]string{"": records(3, "X", "F")})
+		// when
+		err := svc.ImportFromFile(context.Background(), event.ID, filename)
+		// then
+		require.Error(t, err)
+		assert.True(t, model.IsValidationError(err))
+		assert.Contains(t, err.Error(), "unknown gender 'X'")
+	})
+
+	s.T().Run("gender category not in the race", func(t *testing.T) {
+		// given
+		filename := writeFile(t, "teams.csv", map[string]string{"": records(4, "H", "H")})
+		// when
+		err := svc.ImportFromFile(context.Background(), event.ID, filename)
+		// then
+		require.Error(t, err)
+		assert.True(t, model.IsValidationError(err))
+		assert.Contains(t, err.Error(), "no gender category 'H' in race 'Bike & Run Famille'")
+	})
+}
+
 func TestGetAgeCategory(t *testing.T) {
 
 	pattern := "2006-01-02"
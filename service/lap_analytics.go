@@ -0,0 +1,172 @@
+package service
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/model"
+)
+
+// LapSplit a lap of a team, along with its duration
+type LapSplit struct {
+	Number   int
+	Time     time.Time
+	Duration time.Duration
+}
+
+// LapAnalytics the analytics of the laps of a team
+type LapAnalytics struct {
+	BibNumber int
+	Laps      []LapSplit
+	// FastestLap the fastest lap, or nil if no lap was recorded
+	FastestLap *LapSplit
+	// AveragePace the average duration of a lap
+	AveragePace time.Duration
+	// Slowdown the difference between the average duration of the laps in the second half
+	// and in the first half of the race (a positive value means that the team slowed down)
+	Slowdown time.Duration
+	// Consistency the standard deviation of the lap durations (the lower, the more consistent)
+	Consistency time.Duration
+}
+
+// NewLapAnalytics computes the analytics of the given laps of a team in the given race.
+// The duration of the first lap is computed from the start of the race, which is ignored if the race
+// has not started.
+func NewLapAnalytics(race model.Race, bibNumber int, laps []model.Lap) LapAnalytics {
+	sorted := make([]model.Lap, len(laps))
+	copy(sorted, laps)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+	result := LapAnalytics{
+		BibNumber: bibNumber,
+		Laps:      make([]LapSplit, 0, len(sorted)),
+	}
+	previous := race.StartTime
+	for i, l := range sorted {
+		split := LapSplit{
+			Number: i + 1,
+			Time:   l.Time,
+		}
+		if !previous.IsZero() {
+			split.Duration = l.Time.Sub(previous)
+		}
+		previous = l.Time
+		result.Laps = append(result.Laps, split)
+	}
+	// only consider the laps with a known duration
+	timed := make([]LapSplit, 0, len(result.Laps))
+	for _, l := range result.Laps {
+		if l.Duration > 0 {
+			timed = append(timed, l)
+		}
+	}
+	if len(timed) == 0 {
+		return result
+	}
+	var total time.Duration
+	for i, l := range timed {
+		total += l.Duration
+		if result.FastestLap == nil || l.Duration < result.FastestLap.Duration {
+			result.FastestLap = &timed[i]
+		}
+	}
+	result.AveragePace = total / time.Duration(len(timed))
+	if len(timed) > 1 {
+		firstHalf := timed[:len(timed)/2]
+		secondHalf := timed[len(timed)-len(timed)/2:]
+		result.Slowdown = averageDuration(secondHalf) - averageDuration(firstHalf)
+		variance := 0.0
+		for _, l := range timed {
+			d := float64(l.Duration - result.AveragePace)
+			variance += d * d
+		}
+		result.Consistency = time.Duration(math.Sqrt(variance / float64(len(timed))))
+	}
+	return result
+}
+
+func averageDuration(laps []LapSplit) time.Duration {
+	var total time.Duration
+	for _, l := range laps {
+		total += l.Duration
+	}
+	return total / time.Duration(len(laps))
+}
+
+type jsonLapSplit struct {
+	Number   int       `json:"number"`
+	Time     time.Time `json:"time"`
+	Duration float64   `json:"duration"`
+}
+
+// MarshalJSON implements json.Marshaler. The duration is expressed in seconds.
+func (l LapSplit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonLapSplit{
+		Number:   l.Number,
+		Time:     l.Time,
+		Duration: l.Duration.Seconds(),
+	})
+}
+
+type jsonLapAnalytics struct {
+	BibNumber   int        `json:"bibNumber"`
+	Laps        []LapSplit `json:"laps"`
+	FastestLap  *LapSplit  `json:"fastestLap,omitempty"`
+	AveragePace float64    `json:"averagePace"`
+	Slowdown    float64    `json:"slowdown"`
+	Consistency float64    `json:"consistency"`
+}
+
+// MarshalJSON implements json.Marshaler. All durations are expressed in seconds.
+func (a LapAnalytics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonLapAnalytics{
+		BibNumber:   a.BibNumber,
+		Laps:        a.Laps,
+		FastestLap:  a.FastestLap,
+		AveragePace: a.AveragePace.Seconds(),
+		Slowdown:    a.Slowdown.Seconds(),
+		Consistency: a.Consistency.Seconds(),
+	})
+}
+
+// FastestLapAwards returns a ranking with the team with the fastest lap in each of the given rankings.
+// The category of each result is the title of the ranking in which it was awarded, and its rank is 1 since the team
+// is the winner of this category.
+func FastestLapAwards(race model.Race, rankings []Ranking) Ranking {
+	locale := ""
+	if len(rankings) > 0 {
+		locale = rankings[0].Locale
+	}
+	msg := newTranslator(locale)
+	awards := Ranking{
+		Race:                race,
+		Name:                "Meilleurs-tours",
+		Title:               msg.T("Meilleurs tours"),
+		IncludeCategory:     true,
+		IncludeLapAnalytics: true,
+		Results:             []TeamResult{},
+		Locale:              msg.locale,
+	}
+	for _, ranking := range rankings {
+		var fastest *TeamResult
+		for i, r := range ranking.Results {
+			if r.FastestLap <= 0 {
+				continue
+			}
+			if fastest == nil || r.FastestLap < fastest.FastestLap {
+				fastest = &ranking.Results[i]
+			}
+		}
+		if fastest == nil {
+			continue
+		}
+		award := *fastest
+		award.Rank = 1
+		award.Category = ranking.Title
+		awards.Results = append(awards.Results, award)
+	}
+	return awards
+}
@@ -0,0 +1,155 @@
+package service_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLapAnalytics(t *testing.T) {
+
+	start := time.Date(2019, 11, 17, 10, 0, 0, 0, time.UTC)
+	newLaps := func(durations ...time.Duration) []model.Lap {
+		laps := []model.Lap{}
+		lapTime := start
+		for _, d := range durations {
+			lapTime = lapTime.Add(d)
+			laps = append(laps, model.Lap{Time: lapTime})
+		}
+		return laps
+	}
+
+	t.Run("started race", func(t *testing.T) {
+		// given
+		race := model.Race{StartTime: start}
+		laps := newLaps(10*time.Minute, 12*time.Minute, 11*time.Minute, 15*time.Minute)
+		// reverse the order of the laps, which should not matter
+		laps[0], laps[3] = laps[3], laps[0]
+		// when
+		result := service.NewLapAnalytics(race, 7, laps)
+		// then
+		assert.Equal(t, 7, result.BibNumber)
+		require.Len(t, result.Laps, 4)
+		assert.Equal(t, 1, result.Laps[0].Number)
+		assert.Equal(t, 10*time.Minute, result.Laps[0].Duration)
+		assert.Equal(t, 12*time.Minute, result.Laps[1].Duration)
+		assert.Equal(t, 11*time.Minute, result.Laps[2].Duration)
+		assert.Equal(t, 15*time.Minute, result.Laps[3].Duration)
+		require.NotNil(t, result.FastestLap)
+		assert.Equal(t, 1, result.FastestLap.Number)
+		assert.Equal(t, 12*time.Minute, result.AveragePace)
+		// (11+15)/2 - (10+12)/2
+		assert.Equal(t, 2*time.Minute, result.Slowdown)
+		// sqrt((4+0+1+9)/4) minutes
+		assert.Equal(t, 112, int(result.Consistency.Seconds()))
+	})
+
+	t.Run("race not started", func(t *testing.T) {
+		// given
+		race := model.Race{}
+		laps := newLaps(10*time.Minute, 12*time.Minute, 11*time.Minute)
+		// when
+		result := service.NewLapAnalytics(race, 7, laps)
+		// then
+		require.Len(t, result.Laps, 3)
+		assert.Equal(t, time.Duration(0), result.Laps[0].Duration)
+		require.NotNil(t, result.FastestLap)
+		assert.Equal(t, 3, result.FastestLap.Number)
+		assert.Equal(t, 11*time.Minute+30*time.Second, result.AveragePace)
+	})
+
+	t.Run("no lap", func(t *testing.T) {
+		// when
+		result := service.NewLapAnalytics(model.Race{StartTime: start}, 7, []model.Lap{})
+		// then
+		assert.Empty(t, result.Laps)
+		assert.Nil(t, result.FastestLap)
+		assert.Equal(t, time.Duration(0), result.AveragePace)
+	})
+
+	t.Run("daylight saving time change", func(t *testing.T) {
+		// given a race which started at 01:30 CEST, before the clocks were set back from 03:00 CEST to 02:00 CET
+		paris, err := time.LoadLocation("Europe/Paris")
+		require.NoError(t, err)
+		start := time.Date(2019, 10, 27, 1, 30, 0, 0, paris)
+		race := model.Race{StartTime: start}
+		laps := []model.Lap{
+			{Time: start.Add(45 * time.Minute).UTC()},          // 02:15 CEST
+			{Time: start.Add(105 * time.Minute).In(paris)},     // 02:15 CET
+			{Time: time.Date(2019, 10, 27, 3, 0, 0, 0, paris)}, // 03:00 CET
+		}
+		// when
+		result := service.NewLapAnalytics(race, 7, laps)
+		// then the durations are the elapsed times, regardless of the wall clocks and of the time zones of the laps
+		require.Len(t, result.Laps, 3)
+		assert.Equal(t, 45*time.Minute, result.Laps[0].Duration)
+		assert.Equal(t, time.Hour, result.Laps[1].Duration)
+		assert.Equal(t, 45*time.Minute, result.Laps[2].Duration)
+		assert.Equal(t, "02:15", result.Laps[1].Time.Format("15:04"))
+		data, err := json.Marshal(result.Laps[1])
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"2019-10-27T02:15:00+01:00"`)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		// given
+		race := model.Race{StartTime: start}
+		result := service.NewLapAnalytics(race, 7, newLaps(10*time.Minute, 12*time.Minute))
+		// when
+		data, err := json.Marshal(result)
+		// then
+		require.NoError(t, err)
+		var actual map[string]interface{}
+		err = json.Unmarshal(data, &actual)
+		require.NoError(t, err)
+		assert.Equal(t, float64(7), actual["bibNumber"])
+		assert.Equal(t, float64(660), actual["averagePace"])
+		assert.Equal(t, float64(120), actual["slowdown"])
+		assert.Equal(t, float64(600), actual["fastestLap"].(map[string]interface{})["duration"])
+		assert.Len(t, actual["laps"], 2)
+	})
+}
+
+func TestFastestLapAwards(t *testing.T) {
+	// given
+	race := model.Race{Name: "Bike & Run XS"}
+	rankings := []service.Ranking{
+		{
+			Title: "Scratch",
+			Results: []service.TeamResult{
+				{BibNumber: 1, FastestLap: 9 * time.Minute},
+				{BibNumber: 2, FastestLap: 8 * time.Minute},
+				{BibNumber: 3, FastestLap: 10 * time.Minute},
+			},
+		},
+		{
+			Title: "Seniors / Hommes",
+			Results: []service.TeamResult{
+				{BibNumber: 1, FastestLap: 9 * time.Minute},
+				{BibNumber: 3, FastestLap: 10 * time.Minute},
+			},
+		},
+		{
+			Title:   "Cadets / Femmes",
+			Results: []service.TeamResult{},
+		},
+	}
+	// when
+	result := service.FastestLapAwards(race, rankings)
+	// then
+	assert.True(t, result.IncludeCategory)
+	assert.True(t, result.IncludeLapAnalytics)
+	require.Len(t, result.Results, 2)
+	assert.Equal(t, 2, result.Results[0].BibNumber)
+	assert.Equal(t, "Scratch", result.Results[0].Category)
+	assert.Equal(t, 1, result.Results[1].BibNumber)
+	assert.Equal(t, 1, result.Results[0].Rank)
+	assert.Equal(t, 1, result.Results[1].Rank)
+	assert.Equal(t, "Seniors / Hommes", result.Results[1].Category)
+}
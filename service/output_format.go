@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// OutputFormat the format of a generated document
+type OutputFormat string
+
+const (
+	// AsciidocFormat the Asciidoc format
+	AsciidocFormat OutputFormat = "adoc"
+	// CSVFormat the CSV format
+	CSVFormat OutputFormat = "csv"
+	// JSONFormat the JSON format
+	JSONFormat OutputFormat = "json"
+	// HTMLFormat the HTML format (self-contained document)
+	HTMLFormat OutputFormat = "html"
+	// PDFFormat the PDF format (print-ready document)
+	PDFFormat OutputFormat = "pdf"
+)
+
+// supportedOutputFormats the formats in which the documents can be generated
+var supportedOutputFormats = []OutputFormat{AsciidocFormat, CSVFormat, JSONFormat, HTMLFormat, PDFFormat}
+
+// ParseOutputFormats parses the given comma-separated list of formats (eg: "adoc,csv")
+func ParseOutputFormats(value string) ([]OutputFormat, error) {
+	result := []OutputFormat{}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" {
+			continue
+		}
+		f, err := parseOutputFormat(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("missing output format")
+	}
+	return result, nil
+}
+
+func parseOutputFormat(value string) (OutputFormat, error) {
+	for _, f := range supportedOutputFormats {
+		if string(f) == value {
+			return f, nil
+		}
+	}
+	supported := make([]string, len(supportedOutputFormats))
+	for i, f := range supportedOutputFormats {
+		supported[i] = string(f)
+	}
+	return "", errors.Errorf("unsupported output format: '%s' (expected one of: %s)", value, strings.Join(supported, ", "))
+}
+
+// outputFilename returns the name of the file to generate for the given title (eg: the name of the race),
+// document name and format
+func outputFilename(outputDir string, title string, name string, format OutputFormat) string {
+	return filepath.Join(outputDir, fmt.Sprintf("%s-%s.%s", strings.Replace(title, " ", "-", -1), name, format))
+}
@@ -0,0 +1,38 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutputFormats(t *testing.T) {
+
+	t.Run("ok", func(t *testing.T) {
+		// when
+		result, err := service.ParseOutputFormats("adoc, CSV,json")
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []service.OutputFormat{service.AsciidocFormat, service.CSVFormat, service.JSONFormat}, result)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+
+		t.Run("unsupported format", func(t *testing.T) {
+			// when
+			_, err := service.ParseOutputFormats("adoc,docx")
+			// then
+			require.Error(t, err)
+		})
+
+		t.Run("missing format", func(t *testing.T) {
+			// when
+			_, err := service.ParseOutputFormats(" , ")
+			// then
+			require.Error(t, err)
+		})
+	})
+}
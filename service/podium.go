@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+
+	"github.com/vatriathlon/stopwatch/model"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// PodiumOptions the options to compute the podiums
+type PodiumOptions struct {
+	// Size the number of teams on each podium (default: 3)
+	Size int
+	// ExcludeScratchWinners 'true' if the teams on the scratch podium cannot be awarded in their category,
+	// since a team can only be awarded once
+	ExcludeScratchWinners bool
+	// Locale the locale of the generated documents (default: the locale set with `SetLocale`)
+	Locale string
+}
+
+// Podium the podium of a category
+type Podium struct {
+	Title   string
+	Results []TeamResult
+}
+
+// PodiumReport the podiums of all the categories of a race, in ceremony order
+type PodiumReport struct {
+	Race    model.Race
+	Podiums []Podium
+	// Missing the title of the categories in which no team was ranked
+	Missing []string
+	// Locale the locale of the generated documents
+	Locale string
+}
+
+// Podiums computes the podiums of the given race
+func (s *ResultService) Podiums(ctx context.Context, raceID int, opts PodiumOptions) (PodiumReport, error) {
+	rankings, err := s.Rankings(ctx, raceID, opts.Locale)
+	if err != nil {
+		return PodiumReport{}, errors.Wrap(err, "unable to compute podiums")
+	}
+	return NewPodiumReport(rankings, opts), nil
+}
+
+// NewPodiumReport returns the podiums of the given rankings, in ceremony order: the categories
+// (from the youngest to the oldest teams), then the challenges and finally, the scratch.
+func NewPodiumReport(rankings []Ranking, opts PodiumOptions) PodiumReport {
+	if opts.Size <= 0 {
+		opts.Size = 3
+	}
+	report := PodiumReport{
+		Podiums: []Podium{},
+		Missing: []string{},
+	}
+	// the scratch podium is computed first, since its teams may be excluded from the other podiums
+	var scratch *Podium
+	awarded := map[int]bool{}
+	for _, ranking := range rankings {
+		report.Race = ranking.Race
+		report.Locale = ranking.Locale
+		if ranking.Name == ScratchRankingName {
+			p := newPodium(ranking, opts.Size, awarded)
+			scratch = &p
+			if opts.ExcludeScratchWinners {
+				for _, r := range p.Results {
+					awarded[r.BibNumber] = true
+				}
+			}
+		}
+	}
+	categories := []Podium{}
+	challenges := []Podium{}
+	for _, ranking := range rankings {
+		if ranking.Name == ScratchRankingName {
+			continue
+		}
+		p := newPodium(ranking, opts.Size, awarded)
+		if len(p.Results) == 0 {
+			report.Missing = append(report.Missing, p.Title)
+			continue
+		}
+		if ranking.IncludeCategory {
+			// rankings which include teams of all categories are challenges
+			challenges = append(challenges, p)
+		} else {
+			categories = append(categories, p)
+		}
+	}
+	report.Podiums = append(report.Podiums, categories...)
+	report.Podiums = append(report.Podiums, challenges...)
+	if scratch != nil {
+		if len(scratch.Results) == 0 {
+			report.Missing = append(report.Missing, scratch.Title)
+		} else {
+			report.Podiums = append(report.Podiums, *scratch)
+		}
+	}
+	return report
+}
+
+// newPodium returns the podium of the given ranking, excluding the teams which were already awarded
+func newPodium(ranking Ranking, size int, awarded map[int]bool) Podium {
+	p := Podium{
+		Title:   ranking.Title,
+		Results: []TeamResult{},
+	}
+	for _, r := range ranking.Results {
+		if len(p.Results) == size {
+			break
+		}
+		if awarded[r.BibNumber] {
+			continue
+		}
+		r.Rank = len(p.Results) + 1
+		p.Results = append(p.Results, r)
+	}
+	return p
+}
+
+// GeneratePodiums generates the podium report of the given race in the given output directory, in all the given formats
+func (s *ResultService) GeneratePodiums(ctx context.Context, raceID int, outputDir string, formats []OutputFormat, opts PodiumOptions) error {
+	report, err := s.Podiums(ctx, raceID, opts)
+	if err != nil {
+		return err
+	}
+	for _, format := range formats {
+		filename := outputFilename(outputDir, report.Race.Name, "Podiums", format)
+		logrus.WithField("race_name", report.Race.Name).
+			WithField("podiums", len(report.Podiums)).
+			WithField("missing", len(report.Missing)).
+			WithField("output_file", filename).
+			Info("generating podiums...")
+		if err := writePodiumReport(filename, report, format); err != nil {
+			return errors.Wrapf(err, "unable to generate podiums in %s", format)
+		}
+	}
+	return nil
+}
+
+func writePodiumReport(filename string, report PodiumReport, format OutputFormat) error {
+	return writeTable(filename, podiumReportTable(report), format)
+}
+
+// podiumReportTable returns the table of the given podium report, with a section per podium
+func podiumReportTable(report PodiumReport) table {
+	msg := newTranslator(report.Locale)
+	t := table{
+		title:         msg.Tf("Podiums %s", report.Race.Name),
+		columns:       resultColumns(msg, hideCategory, false),
+		sections:      make([]tableSection, 0, len(report.Podiums)+1),
+		sectionColumn: msg.T("Catégorie"),
+		data:          newJSONPodiumReport(report),
+		locale:        msg.locale,
+	}
+	for _, p := range report.Podiums {
+		rows := make([][]string, len(p.Results))
+		for i, r := range p.Results {
+			rows[i] = resultRow(r, hideCategory, false)
+		}
+		t.sections = append(t.sections, tableSection{title: p.Title, rows: rows})
+	}
+	if len(report.Missing) > 0 {
+		t.sections = append(t.sections, tableSection{title: msg.T("Catégories sans équipe classée"), items: report.Missing})
+	}
+	return t
+}
+
+type jsonPodiumReport struct {
+	Race    string        `json:"race"`
+	Podiums []jsonRanking `json:"podiums"`
+	Missing []string      `json:"missing"`
+}
+
+func newJSONPodiumReport(report PodiumReport) jsonPodiumReport {
+	result := jsonPodiumReport{
+		Race:    report.Race.Name,
+		Podiums: make([]jsonRanking, len(report.Podiums)),
+		Missing: report.Missing,
+	}
+	for i, p := range report.Podiums {
+		result.Podiums[i] = newJSONRanking(Ranking{
+			Race:    report.Race,
+			Title:   p.Title,
+			Results: p.Results,
+		})
+	}
+	return result
+}
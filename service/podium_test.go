@@ -0,0 +1,93 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPodiumReport(t *testing.T) {
+
+	race := model.Race{ID: 1, Name: "Bike & Run XS"}
+	newRanking := func(name string, includeCategory bool, bibNumbers ...int) service.Ranking {
+		ranking := service.Ranking{
+			Race:            race,
+			Name:            name,
+			Title:           name,
+			IncludeCategory: includeCategory,
+			Results:         []service.TeamResult{},
+		}
+		for i, b := range bibNumbers {
+			ranking.Results = append(ranking.Results, service.TeamResult{Rank: i + 1, BibNumber: b})
+		}
+		return ranking
+	}
+	rankings := []service.Ranking{
+		newRanking(service.ScratchRankingName, true, 1, 2, 3, 4, 5, 6, 7),
+		newRanking("Challenge Entreprise", true, 3, 6),
+		newRanking("Senior-H", false, 1, 4, 5, 7),
+		newRanking("Senior-F", false, 2, 6),
+		newRanking("Cadet-F", false),
+	}
+	bibNumbers := func(p service.Podium) []int {
+		result := []int{}
+		for _, r := range p.Results {
+			result = append(result, r.BibNumber)
+		}
+		return result
+	}
+
+	t.Run("scratch winners included in categories", func(t *testing.T) {
+		// when
+		report := service.NewPodiumReport(rankings, service.PodiumOptions{})
+		// then
+		assert.Equal(t, race, report.Race)
+		require.Len(t, report.Podiums, 4)
+		// ceremony order: categories, then challenges and finally, the scratch
+		assert.Equal(t, "Senior-H", report.Podiums[0].Title)
+		assert.Equal(t, []int{1, 4, 5}, bibNumbers(report.Podiums[0]))
+		assert.Equal(t, "Senior-F", report.Podiums[1].Title)
+		assert.Equal(t, []int{2, 6}, bibNumbers(report.Podiums[1]))
+		assert.Equal(t, "Challenge Entreprise", report.Podiums[2].Title)
+		assert.Equal(t, []int{3, 6}, bibNumbers(report.Podiums[2]))
+		assert.Equal(t, service.ScratchRankingName, report.Podiums[3].Title)
+		assert.Equal(t, []int{1, 2, 3}, bibNumbers(report.Podiums[3]))
+		assert.Equal(t, []string{"Cadet-F"}, report.Missing)
+	})
+
+	t.Run("scratch winners excluded from categories", func(t *testing.T) {
+		// when
+		report := service.NewPodiumReport(rankings, service.PodiumOptions{
+			ExcludeScratchWinners: true,
+		})
+		// then
+		require.Len(t, report.Podiums, 4)
+		assert.Equal(t, "Senior-H", report.Podiums[0].Title)
+		assert.Equal(t, []int{4, 5, 7}, bibNumbers(report.Podiums[0]))
+		// ranks are recomputed within the podium
+		assert.Equal(t, 1, report.Podiums[0].Results[0].Rank)
+		assert.Equal(t, "Senior-F", report.Podiums[1].Title)
+		assert.Equal(t, []int{6}, bibNumbers(report.Podiums[1]))
+		assert.Equal(t, "Challenge Entreprise", report.Podiums[2].Title)
+		assert.Equal(t, []int{6}, bibNumbers(report.Podiums[2]))
+		assert.Equal(t, service.ScratchRankingName, report.Podiums[3].Title)
+		assert.Equal(t, []int{1, 2, 3}, bibNumbers(report.Podiums[3]))
+		assert.Equal(t, []string{"Cadet-F"}, report.Missing)
+	})
+
+	t.Run("custom podium size", func(t *testing.T) {
+		// when
+		report := service.NewPodiumReport(rankings, service.PodiumOptions{
+			Size: 1,
+		})
+		// then
+		require.Len(t, report.Podiums, 4)
+		for _, p := range report.Podiums {
+			assert.Len(t, p.Results, 1)
+		}
+	})
+}
@@ -0,0 +1,475 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RecordSource a source of records (ie, rows of cells) to import.
+// Read returns `io.EOF` once all records have been read.
+type RecordSource interface {
+	Read() ([]string, error)
+	Close() error
+}
+
+// NewRecordSource returns a new RecordSource for the given file, based on its extension
+// (`.csv`, `.xlsx` or `.ods`)
+func NewRecordSource(filename string) (RecordSource, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		return newXLSXRecordSource(filename)
+	case ".ods":
+		return newODSRecordSource(filename)
+	case ".csv", "":
+		return newCSVRecordSource(filename)
+	default:
+		return nil, errors.Errorf("unsupported file format: '%s'", filepath.Ext(filename))
+	}
+}
+
+// ------------------------------------
+// CSV
+// ------------------------------------
+
+type csvRecordSource struct {
+	file   *os.File
+	reader *csv.Reader
+}
+
+func newCSVRecordSource(filename string) (RecordSource, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &csvRecordSource{
+		file:   file,
+		reader: csv.NewReader(file),
+	}, nil
+}
+
+func (s *csvRecordSource) Read() ([]string, error) {
+	return s.reader.Read()
+}
+
+func (s *csvRecordSource) Close() error {
+	return s.file.Close()
+}
+
+// ------------------------------------
+// Spreadsheets
+// ------------------------------------
+
+// dateOfBirthLayout the layout of dates as expected by the importer
+const dateOfBirthLayout = "02/01/2006"
+
+// sliceRecordSource a record source for the rows of a spreadsheet, once they have all been loaded in memory.
+// All records are padded to the length of the first one (ie, the headers) since trailing empty cells
+// are not stored in the spreadsheet files.
+type sliceRecordSource struct {
+	records [][]string
+	width   int
+	index   int
+}
+
+func newSliceRecordSource(records [][]string) *sliceRecordSource {
+	result := &sliceRecordSource{}
+	for _, record := range records {
+		if isBlank(record) {
+			continue
+		}
+		if result.width == 0 {
+			result.width = len(record)
+		}
+		result.records = append(result.records, record)
+	}
+	return result
+}
+
+func (s *sliceRecordSource) Read() ([]string, error) {
+	if s.index >= len(s.records) {
+		return nil, io.EOF
+	}
+	record := s.records[s.index]
+	s.index++
+	for len(record) < s.width {
+		record = append(record, "")
+	}
+	return record, nil
+}
+
+func (s *sliceRecordSource) Close() error {
+	return nil
+}
+
+func isBlank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func readZipEntry(r *zip.ReadCloser, name string) ([]byte, error) {
+	for _, f := range r.File {
+		if f.Name == name {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to open '%s'", name)
+			}
+			defer rc.Close()
+			return ioutil.ReadAll(rc)
+		}
+	}
+	return nil, nil
+}
+
+// ------------------------------------
+// XLSX (Office Open XML)
+// ------------------------------------
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	result := strings.Builder{}
+	for _, r := range t.Runs {
+		result.WriteString(r.Text)
+	}
+	return result.String()
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID         int    `xml:"numFmtId,attr"`
+		FormatCode string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref       string       `xml:"r,attr"`
+			Type      string       `xml:"t,attr"`
+			Style     int          `xml:"s,attr"`
+			Value     string       `xml:"v"`
+			InlineStr xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func newXLSXRecordSource(filename string) (RecordSource, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open '%s'", filename)
+	}
+	defer r.Close()
+	sheetPath, err := xlsxFirstSheetPath(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read '%s'", filename)
+	}
+	sharedStrings := xlsxSharedStrings{}
+	if err := unmarshalZipEntry(r, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+		return nil, errors.Wrapf(err, "unable to read '%s'", filename)
+	}
+	styles := xlsxStyles{}
+	if err := unmarshalZipEntry(r, "xl/styles.xml", &styles); err != nil {
+		return nil, errors.Wrapf(err, "unable to read '%s'", filename)
+	}
+	dateStyles := xlsxDateStyles(styles)
+	sheet := xlsxWorksheet{}
+	if err := unmarshalZipEntry(r, sheetPath, &sheet); err != nil {
+		return nil, errors.Wrapf(err, "unable to read '%s'", filename)
+	}
+	records := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		record := []string{}
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = xlsxColumnIndex(c.Ref)
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			var value string
+			switch c.Type {
+			case "s":
+				idx, err := strconv.Atoi(c.Value)
+				if err != nil || idx < 0 || idx >= len(sharedStrings.Items) {
+					return nil, errors.Errorf("invalid shared string index '%s' in cell '%s'", c.Value, c.Ref)
+				}
+				value = sharedStrings.Items[idx].String()
+			case "inlineStr":
+				value = c.InlineStr.String()
+			case "b":
+				value = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+			case "str", "e":
+				value = c.Value
+			default:
+				value = c.Value
+				if dateStyles[c.Style] && c.Value != "" {
+					serial, err := strconv.ParseFloat(c.Value, 64)
+					if err != nil {
+						return nil, errors.Wrapf(err, "invalid date value '%s' in cell '%s'", c.Value, c.Ref)
+					}
+					value = xlsxSerialToTime(serial).Format(dateOfBirthLayout)
+				}
+			}
+			record[col] = strings.TrimSpace(value)
+		}
+		records = append(records, record)
+	}
+	return newSliceRecordSource(records), nil
+}
+
+func unmarshalZipEntry(r *zip.ReadCloser, name string, v interface{}) error {
+	data, err := readZipEntry(r, name)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		// optional entry
+		return nil
+	}
+	return errors.Wrapf(xml.Unmarshal(data, v), "unable to parse '%s'", name)
+}
+
+// xlsxFirstSheetPath returns the path of the first worksheet in the workbook
+func xlsxFirstSheetPath(r *zip.ReadCloser) (string, error) {
+	defaultPath := "xl/worksheets/sheet1.xml"
+	workbook := xlsxWorkbook{}
+	if err := unmarshalZipEntry(r, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	rels := xlsxRelationships{}
+	if err := unmarshalZipEntry(r, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return defaultPath, nil
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].ID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return defaultPath, nil
+}
+
+// xlsxDateStyles returns the indexes of the cell styles which are used to display dates
+func xlsxDateStyles(styles xlsxStyles) map[int]bool {
+	customFormats := map[int]string{}
+	for _, f := range styles.NumFmts {
+		customFormats[f.ID] = f.FormatCode
+	}
+	result := map[int]bool{}
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) {
+			result[i] = true
+			continue
+		}
+		if code, found := customFormats[id]; found {
+			code = strings.ToLower(code)
+			result[i] = strings.Contains(code, "d") && strings.Contains(code, "y")
+		}
+	}
+	return result
+}
+
+// xlsxColumnIndex returns the (zero-based) column index of the given cell reference (eg: "C12" -> 2)
+func xlsxColumnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+// xlsxSerialToTime converts the given serial date (number of days since 1899-12-30) to a time.Time
+func xlsxSerialToTime(serial float64) time.Time {
+	days := math.Floor(serial)
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(days))
+}
+
+// ------------------------------------
+// ODS (OpenDocument Spreadsheet)
+// ------------------------------------
+
+type odsContent struct {
+	Tables []struct {
+		Rows []struct {
+			Repeated int `xml:"number-rows-repeated,attr"`
+			Cells    []struct {
+				XMLName    xml.Name
+				Repeated   int            `xml:"number-columns-repeated,attr"`
+				ValueType  string         `xml:"value-type,attr"`
+				Value      string         `xml:"value,attr"`
+				DateValue  string         `xml:"date-value,attr"`
+				Paragraphs []odsParagraph `xml:"p"`
+			} `xml:",any"`
+		} `xml:"table-row"`
+	} `xml:"body>spreadsheet>table"`
+}
+
+// odsParagraph the text of a paragraph, including the text of its spans and links, and the spaces, tabs and line
+// breaks which are encoded as elements
+type odsParagraph string
+
+// UnmarshalXML implements xml.Unmarshaler
+func (p *odsParagraph) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	text := &strings.Builder{}
+	for depth := 1; depth > 0; {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			depth++
+			switch t.Name.Local {
+			case "s":
+				spaces := 1
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "c" {
+						if c, err := strconv.Atoi(attr.Value); err == nil && c > 0 {
+							spaces = c
+						}
+					}
+				}
+				text.WriteString(strings.Repeat(" ", spaces))
+			case "tab":
+				text.WriteString("\t")
+			case "line-break":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	*p = odsParagraph(text.String())
+	return nil
+}
+
+func newODSRecordSource(filename string) (RecordSource, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open '%s'", filename)
+	}
+	defer r.Close()
+	content := odsContent{}
+	if err := unmarshalZipEntry(r, "content.xml", &content); err != nil {
+		return nil, errors.Wrapf(err, "unable to read '%s'", filename)
+	}
+	if len(content.Tables) == 0 {
+		return nil, errors.Errorf("unable to read '%s': no table found", filename)
+	}
+	records := [][]string{}
+	for _, row := range content.Tables[0].Rows {
+		record := []string{}
+		// empty cells are only appended when followed by a non-empty cell, since
+		// trailing empty cells (until the end of the sheet) are stored as a single, repeated cell
+		emptyCells := 0
+		for _, c := range row.Cells {
+			if c.XMLName.Local != "table-cell" && c.XMLName.Local != "covered-table-cell" {
+				continue
+			}
+			var value string
+			switch c.ValueType {
+			case "date":
+				dateValue := c.DateValue
+				if len(dateValue) > 10 {
+					dateValue = dateValue[:10] // ignore the time part, if any
+				}
+				d, err := time.Parse("2006-01-02", dateValue)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid date value '%s'", c.DateValue)
+				}
+				value = d.Format(dateOfBirthLayout)
+			case "float", "percentage", "currency":
+				value = c.Value
+			default:
+				paragraphs := make([]string, len(c.Paragraphs))
+				for i, p := range c.Paragraphs {
+					paragraphs[i] = string(p)
+				}
+				value = strings.Join(paragraphs, "\n")
+			}
+			value = strings.TrimSpace(value)
+			repeated := c.Repeated
+			if repeated < 1 {
+				repeated = 1
+			}
+			if value == "" {
+				emptyCells += repeated
+				continue
+			}
+			for ; emptyCells > 0; emptyCells-- {
+				record = append(record, "")
+			}
+			for i := 0; i < repeated; i++ {
+				record = append(record, value)
+			}
+		}
+		if isBlank(record) {
+			// empty rows (until the end of the sheet) are stored as a single, repeated row
+			continue
+		}
+		repeated := row.Repeated
+		if repeated < 1 {
+			repeated = 1
+		}
+		for i := 0; i < repeated; i++ {
+			records = append(records, record)
+		}
+	}
+	return newSliceRecordSource(records), nil
+}
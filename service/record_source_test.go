@@ -0,0 +1,242 @@
+package service_test
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var expectedRecords = [][]string{
+	{"Course", "Dossard", "Equipe", "Challenge", "Nom", "Prénom", "Date de naissance", "Sexe", "", "", "Club"},
+	{"Bike & Run XS", "1", "Les Vétérans", "open", "Doe", "John", "12/05/1970", "H", "", "", "Vélo Club d'Asnières"},
+	{"Bike & Run XS", "1", "Les Vétérans", "open", "Doe", "Jane", "03/11/1972", "F", "", "", ""},
+}
+
+func TestCSVRecordSource(t *testing.T) {
+	// given
+	filename := writeFile(t, "registrations.csv", map[string]string{
+		"": "Course,Dossard,Equipe,Challenge,Nom,Prénom,Date de naissance,Sexe,,,Club\n" +
+			"Bike & Run XS,1,Les Vétérans,open,Doe,John,12/05/1970,H,,,Vélo Club d'Asnières\n" +
+			"Bike & Run XS,1,Les Vétérans,open,Doe,Jane,03/11/1972,F,,,\n",
+	})
+	// when
+	src, err := service.NewRecordSource(filename)
+	// then
+	require.NoError(t, err)
+	defer src.Close()
+	assert.Equal(t, expectedRecords, readAll(t, src))
+}
+
+func TestXLSXRecordSource(t *testing.T) {
+	// given
+	filename := writeFile(t, "registrations.xlsx", map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+	<sheets><sheet name="Inscriptions" sheetId="1" r:id="rId2"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+	<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+	<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/inscriptions.xml"/>
+</Relationships>`,
+		"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+	<numFmts count="1"><numFmt numFmtId="164" formatCode="dd/mm/yyyy"/></numFmts>
+	<cellXfs count="2"><xf numFmtId="0"/><xf numFmtId="164"/></cellXfs>
+</styleSheet>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+	<si><t>Course</t></si>
+	<si><t>Bike &amp; Run XS</t></si>
+	<si><r><t>Les </t></r><r><t>Vétérans</t></r></si>
+</sst>`,
+		"xl/worksheets/inscriptions.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+	<sheetData>
+		<row r="1">
+			<c r="A1" t="s"><v>0</v></c>
+			<c r="B1" t="inlineStr"><is><t>Dossard</t></is></c>
+			<c r="C1" t="inlineStr"><is><t>Equipe</t></is></c>
+			<c r="D1" t="inlineStr"><is><t>Challenge</t></is></c>
+			<c r="E1" t="inlineStr"><is><t>Nom</t></is></c>
+			<c r="F1" t="inlineStr"><is><t>Prénom</t></is></c>
+			<c r="G1" t="inlineStr"><is><t>Date de naissance</t></is></c>
+			<c r="H1" t="inlineStr"><is><t>Sexe</t></is></c>
+			<c r="K1" t="inlineStr"><is><t>Club</t></is></c>
+		</row>
+		<row r="2">
+			<c r="A2" t="s"><v>1</v></c>
+			<c r="B2"><v>1</v></c>
+			<c r="C2" t="s"><v>2</v></c>
+			<c r="D2" t="inlineStr"><is><t>open</t></is></c>
+			<c r="E2" t="inlineStr"><is><t>Doe</t></is></c>
+			<c r="F2" t="inlineStr"><is><t>John</t></is></c>
+			<c r="G2" s="1"><v>25700</v></c>
+			<c r="H2" t="inlineStr"><is><t>H</t></is></c>
+			<c r="K2" t="inlineStr"><is><t>Vélo Club d'Asnières</t></is></c>
+		</row>
+		<row r="3"/>
+		<row r="4">
+			<c r="A4" t="s"><v>1</v></c>
+			<c r="B4"><v>1</v></c>
+			<c r="C4" t="s"><v>2</v></c>
+			<c r="D4" t="inlineStr"><is><t>open</t></is></c>
+			<c r="E4" t="inlineStr"><is><t>Doe</t></is></c>
+			<c r="F4" t="inlineStr"><is><t>Jane</t></is></c>
+			<c r="G4" s="1"><v>26606</v></c>
+			<c r="H4" t="inlineStr"><is><t>F</t></is></c>
+		</row>
+	</sheetData>
+</worksheet>`,
+	})
+	// when
+	src, err := service.NewRecordSource(filename)
+	// then
+	require.NoError(t, err)
+	defer src.Close()
+	assert.Equal(t, expectedRecords, readAll(t, src))
+}
+
+func TestODSRecordSource(t *testing.T) {
+	// given
+	filename := writeFile(t, "registrations.ods", map[string]string{
+		"content.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+	<office:body>
+		<office:spreadsheet>
+			<table:table table:name="Inscriptions">
+				<table:table-row>
+					<table:table-cell office:value-type="string"><text:p>Course</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Dossard</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Equipe</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Challenge</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Nom</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Prénom</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Date de naissance</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Sexe</text:p></table:table-cell>
+					<table:table-cell table:number-columns-repeated="2"/>
+					<table:table-cell office:value-type="string"><text:p>Club</text:p></table:table-cell>
+					<table:table-cell table:number-columns-repeated="1013"/>
+				</table:table-row>
+				<table:table-row>
+					<table:table-cell office:value-type="string"><text:p>Bike &amp; Run XS</text:p></table:table-cell>
+					<table:table-cell office:value-type="float" office:value="1"><text:p>1</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Les Vétérans</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>open</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Doe</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>John</text:p></table:table-cell>
+					<table:table-cell office:value-type="date" office:date-value="1970-05-12"><text:p>12/05/70</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>H</text:p></table:table-cell>
+					<table:table-cell table:number-columns-repeated="2"/>
+					<table:table-cell office:value-type="string"><text:p>Vélo Club d'Asnières</text:p></table:table-cell>
+				</table:table-row>
+				<table:table-row>
+					<table:table-cell office:value-type="string"><text:p>Bike &amp; Run XS</text:p></table:table-cell>
+					<table:table-cell office:value-type="float" office:value="1"><text:p>1</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Les Vétérans</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>open</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Doe</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Jane</text:p></table:table-cell>
+					<table:table-cell office:value-type="date" office:date-value="1972-11-03T00:00:00"><text:p>03/11/72</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>F</text:p></table:table-cell>
+					<table:table-cell table:number-columns-repeated="1016"/>
+				</table:table-row>
+				<table:table-row table:number-rows-repeated="1048573">
+					<table:table-cell table:number-columns-repeated="1024"/>
+				</table:table-row>
+			</table:table>
+		</office:spreadsheet>
+	</office:body>
+</office:document-content>`,
+	})
+	// when
+	src, err := service.NewRecordSource(filename)
+	// then
+	require.NoError(t, err)
+	defer src.Close()
+	assert.Equal(t, expectedRecords, readAll(t, src))
+}
+
+func TestODSRecordSourceFormattedText(t *testing.T) {
+	// given a cell with spans (eg: a name in bold) and spaces encoded as elements
+	filename := writeFile(t, "registrations.ods", map[string]string{
+		"content.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+	<office:body>
+		<office:spreadsheet>
+			<table:table table:name="Inscriptions">
+				<table:table-row>
+					<table:table-cell office:value-type="string"><text:p>Equipe</text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p>Nom</text:p></table:table-cell>
+				</table:table-row>
+				<table:table-row>
+					<table:table-cell office:value-type="string"><text:p>Les<text:s/><text:span text:style-name="T1">Vétérans</text:span></text:p></table:table-cell>
+					<table:table-cell office:value-type="string"><text:p><text:span text:style-name="T1">De</text:span> La<text:s text:c="2"/>Fontaine</text:p></table:table-cell>
+				</table:table-row>
+			</table:table>
+		</office:spreadsheet>
+	</office:body>
+</office:document-content>`,
+	})
+	// when
+	src, err := service.NewRecordSource(filename)
+	// then
+	require.NoError(t, err)
+	defer src.Close()
+	assert.Equal(t, [][]string{
+		{"Equipe", "Nom"},
+		{"Les Vétérans", "De La  Fontaine"},
+	}, readAll(t, src))
+}
+
+func TestUnsupportedRecordSource(t *testing.T) {
+	// when
+	_, err := service.NewRecordSource("registrations.pdf")
+	// then
+	require.Error(t, err)
+	assert.Equal(t, "unsupported file format: '.pdf'", err.Error())
+}
+
+// writeFile writes a file with the given name in a temporary directory. If the `entries` map contains
+// a single, unnamed entry, then its content is written as-is, otherwise the entries are written in a zip archive
+func writeFile(t *testing.T, name string, entries map[string]string) string {
+	dir, err := ioutil.TempDir("", "stopwatch")
+	require.NoError(t, err)
+	filename := filepath.Join(dir, name)
+	if content, found := entries[""]; found {
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+		return filename
+	}
+	f, err := os.Create(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for entry, content := range entries {
+		ew, err := w.Create(entry)
+		require.NoError(t, err)
+		_, err = ew.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return filename
+}
+
+func readAll(t *testing.T, src service.RecordSource) [][]string {
+	result := [][]string{}
+	for {
+		record, err := src.Read()
+		if err == io.EOF {
+			return result
+		}
+		require.NoError(t, err)
+		result = append(result, record)
+	}
+}
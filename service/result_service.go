@@ -1,13 +1,9 @@
 package service
 
 import (
-	"bufio"
+	"context"
 	"database/sql"
-	"encoding/csv"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -21,98 +17,101 @@ import (
 // ResultService the interface for the application service
 type ResultService struct {
 	baseService *GormService
-	raceRepo    model.RaceRepository
-	teamRepo    model.TeamRepository
-	lapRepo     model.LapRepository
 }
 
 // NewResultService returns a new ResultService
 func NewResultService(db *gorm.DB) ResultService {
 	return ResultService{
 		baseService: NewGormService(db),
-		raceRepo:    model.NewRaceRepository(db),
-		teamRepo:    model.NewTeamRepository(db),
-		lapRepo:     model.NewLapRepository(db),
 	}
 }
 
-type teamResult struct {
-	bibNumber string
-	name      string
-	category  string
-	gender    string
-	members   string
-	club      string
-	laps      int
-	totalTime time.Duration
+// TeamResult the result of a team in a ranking
+type TeamResult struct {
+	Rank        int
+	TeamID      int
+	BibNumber   int
+	Name        string
+	AgeCategory string
+	Gender      string
+	Challenge   string
+	Category    string
+	Members     string
+	// MemberNames the first and last names of the team members
+	MemberNames []string
+	Club        string
+	// MemberClubs the clubs of the team members (empty if a member has no club)
+	MemberClubs []string
+	Laps        int
+	TotalTime   time.Duration
+	// FastestLap the duration of the fastest lap
+	FastestLap time.Duration
+	// AveragePace the average duration of a lap
+	AveragePace time.Duration
+}
+
+// Ranking the ranking of the teams of a race in a given category
+type Ranking struct {
+	Race model.Race
+	// Name the name of the ranking, used in the name of the generated files
+	Name string
+	// Title the title of the ranking, used in the generated documents
+	Title string
+	// IncludeCategory 'true' if the category of each team should be displayed
+	IncludeCategory bool
+	// IncludeLapAnalytics 'true' if the fastest lap and average pace of each team should be displayed
+	IncludeLapAnalytics bool
+	Results             []TeamResult
+	// Locale the locale of the generated documents
+	Locale string
+}
+
+// ResultOptions the options to generate the results
+type ResultOptions struct {
+	// IncludeLapAnalytics 'true' if the fastest lap and average pace of each team should be included in the results,
+	// along with the fastest lap awards per category
+	IncludeLapAnalytics bool
+	// Locale the locale of the generated documents (default: the locale set with `SetLocale`)
+	Locale string
 }
 
 const (
-	scratchQuery = `select t.bib_number, t.name, t.gender, t.age_category, t.challenge, 
+	// ScratchRankingName the name of the scratch ranking
+	ScratchRankingName = "Scratch"
+)
+
+const (
+	resultsQuery = `select t.team_id, t.bib_number, t.name, t.gender, t.age_category, t.challenge, 
 		member1_last_name, member1_first_name, member1_club, 
-		member2_last_name, member2_first_name, member1_club, 
+		member2_last_name, member2_first_name, member2_club, 
 		count(l), max(l.time)
 		from team t join lap l on l.team_id = t.team_id 
 		where t.race_id = ? 
-		group by 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11
-		order by 12 desc, 13 asc;`
-	entrepriseChallengeQuery = `select t.bib_number, t.name, t.gender, t.age_category, t.challenge, 
-		member1_last_name, member1_first_name, member1_club, 
-		member2_last_name, member2_first_name, member1_club, 
-		count(l), max(l.time)
-		from team t join lap l on l.team_id = t.team_id 
-		where t.race_id = ? and t.challenge = 'Challenge Entreprise'
-		group by 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11
-		order by 12 desc, 13 asc;`
-	byGenderAndAgeQuery = `select t.bib_number, t.name, t.gender, t.age_category, t.challenge, 
-		member1_last_name, member1_first_name, member1_club, 
-		member2_last_name, member2_first_name, member1_club, 
-		count(l), max(l.time)
-		from team t join lap l on l.team_id = t.team_id 
-		where t.race_id = ? and t.age_category = ? and t.gender = ?
-		group by 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11
-		order by 12 desc, 13 asc;`
+		group by 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12
+		order by 13 desc, 14 asc;`
 )
 
-// GenerateResults imports the data from the given file
-func (s *ResultService) GenerateResults(raceID int, outputDir string) error {
-	race, err := s.raceRepo.Lookup(raceID)
-	if err != nil {
-		return errors.Wrap(err, "unable to generate results")
-	}
-	// scratch
-	scratchRows, err := s.baseService.db.Raw(scratchQuery, race.ID).Rows()
+// GenerateResults generates the results of the given race in the given output directory, in all the given formats
+func (s *ResultService) GenerateResults(ctx context.Context, raceID int, outputDir string, formats []OutputFormat, opts ResultOptions) error {
+	rankings, err := s.Rankings(ctx, raceID, opts.Locale)
 	if err != nil {
 		return errors.Wrap(err, "unable to generate results")
 	}
-	defer scratchRows.Close()
-	err = generateAsciidoc(outputDir, race, scratchRows, "Scratch", "", true)
-	if err != nil {
-		return errors.Wrap(err, "unable to generate results")
-	}
-
-	// challenge entreprises
-	challengeRows, err := s.baseService.db.Raw(entrepriseChallengeQuery, race.ID).Rows()
-	if err != nil {
-		return errors.Wrap(err, "unable to generate results")
-	}
-	defer challengeRows.Close()
-	err = generateAsciidoc(outputDir, race, challengeRows, "Challenge Entreprise", "", true)
-	if err != nil {
-		return errors.Wrap(err, "unable to generate results")
+	if opts.IncludeLapAnalytics && len(rankings) > 0 {
+		for i := range rankings {
+			rankings[i].IncludeLapAnalytics = true
+		}
+		rankings = append(rankings, FastestLapAwards(rankings[0].Race, rankings))
 	}
-
-	// by age and gender
-	ageCategories := []string{Poussin, Pupille, Benjamin, Minime, Cadet, Junior, Senior, Veteran}
-	genders := []string{"H", "F", "M"}
-	for _, ageCategory := range ageCategories {
-		for _, gender := range genders {
-			categoryRows, err := s.baseService.db.Raw(byGenderAndAgeQuery, race.ID, ageCategory, gender).Rows()
-			defer categoryRows.Close()
-			if err != nil {
-				return errors.Wrap(err, "unable to generate results")
-			}
-			err = generateAsciidoc(outputDir, race, categoryRows, ageCategory, gender, false)
+	for _, ranking := range rankings {
+		if len(ranking.Results) == 0 {
+			logrus.WithField("race_name", ranking.Race.Name).
+				WithField("result_category", ranking.Name).
+				Warn("skipping: no result in this category for this race")
+			continue
+		}
+		for _, format := range formats {
+			err := writeRanking(outputDir, ranking, format)
 			if err != nil {
 				return errors.Wrap(err, "unable to generate results")
 			}
@@ -121,124 +120,131 @@ func (s *ResultService) GenerateResults(raceID int, outputDir string) error {
 	return nil
 }
 
-func generateCSV(outputDir string, resultType string, race model.Race, rows *sql.Rows) error {
-	results, err := readRows(race, rows)
+// GenerateEventResults generates the results of all the races of the given event in the given output directory,
+// in all the given formats
+func (s *ResultService) GenerateEventResults(ctx context.Context, eventID int, outputDir string, formats []OutputFormat, opts ResultOptions) error {
+	var races []model.Race
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		_, err := app.Events().Lookup(eventID)
+		if err != nil {
+			return err
+		}
+		races, err = app.Races().ListByEvent(eventID)
+		return err
+	})
 	if err != nil {
 		return errors.Wrap(err, "unable to generate results")
 	}
-
-	if len(results) == 0 {
-		logrus.WithField("race_name", race.Name).WithField("result_category", resultType).Warn("skipping CSV generation: no result in this category for this race")
-		return nil
+	for _, race := range races {
+		err := s.GenerateResults(ctx, race.ID, outputDir, formats, opts)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	file, err := os.Create(filepath.Join(outputDir, fmt.Sprintf("%s-%s.csv", strings.Replace(race.Name, " ", "-", -1), resultType)))
-	if err != nil {
-		return errors.Wrap(err, "unable to generate csv")
-	}
-	defer file.Close()
-	csvWriter := csv.NewWriter(file)
-	defer csvWriter.Flush()
-	// headers
-	err = csvWriter.Write([]string{
-		"Dossard",
-		"Equipe",
-		"Catégorie",
-		"Coureur 1",
-		"Coureur 2",
-		"Club",
-		"Nb Tours",
-		"Temps Total",
+// Rankings computes all the rankings of the given race: scratch, each challenge declared in the race
+// and by age and gender, with their titles in the given locale
+func (s *ResultService) Rankings(ctx context.Context, raceID int, locale string) ([]Ranking, error) {
+	var rankings []Ranking
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		rankings, err = loadRankings(app, raceID, locale)
+		return err
 	})
 	if err != nil {
-		return errors.Wrap(err, "unable to generate csv")
+		return nil, err
 	}
-
-	for _, r := range results {
-		err := csvWriter.Write([]string{
-			r.name,
-			r.bibNumber,
-			r.category,
-			r.members,
-			r.club,
-			strconv.Itoa(r.laps),
-			r.totalTime.String(),
-		})
-		if err != nil {
-			return errors.Wrap(err, "unable to generate csv")
-		}
-	}
-	return nil
+	return rankings, nil
 }
 
-func generateAsciidoc(outputDir string, race model.Race, rows *sql.Rows, cat1, cat2 string, includeAgeGender bool) error {
-	results, err := readRows(race, rows)
+// loadRankings computes all the rankings of the given race with the given repositories, so the rankings can be
+// loaded in the transaction of the caller along with other data of the race
+func loadRankings(app Repositories, raceID int, locale string) ([]Ranking, error) {
+	locale = newTranslator(locale).locale
+	race, err := app.Races().Lookup(raceID)
 	if err != nil {
-		return errors.Wrap(err, "unable to generate results")
+		return nil, err
 	}
-	var category string
-	if cat2 != "" {
-		category = fmt.Sprintf("%s-%s", cat1, cat2)
-	} else {
-		category = cat1
+	rows, err := app.DB().Raw(resultsQuery, race.ID).Rows()
+	if err != nil {
+		return nil, err
 	}
-	if len(results) == 0 {
-		logrus.WithField("race_name", race.Name).
-			WithField("result_category", category).
-			Warn("skipping: no result in this category for this race")
-		return nil
+	results, err := readRows(race, rows)
+	if err != nil {
+		return nil, err
 	}
-	file, err := os.Create(filepath.Join(outputDir, fmt.Sprintf("%s-%s.adoc", strings.Replace(race.Name, " ", "-", -1), category)))
+	challenges, err := app.Challenges().List(race.ID)
 	if err != nil {
-		return errors.Wrap(err, "unable to generate results in asciidoc")
+		return nil, err
 	}
-	defer file.Close()
-
-	logrus.WithField("race_name", race.Name).
-		WithField("result_category", category).
-		WithField("teams", len(results)).
-		Info("generating results...")
-
-	adocWriter := bufio.NewWriter(file)
-	adocWriter.WriteString(fmt.Sprintf("= Classement %s\n\n", label(cat1, cat2)))
-	adocWriter.WriteString(fmt.Sprintf("== Classement %s\n\n", label(cat1, cat2)))
-	// table header
-	adocWriter.WriteString("[cols=\"2,5,5,")
-	if includeAgeGender {
-		adocWriter.WriteString("5,")
+	laps, err := app.Laps().List(race.ID)
+	if err != nil {
+		return nil, err
 	}
-	adocWriter.WriteString("8,8,3,4\"]\n")
-	adocWriter.WriteString("|===\n")
-	adocWriter.WriteString("|# |Dossard ")
-	adocWriter.WriteString("|Equipe ")
-	if includeAgeGender {
-		adocWriter.WriteString("|Catégorie ")
+	lapsByTeam := map[int][]model.Lap{}
+	for _, l := range laps {
+		lapsByTeam[l.TeamID] = append(lapsByTeam[l.TeamID], l)
 	}
-	adocWriter.WriteString("|Coureurs |Club |Tours |Temps Total\n\n")
-
-	// table rows
 	for i, r := range results {
-		adocWriter.WriteString(fmt.Sprintf("|%d |%s |%s ",
-			i+1,
-			r.bibNumber,
-			r.name))
-		if includeAgeGender {
-			adocWriter.WriteString(fmt.Sprintf("|%s ",
-				r.category))
+		analytics := NewLapAnalytics(race, r.BibNumber, lapsByTeam[r.TeamID])
+		if analytics.FastestLap != nil {
+			results[i].FastestLap = analytics.FastestLap.Duration
 		}
-		adocWriter.WriteString(fmt.Sprintf("|%s |%s |%d |%s \n",
-			r.members,
-			r.club,
-			r.laps,
-			fmtDuration(r.totalTime)))
+		results[i].AveragePace = analytics.AveragePace
 	}
-	// close table
-	adocWriter.WriteString("|===\n")
-	err = adocWriter.Flush()
-	if err != nil {
-		return errors.Wrap(err, "unable to generate results in asciidoc")
+	rankings := []Ranking{
+		// scratch
+		newRanking(race, ScratchRankingName, ScratchRankingName, true, results, func(r TeamResult) bool {
+			return true
+		}),
 	}
-	return nil
+	// challenges
+	for _, challenge := range challenges {
+		name := challenge.Name
+		rankings = append(rankings, newRanking(race, name, name, true, results, func(r TeamResult) bool {
+			return r.Challenge == name
+		}))
+	}
+	// by age and gender, in the gender categories of the race
+	ageCategories := []string{Poussin, Pupille, Benjamin, Minime, Cadet, Junior, Senior, Veteran}
+	genders := race.Genders
+	if len(genders) == 0 {
+		genders = model.DefaultGenders
+	}
+	for _, ageCategory := range ageCategories {
+		for _, gender := range genders {
+			ageCategory, gender := ageCategory, gender
+			name := fmt.Sprintf("%s-%s", ageCategory, gender)
+			rankings = append(rankings, newRanking(race, name, CategoryLabel(locale, ageCategory, gender), false, results, func(r TeamResult) bool {
+				return r.AgeCategory == ageCategory && r.Gender == gender
+			}))
+		}
+	}
+	for i := range rankings {
+		rankings[i].Locale = locale
+	}
+	return rankings, nil
+}
+
+// newRanking returns a new ranking with the results that match the given filter.
+// The results must already be sorted.
+func newRanking(race model.Race, name, title string, includeCategory bool, results []TeamResult, filter func(TeamResult) bool) Ranking {
+	ranking := Ranking{
+		Race:            race,
+		Name:            name,
+		Title:           title,
+		IncludeCategory: includeCategory,
+		Results:         []TeamResult{},
+	}
+	for _, r := range results {
+		if filter(r) {
+			r.Rank = len(ranking.Results) + 1
+			ranking.Results = append(ranking.Results, r)
+		}
+	}
+	return ranking
 }
 
 func fmtDuration(d time.Duration) string {
@@ -251,26 +257,11 @@ func fmtDuration(d time.Duration) string {
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
 }
 
-func label(cat1, cat2 string) string {
-	// "Scratch" and "Challenge Entreprise"
-	if cat2 == "" {
-		return cat1
-	}
-	// other: age / gender
-	switch cat2 {
-	case "M":
-		return fmt.Sprintf("%ss / Mixte", cat1)
-	case "F":
-		return fmt.Sprintf("%ss / Femmes", cat1)
-	default:
-		return fmt.Sprintf("%ss / Hommes", cat1)
-	}
-}
-
-func readRows(race model.Race, rows *sql.Rows) ([]teamResult, error) {
+func readRows(race model.Race, rows *sql.Rows) ([]TeamResult, error) {
 	defer rows.Close()
-	results := []teamResult{}
+	results := []TeamResult{}
 	for rows.Next() {
+		var teamID int
 		var bibNumber int
 		var name string
 		var gender string
@@ -284,25 +275,34 @@ func readRows(race model.Race, rows *sql.Rows) ([]teamResult, error) {
 		var member2Club string
 		var laps int
 		var endTime time.Time
-		err := rows.Scan(&bibNumber, &name, &gender, &ageCategory, &challenge, &member1LastName,
-			&member1FirstName, &member1Club, &member2LastName, &member2FirstName, &member1Club,
+		err := rows.Scan(&teamID, &bibNumber, &name, &gender, &ageCategory, &challenge, &member1LastName,
+			&member1FirstName, &member1Club, &member2LastName, &member2FirstName, &member2Club,
 			&laps, &endTime)
 		if err != nil {
 			return results, errors.Wrap(err, "unable to generate results")
 		}
 
-		result := teamResult{
-			bibNumber: strconv.Itoa(bibNumber),
-			name:      name,
-			category:  getCategory(ageCategory, gender),
-			members:   getMemberNames(member1LastName, member2LastName),
-			club:      getMemberClubs(member1Club, member2Club),
-			laps:      laps,
-			totalTime: endTime.Sub(race.StartTime).Round(time.Second),
+		result := TeamResult{
+			TeamID:      teamID,
+			BibNumber:   bibNumber,
+			Name:        name,
+			AgeCategory: ageCategory,
+			Gender:      gender,
+			Challenge:   challenge,
+			Category:    getCategory(ageCategory, gender),
+			Members:     getMemberNames(member1LastName, member2LastName),
+			MemberNames: []string{
+				fmt.Sprintf("%s %s", member1FirstName, member1LastName),
+				fmt.Sprintf("%s %s", member2FirstName, member2LastName),
+			},
+			Club:        getMemberClubs(member1Club, member2Club),
+			MemberClubs: []string{member1Club, member2Club},
+			Laps:        laps,
+			TotalTime:   endTime.Sub(race.StartTime).Round(time.Second),
 		}
-		logrus.WithField("name", result.name).
-			WithField("laps", result.laps).
-			WithField("total_time", result.totalTime).
+		logrus.WithField("name", result.Name).
+			WithField("laps", result.Laps).
+			WithField("total_time", result.TotalTime).
 			Debug("adding team to result")
 		results = append(results, result)
 	}
@@ -323,74 +323,3 @@ func getMemberClubs(member1Club, member2Club string) string {
 	}
 	return strings.TrimSpace(fmt.Sprintf("%s %s", member1Club, member2Club))
 }
-
-// const (
-// 	// FontName the name of the font to use
-// 	fontName   string = "SourceCodePro-Regular"
-// 	leftMargin string = "  "
-// )
-
-// func generatePDF(outputFilename string, race model.Race, results []teamResult) error {
-// 	pdf := gopdf.GoPdf{}
-// 	pdf.Start(gopdf.Config{PageSize: gopdf.PageSizeA4})
-// 	fontLocation := fmt.Sprintf("../ttf/%s.ttf", fontName)
-// 	var parser core.TTFParser
-// 	err := parser.Parse(fontLocation)
-// 	if err != nil {
-// 		return errors.Wrap(err, "unable to generate PDF")
-// 	}
-// 	err = pdf.AddTTFFont(fontName, fontLocation)
-// 	if err != nil {
-// 		return errors.Wrap(err, "unable to generate PDF")
-// 	}
-
-// 	pdf.AddPage()
-// 	// title
-// 	fontSize := 20
-// 	err = pdf.SetFont(fontName, "", fontSize)
-// 	if err != nil {
-// 		return errors.Wrap(err, "unable to generate PDF")
-// 	}
-// 	pdf.Br(getHeight(&parser, fontSize) * 2)
-// 	pdf.Cell(nil, race.Name)
-// 	pdf.Br(getHeight(&parser, fontSize) * 2)
-// 	// teams in order
-// 	for i, teamResult := range results {
-// 		if (i+1)%25 == 0 {
-// 			pdf.AddPage()
-// 			pdf.Br(getHeight(&parser, fontSize) * 2)
-// 		}
-// 		// userName := strings.ToTitle(userData[0])
-// 		// pdf.Cell(nil, fmt.Sprintf("%s%s", leftMargin, userName))
-// 		// pdf.Br(getHeight(&parser, fontSize) * 2)
-// 		// items
-// 		fontSize = 10
-// 		err = pdf.SetFont(fontName, "", fontSize)
-// 		if err != nil {
-// 			return errors.Wrap(err, "unable to generate PDF")
-// 		}
-// 		err = pdf.Cell(nil, teamResult.name)
-// 		if err != nil {
-// 			return errors.Wrap(err, "unable to generate PDF")
-// 		}
-// 		pdf.Br(getHeight(&parser, fontSize) * 2)
-
-// 	}
-
-// 	// write output
-// 	err = pdf.WritePdf(outputFilename)
-// 	if err != nil {
-// 		return errors.Wrap(err, "unable to generate PDF")
-// 	}
-// 	return nil
-// }
-
-// func getHeight(parser *core.TTFParser, fontSize int) float64 {
-// 	//Measure Height
-// 	//get  CapHeight (https://en.wikipedia.org/wiki/Cap_height)
-// 	cap := float64(float64(parser.CapHeight()) * 1000.00 / float64(parser.UnitsPerEm()))
-// 	//convert
-// 	realHeight := cap * (float64(fontSize) / 1000.0)
-// 	// fmt.Printf("realHeight = %f", realHeight)
-// 	return realHeight * 2
-// }
@@ -1,7 +1,13 @@
 package service_test
 
 import (
+	"context"
+	"encoding/csv"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +16,7 @@ import (
 	"github.com/vatriathlon/stopwatch/model"
 
 	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/vatriathlon/stopwatch/configuration"
@@ -30,16 +37,20 @@ type ResultServiceTestSuite struct {
 func (s *ResultServiceTestSuite) TestListRacesNoResult() {
 	// given a dataset
 	raceRepo := model.NewRaceRepository(s.DB)
+	event := testmodel.CreateEvent(s.T(), s.DB)
 	teamRepo := model.NewTeamRepository(s.DB)
 	lapRepo := model.NewLapRepository(s.DB)
 	race := model.Race{
-		Name: fmt.Sprintf("race %s", uuid.NewV4()),
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
 	}
 	err := raceRepo.Create(&race)
 	require.NoError(s.T(), err)
 	race.StartTime = time.Now().Add(-60 * time.Minute)
 	err = raceRepo.Save(&race)
 	require.NoError(s.T(), err)
+	err = model.NewChallengeRepository(s.DB).Create(&model.Challenge{RaceID: race.ID, Name: "Challenge Entreprise"})
+	require.NoError(s.T(), err)
 
 	// 10 teams, 4 laps each
 	for i := 1; i < 120; i++ {
@@ -90,10 +101,101 @@ func (s *ResultServiceTestSuite) TestListRacesNoResult() {
 		}
 	}
 
+	svc := service.NewResultService(s.DB)
+
+	s.T().Run("asciidoc", func(t *testing.T) {
+		// when
+		err := svc.GenerateResults(context.Background(), race.ID, "../tmp/results", []service.OutputFormat{service.AsciidocFormat}, service.ResultOptions{})
+		// then
+		require.NoError(t, err)
+	})
+
+	s.T().Run("all formats", func(t *testing.T) {
+		// given
+		outputDir, err := ioutil.TempDir("", "stopwatch")
+		require.NoError(t, err)
+		defer os.RemoveAll(outputDir)
+		// when
+		err = svc.GenerateResults(context.Background(), race.ID, outputDir, []service.OutputFormat{
+			service.AsciidocFormat,
+			service.CSVFormat,
+			service.JSONFormat,
+			service.HTMLFormat,
+			service.PDFFormat,
+		}, service.ResultOptions{})
+		// then
+		require.NoError(t, err)
+		prefix := filepath.Join(outputDir, strings.Replace(race.Name, " ", "-", -1))
+		for _, ext := range []string{"adoc", "csv", "json", "html", "pdf"} {
+			assert.FileExists(t, fmt.Sprintf("%s-Scratch.%s", prefix, ext))
+			// one ranking per declared challenge
+			assert.FileExists(t, fmt.Sprintf("%s-Challenge Entreprise.%s", prefix, ext))
+		}
+		// verify the columns of the CSV file
+		f, err := os.Open(fmt.Sprintf("%s-Scratch.csv", prefix))
+		require.NoError(t, err)
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		require.NoError(t, err)
+		require.True(t, len(records) > 1)
+		assert.Equal(t, []string{"Classement", "Dossard", "Equipe", "Catégorie", "Coureurs", "Club", "Tours", "Temps Total"}, records[0])
+		// team 1 completed 4 laps, faster than all other teams
+		assert.Equal(t, []string{"1", "1", "team 1", "S/M", "doe - doe", "", "4", "01:01:00"}, records[1])
+	})
+	s.T().Run("podiums", func(t *testing.T) {
+		// given
+		outputDir, err := ioutil.TempDir("", "stopwatch")
+		require.NoError(t, err)
+		defer os.RemoveAll(outputDir)
+		// when
+		err = svc.GeneratePodiums(context.Background(), race.ID, outputDir, []service.OutputFormat{service.AsciidocFormat, service.PDFFormat}, service.PodiumOptions{
+			ExcludeScratchWinners: true,
+		})
+		// then
+		require.NoError(t, err)
+		prefix := filepath.Join(outputDir, strings.Replace(race.Name, " ", "-", -1))
+		assert.FileExists(t, fmt.Sprintf("%s-Podiums.adoc", prefix))
+		assert.FileExists(t, fmt.Sprintf("%s-Podiums.pdf", prefix))
+	})
+}
+
+func (s *ResultServiceTestSuite) TestRankingsDaylightSavingTime() {
+	// given a race which started before the end of the daylight saving time in Paris (at 03:00 CEST, which became
+	// 02:00 CET) and a team which completed its last lap after it
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(s.T(), err)
+	model.SetTimeZone(paris)
+	defer model.SetTimeZone(time.Local)
+	event := testmodel.CreateEvent(s.T(), s.DB)
+	race := model.Race{
+		EventID: event.ID,
+		Name:    fmt.Sprintf("race %s", uuid.NewV4()),
+	}
+	err = model.NewRaceRepository(s.DB).Create(&race)
+	require.NoError(s.T(), err)
+	race.StartTime = time.Date(2019, 10, 27, 1, 30, 0, 0, paris)
+	err = model.NewRaceRepository(s.DB).Save(&race)
+	require.NoError(s.T(), err)
+	team := testmodel.NewTeam(race.ID, 1)
+	err = model.NewTeamRepository(s.DB).Create(&team)
+	require.NoError(s.T(), err)
+	for _, lapTime := range []time.Time{
+		race.StartTime.Add(time.Hour),               // 02:30 CEST
+		race.StartTime.Add(2 * time.Hour),           // 02:30 CET
+		time.Date(2019, 10, 27, 3, 30, 0, 0, paris), // 03:30 CET
+	} {
+		err = model.NewLapRepository(s.DB).Create(&model.Lap{RaceID: race.ID, TeamID: team.ID, Time: lapTime})
+		require.NoError(s.T(), err)
+	}
 	svc := service.NewResultService(s.DB)
 	// when
-	err = svc.GenerateResults(race.ID, "../tmp/results")
-	// then
+	rankings, err := svc.Rankings(context.Background(), race.ID, "")
+	// then the total time is the elapsed time, not the difference of the wall clocks
 	require.NoError(s.T(), err)
-
+	require.NotEmpty(s.T(), rankings)
+	require.Len(s.T(), rankings[0].Results, 1)
+	assert.Equal(s.T(), 3, rankings[0].Results[0].Laps)
+	assert.Equal(s.T(), 3*time.Hour, rankings[0].Results[0].TotalTime)
+	// and the start time is read in the time zone of the event
+	assert.Equal(s.T(), "2019-10-27 01:30:00 CEST", rankings[0].Race.StartTimeStr())
 }
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// writeRanking writes the given ranking in a file in the given output directory, using the given format
+func writeRanking(outputDir string, ranking Ranking, format OutputFormat) error {
+	filename := outputFilename(outputDir, ranking.Race.Name, ranking.Name, format)
+	logrus.WithField("race_name", ranking.Race.Name).
+		WithField("result_category", ranking.Name).
+		WithField("teams", len(ranking.Results)).
+		WithField("output_file", filename).
+		Info("generating results...")
+	err := writeTable(filename, rankingTable(ranking), format)
+	return errors.Wrapf(err, "unable to generate results in %s", format)
+}
+
+// rankingTable returns the table of the given ranking
+func rankingTable(ranking Ranking) table {
+	msg := newTranslator(ranking.Locale)
+	// the category is always written in the CSV documents
+	category := categoryInCSVOnly
+	if ranking.IncludeCategory {
+		category = showCategory
+	}
+	rows := make([][]string, len(ranking.Results))
+	for i, r := range ranking.Results {
+		rows[i] = resultRow(r, category, ranking.IncludeLapAnalytics)
+	}
+	return table{
+		title:    ranking.Race.Name,
+		subtitle: msg.Tf("Classement %s", ranking.Title),
+		columns:  resultColumns(msg, category, ranking.IncludeLapAnalytics),
+		sections: []tableSection{{rows: rows}},
+		data:     newJSONRanking(ranking),
+		locale:   msg.locale,
+	}
+}
+
+// categoryDisplay how the category of the teams is displayed in a table of results
+type categoryDisplay int
+
+const (
+	// hideCategory the category is not displayed
+	hideCategory categoryDisplay = iota
+	// categoryInCSVOnly the category is only displayed in the CSV documents
+	categoryInCSVOnly
+	// showCategory the category is displayed in all documents
+	showCategory
+)
+
+// resultColumns returns the columns of a table of results, in the language of the given translator
+func resultColumns(msg translator, category categoryDisplay, includeLapAnalytics bool) []tableColumn {
+	columns := []tableColumn{
+		{title: "#", csvTitle: msg.T("Classement"), width: 2, align: "R"},
+		{title: msg.T("Dossard"), width: 3, align: "R"},
+		{title: msg.T("Equipe"), width: 9, align: "L"},
+	}
+	if category != hideCategory {
+		columns = append(columns, tableColumn{title: msg.T("Catégorie"), width: 3, align: "C", csvOnly: category == categoryInCSVOnly})
+	}
+	columns = append(columns,
+		tableColumn{title: msg.T("Coureurs"), width: 10, align: "L"},
+		tableColumn{title: msg.T("Club"), width: 10, align: "L"},
+		tableColumn{title: msg.T("Tours"), width: 2, align: "R"},
+		tableColumn{title: msg.T("Temps Total"), width: 4, align: "R"},
+	)
+	if includeLapAnalytics {
+		columns = append(columns,
+			tableColumn{title: msg.T("Meilleur Tour"), width: 4, align: "R"},
+			tableColumn{title: msg.T("Moyenne"), width: 4, align: "R"},
+		)
+	}
+	return columns
+}
+
+// resultRow returns the cells of the given result, in the columns returned by `resultColumns`
+func resultRow(r TeamResult, category categoryDisplay, includeLapAnalytics bool) []string {
+	row := []string{strconv.Itoa(r.Rank), strconv.Itoa(r.BibNumber), r.Name}
+	if category != hideCategory {
+		row = append(row, r.Category)
+	}
+	row = append(row, r.Members, r.Club, strconv.Itoa(r.Laps), fmtDuration(r.TotalTime))
+	if includeLapAnalytics {
+		row = append(row, fmtDuration(r.FastestLap), fmtDuration(r.AveragePace))
+	}
+	return row
+}
+
+type jsonRanking struct {
+	Race     string       `json:"race"`
+	Category string       `json:"category"`
+	Results  []jsonResult `json:"results"`
+}
+
+type jsonResult struct {
+	Rank        int    `json:"rank"`
+	BibNumber   int    `json:"bibNumber"`
+	Team        string `json:"team"`
+	Category    string `json:"category"`
+	Members     string `json:"members"`
+	Club        string `json:"club"`
+	Laps        int    `json:"laps"`
+	TotalTime   string `json:"totalTime"`
+	FastestLap  string `json:"fastestLap,omitempty"`
+	AveragePace string `json:"averagePace,omitempty"`
+}
+
+func newJSONRanking(ranking Ranking) jsonRanking {
+	result := jsonRanking{
+		Race:     ranking.Race.Name,
+		Category: ranking.Title,
+		Results:  make([]jsonResult, len(ranking.Results)),
+	}
+	for i, r := range ranking.Results {
+		result.Results[i] = jsonResult{
+			Rank:      r.Rank,
+			BibNumber: r.BibNumber,
+			Team:      r.Name,
+			Category:  r.Category,
+			Members:   r.Members,
+			Club:      r.Club,
+			Laps:      r.Laps,
+			TotalTime: fmtDuration(r.TotalTime),
+		}
+		if ranking.IncludeLapAnalytics {
+			result.Results[i].FastestLap = fmtDuration(r.FastestLap)
+			result.Results[i].AveragePace = fmtDuration(r.AveragePace)
+		}
+	}
+	return result
+}
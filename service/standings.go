@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/vatriathlon/stopwatch/model"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// StandingsRace a race of a championship, along with its event
+type StandingsRace struct {
+	Race  model.Race
+	Event model.Event
+}
+
+// Standings the standings of the teams in a championship
+type Standings struct {
+	Championship model.Championship
+	// Races the races of the championship, in chronological order
+	Races   []StandingsRace
+	Results []TeamStanding
+	// Locale the locale of the generated documents
+	Locale string
+}
+
+// TeamStanding the standing of a team in a championship
+type TeamStanding struct {
+	Rank    int
+	Name    string
+	Members string
+	Club    string
+	// Races the results of the team in each race of the championship, in the same order as the races of
+	// the standings (nil if the team did not take part in the race)
+	Races []*RaceStanding
+	// Total the sum of the points of the counted results
+	Total int
+}
+
+// RaceStanding the result of a team in a race of a championship
+type RaceStanding struct {
+	// Rank the scratch rank of the team in the race
+	Rank   int
+	Points int
+	// Counted 'true' if the points count in the total of the team (see `model.Championship.BestOf`)
+	Counted bool
+}
+
+// Standings computes the standings of the given championship, from the scratch rankings of its races
+func (s *ResultService) Standings(ctx context.Context, championshipID int) (Standings, error) {
+	var championship model.Championship
+	races := []StandingsRace{}
+	err := Transactional(ctx, s.baseService, func(app Repositories) error {
+		var err error
+		championship, err = app.Championships().Lookup(championshipID)
+		if err != nil {
+			return err
+		}
+		rs, err := app.Championships().ListRaces(championship)
+		if err != nil {
+			return err
+		}
+		races = races[:0]
+		for _, race := range rs {
+			event, err := app.Events().Lookup(race.EventID)
+			if err != nil {
+				return err
+			}
+			races = append(races, StandingsRace{Race: race, Event: event})
+		}
+		return nil
+	})
+	if err != nil {
+		return Standings{}, errors.Wrap(err, "unable to compute standings")
+	}
+	results := make([][]TeamResult, len(races))
+	for i, r := range races {
+		rankings, err := s.Rankings(ctx, r.Race.ID, "")
+		if err != nil {
+			return Standings{}, errors.Wrap(err, "unable to compute standings")
+		}
+		for _, ranking := range rankings {
+			if ranking.Name == ScratchRankingName {
+				results[i] = ranking.Results
+			}
+		}
+	}
+	return NewStandings(championship, races, results), nil
+}
+
+// GenerateStandings generates the standings of the given championship in the given output directory,
+// in all the given formats and in the given locale (or in the locale set with `SetLocale` if empty)
+func (s *ResultService) GenerateStandings(ctx context.Context, championshipID int, outputDir string, formats []OutputFormat, locale string) error {
+	standings, err := s.Standings(ctx, championshipID)
+	if err != nil {
+		return errors.Wrap(err, "unable to generate standings")
+	}
+	standings.Locale = locale
+	if len(standings.Results) == 0 {
+		logrus.WithField("championship_name", standings.Championship.Name).
+			Warn("skipping: no result in this championship")
+		return nil
+	}
+	return WriteStandings(outputDir, standings, formats)
+}
+
+// WriteStandings writes the given standings in the given output directory, in all the given formats
+func WriteStandings(outputDir string, standings Standings, formats []OutputFormat) error {
+	for _, format := range formats {
+		err := writeStandings(outputDir, standings, format)
+		if err != nil {
+			return errors.Wrap(err, "unable to generate standings")
+		}
+	}
+	return nil
+}
+
+// NewStandings returns the standings of the given championship, given the scratch results of each race (in the
+// same order as the races). The results of the same team are matched across the races according to the team
+// matching of the championship. The teams are ranked by their total points, then by their best results.
+func NewStandings(championship model.Championship, races []StandingsRace, results [][]TeamResult) Standings {
+	standings := Standings{
+		Championship: championship,
+		Races:        races,
+		Results:      []TeamStanding{},
+	}
+	teams := map[string]int{}
+	for i, raceResults := range results {
+		for _, r := range raceResults {
+			key := teamKey(championship.TeamMatching, r)
+			t, found := teams[key]
+			if !found {
+				t = len(standings.Results)
+				teams[key] = t
+				standings.Results = append(standings.Results, TeamStanding{
+					Races: make([]*RaceStanding, len(races)),
+				})
+			}
+			if standings.Results[t].Races[i] != nil {
+				// another team with the same identity in the same race, which cannot be told apart
+				logrus.WithField("team_name", r.Name).
+					WithField("race_name", races[i].Race.Name).
+					Warn("skipping: another team with the same identity was ranked before in this race")
+				continue
+			}
+			// the name, members and club of the most recent race are displayed
+			standings.Results[t].Name = r.Name
+			standings.Results[t].Members = r.Members
+			standings.Results[t].Club = r.Club
+			standings.Results[t].Races[i] = &RaceStanding{
+				Rank:   r.Rank,
+				Points: championship.PointsForRank(r.Rank),
+			}
+		}
+	}
+	for i := range standings.Results {
+		countPoints(&standings.Results[i], championship.BestOf)
+	}
+	sort.SliceStable(standings.Results, func(i, j int) bool {
+		if c := compareStandings(standings.Results[i], standings.Results[j]); c != 0 {
+			return c > 0
+		}
+		return standings.Results[i].Name < standings.Results[j].Name
+	})
+	for i := range standings.Results {
+		if i > 0 && compareStandings(standings.Results[i], standings.Results[i-1]) == 0 {
+			standings.Results[i].Rank = standings.Results[i-1].Rank
+		} else {
+			standings.Results[i].Rank = i + 1
+		}
+	}
+	return standings
+}
+
+// countPoints marks the `bestOf` results with the most points as counted (or all of them if `bestOf` is zero),
+// and computes the total of the team
+func countPoints(t *TeamStanding, bestOf int) {
+	results := []*RaceStanding{}
+	for _, r := range t.Races {
+		if r != nil {
+			results = append(results, r)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Points > results[j].Points
+	})
+	t.Total = 0
+	for i, r := range results {
+		r.Counted = bestOf == 0 || i < bestOf
+		if r.Counted {
+			t.Total += r.Points
+		}
+	}
+}
+
+// compareStandings compares the given teams by total points, then by their counted points, from the best to the
+// worst ones. Returns a positive value if `t1` is ahead of `t2`, a negative value if `t2` is ahead of `t1`, and
+// zero if they are tied.
+func compareStandings(t1, t2 TeamStanding) int {
+	if t1.Total != t2.Total {
+		return t1.Total - t2.Total
+	}
+	p1, p2 := countedPoints(t1), countedPoints(t2)
+	for i := 0; i < len(p1) && i < len(p2); i++ {
+		if p1[i] != p2[i] {
+			return p1[i] - p2[i]
+		}
+	}
+	return 0
+}
+
+// countedPoints returns the counted points of the given team, from the highest to the lowest
+func countedPoints(t TeamStanding) []int {
+	result := []int{}
+	for _, r := range t.Races {
+		if r != nil && r.Counted {
+			result = append(result, r.Points)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(result)))
+	return result
+}
+
+// teamKey returns the identity of the team of the given result, according to the given team matching
+func teamKey(matching model.TeamMatching, r TeamResult) string {
+	if matching == model.TeamMatchingByMembers && len(r.MemberNames) > 0 {
+		members := make([]string, len(r.MemberNames))
+		for i, m := range r.MemberNames {
+			members[i] = normalizeName(m)
+		}
+		// the members may be registered in any order
+		sort.Strings(members)
+		return strings.Join(members, "|")
+	}
+	return normalizeName(r.Name)
+}
+
+// normalizeName normalizes the given name, so that the differences of case, accents and spacing
+// are ignored (eg: "Les  Écureuils" and "les ecureuils")
+func normalizeName(name string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(t, name)
+	if err != nil {
+		result = name
+	}
+	return strings.Join(strings.Fields(strings.ToLower(result)), " ")
+}
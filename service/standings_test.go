@@ -0,0 +1,230 @@
+package service_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/model"
+	"github.com/vatriathlon/stopwatch/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStandings(t *testing.T) {
+
+	races := []service.StandingsRace{}
+	for i, d := range []int{3, 10, 17} {
+		races = append(races, service.StandingsRace{
+			Race:  model.Race{ID: i + 1, Name: "Bike & Run XS"},
+			Event: model.Event{ID: i + 1, Name: "Bike & Run", Date: time.Date(2019, 11, d, 0, 0, 0, 0, time.UTC)},
+		})
+	}
+	newResults := func(names ...string) []service.TeamResult {
+		results := []service.TeamResult{}
+		for i, n := range names {
+			results = append(results, service.TeamResult{Rank: i + 1, Name: n})
+		}
+		return results
+	}
+	standing := func(s service.Standings, name string) service.TeamStanding {
+		for _, r := range s.Results {
+			if r.Name == name {
+				return r
+			}
+		}
+		require.FailNow(t, "team not found", name)
+		return service.TeamStanding{}
+	}
+
+	t.Run("all results count", func(t *testing.T) {
+		// given
+		championship := model.Championship{Name: "Winter Series", Points: []int64{10, 8, 6}}
+		results := [][]service.TeamResult{
+			newResults("Foo", "Bar", "Baz", "Qux"),
+			newResults("Bar", "Foo"),
+			newResults("Bar", "Baz", "Foo"),
+		}
+		// when
+		standings := service.NewStandings(championship, races, results)
+		// then
+		require.Len(t, standings.Results, 4)
+		assert.Equal(t, "Bar", standings.Results[0].Name)
+		assert.Equal(t, 1, standings.Results[0].Rank)
+		assert.Equal(t, 28, standings.Results[0].Total)
+		assert.Equal(t, "Foo", standings.Results[1].Name)
+		assert.Equal(t, 24, standings.Results[1].Total)
+		assert.Equal(t, "Baz", standings.Results[2].Name)
+		assert.Equal(t, 14, standings.Results[2].Total)
+		// ranked after the last entry of the points table
+		qux := standings.Results[3]
+		assert.Equal(t, "Qux", qux.Name)
+		assert.Equal(t, 0, qux.Total)
+		require.NotNil(t, qux.Races[0])
+		assert.Equal(t, 4, qux.Races[0].Rank)
+		assert.Nil(t, qux.Races[1])
+		assert.Nil(t, qux.Races[2])
+	})
+
+	t.Run("best 2 of 3", func(t *testing.T) {
+		// given
+		championship := model.Championship{Name: "Winter Series", Points: []int64{10, 8, 6}, BestOf: 2}
+		results := [][]service.TeamResult{
+			newResults("Foo", "Bar", "Baz"),
+			newResults("Baz", "Foo", "Bar"),
+			newResults("Foo", "Baz"),
+		}
+		// when
+		standings := service.NewStandings(championship, races, results)
+		// then
+		foo := standing(standings, "Foo")
+		assert.Equal(t, 1, foo.Rank)
+		assert.Equal(t, 20, foo.Total)
+		assert.True(t, foo.Races[0].Counted)
+		assert.False(t, foo.Races[1].Counted)
+		assert.True(t, foo.Races[2].Counted)
+		baz := standing(standings, "Baz")
+		assert.Equal(t, 2, baz.Rank)
+		assert.Equal(t, 18, baz.Total)
+		bar := standing(standings, "Bar")
+		assert.Equal(t, 3, bar.Rank)
+		assert.Equal(t, 14, bar.Total)
+	})
+
+	t.Run("ties", func(t *testing.T) {
+		// given
+		championship := model.Championship{Name: "Winter Series", Points: []int64{10, 8, 6, 4}}
+		results := [][]service.TeamResult{
+			newResults("Foo", "Bar", "Baz", "Qux"),
+			newResults("Qux", "Baz", "Bar", "Foo"),
+		}
+		// when
+		standings := service.NewStandings(championship, races[:2], results)
+		// then all teams have the same total, but Foo and Qux are ahead on their best result...
+		for _, r := range standings.Results {
+			assert.Equal(t, 14, r.Total)
+		}
+		assert.Equal(t, 1, standing(standings, "Foo").Rank)
+		assert.Equal(t, 1, standing(standings, "Qux").Rank)
+		// ... while Bar and Baz are tied behind them
+		assert.Equal(t, 3, standing(standings, "Bar").Rank)
+		assert.Equal(t, 3, standing(standings, "Baz").Rank)
+	})
+
+	t.Run("match teams by name", func(t *testing.T) {
+		// given
+		championship := model.Championship{Name: "Winter Series", Points: []int64{10, 8}, TeamMatching: model.TeamMatchingByName}
+		results := [][]service.TeamResult{
+			newResults("Les  Écureuils", "Foo"),
+			newResults("les ecureuils", "Foo"),
+		}
+		// when
+		standings := service.NewStandings(championship, races[:2], results)
+		// then
+		require.Len(t, standings.Results, 2)
+		assert.Equal(t, 20, standings.Results[0].Total)
+		// the most recent name is displayed
+		assert.Equal(t, "les ecureuils", standings.Results[0].Name)
+	})
+
+	t.Run("match teams by members", func(t *testing.T) {
+		// given
+		championship := model.Championship{Name: "Winter Series", Points: []int64{10, 8}, TeamMatching: model.TeamMatchingByMembers}
+		results := [][]service.TeamResult{
+			{
+				{Rank: 1, Name: "Foo", MemberNames: []string{"Jean Dupont", "Marie Durand"}},
+				{Rank: 2, Name: "Bar", MemberNames: []string{"Paul Martin", "Anne Petit"}},
+			},
+			{
+				{Rank: 1, Name: "Bar", MemberNames: []string{"Luc Bernard", "Anne Petit"}},
+				{Rank: 2, Name: "Foo Team", MemberNames: []string{"marie durand", "Jean DUPONT"}},
+			},
+		}
+		// when
+		standings := service.NewStandings(championship, races[:2], results)
+		// then
+		require.Len(t, standings.Results, 3)
+		assert.Equal(t, "Foo Team", standings.Results[0].Name)
+		assert.Equal(t, 18, standings.Results[0].Total)
+		assert.Equal(t, 10, standing(standings, "Bar").Total)
+	})
+}
+
+func TestWriteStandings(t *testing.T) {
+	// given
+	standings := service.NewStandings(
+		model.Championship{Name: "Winter Series", Points: []int64{10, 8}, BestOf: 1},
+		[]service.StandingsRace{
+			{Race: model.Race{Name: "XS"}, Event: model.Event{Name: "Bike & Run", Date: time.Date(2019, 11, 3, 0, 0, 0, 0, time.UTC)}},
+			{Race: model.Race{Name: "XS"}, Event: model.Event{Name: "Bike & Run", Date: time.Date(2019, 11, 17, 0, 0, 0, 0, time.UTC)}},
+		},
+		[][]service.TeamResult{
+			{{Rank: 1, Name: "Foo", Members: "Dupont - Durand", Club: "VA | Vatry"}, {Rank: 2, Name: "Bar"}},
+			{{Rank: 1, Name: "Bar"}},
+		},
+	)
+	outputDir, err := ioutil.TempDir("", "standings")
+	require.NoError(t, err)
+	defer os.RemoveAll(outputDir)
+	// when
+	err = service.WriteStandings(outputDir, standings, []service.OutputFormat{service.AsciidocFormat, service.CSVFormat, service.JSONFormat, service.HTMLFormat, service.PDFFormat})
+	// then
+	require.NoError(t, err)
+	for _, ext := range []string{"adoc", "json", "html", "pdf"} {
+		_, err := os.Stat(filepath.Join(outputDir, "Winter-Series-Classement."+ext))
+		assert.NoError(t, err, ext)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(outputDir, "Winter-Series-Classement.csv"))
+	require.NoError(t, err)
+	lines := strings.Split(string(bytes.TrimSpace(content)), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "Classement,Equipe,Coureurs,Club,XS 03/11/2019,XS 17/11/2019,Total", lines[0])
+	assert.Equal(t, "1,Bar,,,(8),10,10", lines[1])
+	assert.Equal(t, "1,Foo,Dupont - Durand,VA | Vatry,10,-,10", lines[2])
+	// the cell separators are escaped in the AsciiDoc document
+	content, err = ioutil.ReadFile(filepath.Join(outputDir, "Winter-Series-Classement.adoc"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `|Foo |Dupont - Durand |VA \| Vatry |10 |- |10`)
+
+	t.Run("in other languages", func(t *testing.T) {
+
+		testcases := map[string]struct {
+			headers  string
+			subtitle string
+		}{
+			"en": {
+				headers:  "Rank,Team,Athletes,Club,XS 2019-11-03,XS 2019-11-17,Total",
+				subtitle: "== Overall Standings",
+			},
+			"de": {
+				headers:  "Platz,Team,Teilnehmer,Verein,XS 03.11.2019,XS 17.11.2019,Gesamt",
+				subtitle: "== Gesamtwertung",
+			},
+		}
+		for locale, testdata := range testcases {
+			t.Run(locale, func(t *testing.T) {
+				// given
+				standings.Locale = locale
+				// when
+				err := service.WriteStandings(outputDir, standings, []service.OutputFormat{service.AsciidocFormat, service.CSVFormat, service.HTMLFormat})
+				// then
+				require.NoError(t, err)
+				content, err := ioutil.ReadFile(filepath.Join(outputDir, "Winter-Series-Classement.csv"))
+				require.NoError(t, err)
+				assert.Equal(t, testdata.headers, strings.Split(string(content), "\n")[0])
+				content, err = ioutil.ReadFile(filepath.Join(outputDir, "Winter-Series-Classement.adoc"))
+				require.NoError(t, err)
+				assert.Contains(t, string(content), testdata.subtitle)
+				content, err = ioutil.ReadFile(filepath.Join(outputDir, "Winter-Series-Classement.html"))
+				require.NoError(t, err)
+				assert.Contains(t, string(content), fmt.Sprintf(`<html lang="%s">`, locale))
+			})
+		}
+	})
+}
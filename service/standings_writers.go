@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// standingsDocumentName the name of the standings in the generated files
+const standingsDocumentName = "Classement"
+
+// writeStandings writes the given standings in a file in the given output directory, using the given format
+func writeStandings(outputDir string, standings Standings, format OutputFormat) error {
+	filename := outputFilename(outputDir, standings.Championship.Name, standingsDocumentName, format)
+	logrus.WithField("championship_name", standings.Championship.Name).
+		WithField("teams", len(standings.Results)).
+		WithField("output_file", filename).
+		Info("generating standings...")
+	err := writeTable(filename, standingsTable(standings), format)
+	return errors.Wrapf(err, "unable to generate standings in %s", format)
+}
+
+// title the title of the column of the race in the standings, with the date in the format of the given locale
+func (r StandingsRace) title(msg translator) string {
+	return fmt.Sprintf("%s %s", r.Race.Name, r.Event.Date.Format(msg.T("02/01/2006")))
+}
+
+// fmtRaceStanding formats the points earned by a team in a race: the points which do not count in
+// the total are displayed in parentheses, and a dash is displayed if the team did not take part in the race
+func fmtRaceStanding(r *RaceStanding) string {
+	switch {
+	case r == nil:
+		return "-"
+	case r.Counted:
+		return strconv.Itoa(r.Points)
+	default:
+		return fmt.Sprintf("(%d)", r.Points)
+	}
+}
+
+// standingsTable returns the table of the standings
+func standingsTable(standings Standings) table {
+	msg := newTranslator(standings.Locale)
+	t := table{
+		title:    standings.Championship.Name,
+		subtitle: msg.T("Classement général"),
+		columns: []tableColumn{
+			{title: "#", csvTitle: msg.T("Classement"), width: 2, align: "R"},
+			{title: msg.T("Equipe"), width: 9, align: "L"},
+			{title: msg.T("Coureurs"), width: 9, align: "L"},
+			{title: msg.T("Club"), width: 9, align: "L"},
+		},
+		data:   newJSONStandings(standings),
+		locale: msg.locale,
+	}
+	rows := make([][]string, len(standings.Results))
+	for _, r := range standings.Races {
+		t.columns = append(t.columns, tableColumn{title: r.title(msg), width: 4, align: "R"})
+	}
+	t.columns = append(t.columns, tableColumn{title: msg.T("Total"), width: 3, align: "R"})
+	for i, ts := range standings.Results {
+		row := []string{strconv.Itoa(ts.Rank), ts.Name, ts.Members, ts.Club}
+		for _, r := range ts.Races {
+			row = append(row, fmtRaceStanding(r))
+		}
+		rows[i] = append(row, strconv.Itoa(ts.Total))
+	}
+	t.sections = []tableSection{{rows: rows}}
+	return t
+}
+
+type jsonStandings struct {
+	Championship string              `json:"championship"`
+	Races        []jsonStandingsRace `json:"races"`
+	Results      []jsonTeamStanding  `json:"results"`
+}
+
+type jsonStandingsRace struct {
+	Race  string `json:"race"`
+	Event string `json:"event"`
+	Date  string `json:"date"`
+}
+
+type jsonTeamStanding struct {
+	Rank    int                 `json:"rank"`
+	Team    string              `json:"team"`
+	Members string              `json:"members"`
+	Club    string              `json:"club"`
+	Races   []*jsonRaceStanding `json:"races"`
+	Total   int                 `json:"total"`
+}
+
+type jsonRaceStanding struct {
+	Rank    int  `json:"rank"`
+	Points  int  `json:"points"`
+	Counted bool `json:"counted"`
+}
+
+func newJSONStandings(standings Standings) jsonStandings {
+	result := jsonStandings{
+		Championship: standings.Championship.Name,
+		Races:        make([]jsonStandingsRace, len(standings.Races)),
+		Results:      make([]jsonTeamStanding, len(standings.Results)),
+	}
+	for i, r := range standings.Races {
+		result.Races[i] = jsonStandingsRace{
+			Race:  r.Race.Name,
+			Event: r.Event.Name,
+			Date:  r.Event.Date.Format("2006-01-02"),
+		}
+	}
+	for i, t := range standings.Results {
+		result.Results[i] = jsonTeamStanding{
+			Rank:    t.Rank,
+			Team:    t.Name,
+			Members: t.Members,
+			Club:    t.Club,
+			Races:   make([]*jsonRaceStanding, len(t.Races)),
+			Total:   t.Total,
+		}
+		for j, r := range t.Races {
+			if r != nil {
+				result.Results[i].Races[j] = &jsonRaceStanding{
+					Rank:    r.Rank,
+					Points:  r.Points,
+					Counted: r.Counted,
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/pkg/errors"
+)
+
+const (
+	pdfFontFamily  = "Helvetica"
+	pdfMargin      = 10.0 // mm
+	pdfRowHeight   = 6.0  // mm
+	pdfTitleHeight = 9.0  // mm
+)
+
+// table a document with one or more tables which have the same columns, such as the results of a race,
+// the podiums of a race (one table per category), the standings of a championship or the ranking of the clubs.
+// All generated documents are written from a table, so they look the same in all formats.
+type table struct {
+	// title the title of the document (eg: the name of the race)
+	title string
+	// subtitle the subtitle of the document (eg: "Classement Scratch"), if any
+	subtitle string
+	columns  []tableColumn
+	sections []tableSection
+	// sectionColumn the title of the column of the section titles in the CSV documents, in which the rows of
+	// all sections are written in a single table
+	sectionColumn string
+	// data the value written in the JSON documents, with typed values (eg: numbers instead of formatted strings)
+	data interface{}
+	// locale the locale of the document, in which the title, subtitle, sections and columns are already translated
+	locale string
+}
+
+// tableColumn a column of a table
+type tableColumn struct {
+	title string
+	// csvTitle the title of the column in the CSV documents, if it differs from the title (eg: "Classement" instead of "#")
+	csvTitle string
+	// width the relative width of the column
+	width float64
+	// align the alignment of the cells (`L`, `C` or `R`)
+	align string
+	// csvOnly 'true' if the column is only written in the CSV documents (which are processed in spreadsheets)
+	csvOnly bool
+}
+
+// tableSection a table in a document, with an optional title (eg: the title of a podium),
+// or a list of items if the section has no rows (eg: the categories without podium)
+type tableSection struct {
+	title string
+	rows  [][]string
+	items []string
+}
+
+// visibleColumns returns the indexes of the columns which are displayed in the documents other than CSV
+func (t table) visibleColumns() []int {
+	result := make([]int, 0, len(t.columns))
+	for i, c := range t.columns {
+		if !c.csvOnly {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// writeTable writes the given table in the file with the given name, using the given format
+func writeTable(filename string, t table, format OutputFormat) error {
+	var write func(io.Writer, table) error
+	switch format {
+	case AsciidocFormat:
+		write = writeTableAsciidoc
+	case CSVFormat:
+		write = writeTableCSV
+	case JSONFormat:
+		write = writeTableJSON
+	case HTMLFormat:
+		write = writeTableHTML
+	case PDFFormat:
+		write = writeTablePDF
+	default:
+		return errors.Errorf("unsupported output format: '%s'", format)
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return write(file, t)
+}
+
+// escapeAsciidocCell escapes the cell separators in the given text
+func escapeAsciidocCell(text string) string {
+	return strings.Replace(text, "|", "\\|", -1)
+}
+
+func writeTableAsciidoc(w io.Writer, t table) error {
+	adocWriter := bufio.NewWriter(w)
+	adocWriter.WriteString(fmt.Sprintf("= %s\n\n", t.title))
+	sectionLevel := "=="
+	if t.subtitle != "" {
+		adocWriter.WriteString(fmt.Sprintf("== %s\n\n", t.subtitle))
+		sectionLevel = "==="
+	}
+	columns := t.visibleColumns()
+	widths := make([]string, len(columns))
+	for i, c := range columns {
+		widths[i] = fmt.Sprintf("%g", t.columns[c].width)
+	}
+	for _, s := range t.sections {
+		if s.title != "" {
+			adocWriter.WriteString(fmt.Sprintf("%s %s\n\n", sectionLevel, s.title))
+		}
+		if s.rows == nil {
+			for _, item := range s.items {
+				adocWriter.WriteString(fmt.Sprintf("* %s\n", item))
+			}
+			adocWriter.WriteString("\n")
+			continue
+		}
+		// table header
+		adocWriter.WriteString(fmt.Sprintf("[cols=\"%s\"]\n", strings.Join(widths, ",")))
+		adocWriter.WriteString("|===\n")
+		for _, c := range columns {
+			adocWriter.WriteString(fmt.Sprintf("|%s ", escapeAsciidocCell(t.columns[c].title)))
+		}
+		adocWriter.WriteString("\n\n")
+		// table rows
+		for _, row := range s.rows {
+			for _, c := range columns {
+				adocWriter.WriteString(fmt.Sprintf("|%s ", escapeAsciidocCell(row[c])))
+			}
+			adocWriter.WriteString("\n")
+		}
+		// close table
+		adocWriter.WriteString("|===\n\n")
+	}
+	return adocWriter.Flush()
+}
+
+func writeTableCSV(w io.Writer, t table) error {
+	csvWriter := csv.NewWriter(w)
+	headers := make([]string, 0, len(t.columns)+1)
+	if t.sectionColumn != "" {
+		headers = append(headers, t.sectionColumn)
+	}
+	for _, c := range t.columns {
+		if c.csvTitle != "" {
+			headers = append(headers, c.csvTitle)
+			continue
+		}
+		headers = append(headers, c.title)
+	}
+	err := csvWriter.Write(headers)
+	if err != nil {
+		return err
+	}
+	for _, s := range t.sections {
+		for _, row := range s.rows {
+			if t.sectionColumn != "" {
+				row = append([]string{s.title}, row...)
+			}
+			if err := csvWriter.Write(row); err != nil {
+				return err
+			}
+		}
+		// the items have no value in the other columns
+		if t.sectionColumn != "" {
+			for _, item := range s.items {
+				if err := csvWriter.Write(append([]string{item}, make([]string, len(t.columns))...)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func writeTableJSON(w io.Writer, t table) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t.data)
+}
+
+var tableHTMLTmpl = template.Must(template.New("table").Parse(`<!DOCTYPE html>
+<html lang="{{ .Locale }}">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}{{ if .Subtitle }} - {{ .Subtitle }}{{ end }}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.6em; }
+h2 { font-size: 1.2em; color: #555; margin-top: 1.5em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #eee; }
+tr:nth-child(even) td { background: #f8f8f8; }
+td.num { text-align: right; }
+td.center { text-align: center; }
+@media print { body { margin: 0; } thead { display: table-header-group; } tr, section { page-break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+{{- if .Subtitle }}
+<h2>{{ .Subtitle }}</h2>
+{{- end }}
+{{- range .Sections }}
+<section>
+{{- if .Title }}
+<h2>{{ .Title }}</h2>
+{{- end }}
+{{- if .Items }}
+<ul>
+{{- range .Items }}
+<li>{{ . }}</li>
+{{- end }}
+</ul>
+{{- else }}
+<table>
+<thead>
+<tr>{{ range $.Columns }}<th>{{ .Title }}</th>{{ end }}</tr>
+</thead>
+<tbody>
+{{- range .Rows }}
+<tr>{{ range . }}<td{{ if .Class }} class="{{ .Class }}"{{ end }}>{{ .Value }}</td>{{ end }}</tr>
+{{- end }}
+</tbody>
+</table>
+{{- end }}
+</section>
+{{- end }}
+</body>
+</html>
+`))
+
+func writeTableHTML(w io.Writer, t table) error {
+	type htmlColumn struct {
+		Title string
+	}
+	type htmlCell struct {
+		Value string
+		Class string
+	}
+	type htmlSection struct {
+		Title string
+		Rows  [][]htmlCell
+		Items []string
+	}
+	columns := t.visibleColumns()
+	data := struct {
+		Locale   string
+		Title    string
+		Subtitle string
+		Columns  []htmlColumn
+		Sections []htmlSection
+	}{
+		Locale:   newTranslator(t.locale).locale,
+		Title:    t.title,
+		Subtitle: t.subtitle,
+		Columns:  make([]htmlColumn, len(columns)),
+		Sections: make([]htmlSection, len(t.sections)),
+	}
+	classes := map[string]string{"R": "num", "C": "center"}
+	for i, c := range columns {
+		data.Columns[i] = htmlColumn{Title: t.columns[c].title}
+	}
+	for i, s := range t.sections {
+		data.Sections[i] = htmlSection{
+			Title: s.title,
+			Rows:  make([][]htmlCell, len(s.rows)),
+			Items: s.items,
+		}
+		for j, row := range s.rows {
+			data.Sections[i].Rows[j] = make([]htmlCell, len(columns))
+			for k, c := range columns {
+				data.Sections[i].Rows[j][k] = htmlCell{Value: row[c], Class: classes[t.columns[c].align]}
+			}
+		}
+	}
+	return tableHTMLTmpl.Execute(w, data)
+}
+
+// writeTablePDF writes the table in a print-ready PDF document, in A4 landscape. In a document with a single,
+// untitled section, the title and subtitle as well as the table header are repeated on each page. Otherwise, each
+// section is kept on a single page when possible.
+func writeTablePDF(w io.Writer, t table) error {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin+pdfRowHeight)
+	pdf.AliasNbPages("{nb}")
+	// core fonts only support the cp1252 encoding
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	if t.subtitle != "" {
+		pdf.SetTitle(fmt.Sprintf("%s - %s", t.title, t.subtitle), true)
+	} else {
+		pdf.SetTitle(t.title, true)
+	}
+	columns := make([]tableColumn, 0, len(t.columns))
+	for _, c := range t.visibleColumns() {
+		columns = append(columns, t.columns[c])
+	}
+	widths := pdfColumnWidths(pdf, columns)
+	writeTitles := func() {
+		pdf.SetFont(pdfFontFamily, "B", 16)
+		pdf.CellFormat(0, pdfTitleHeight, tr(t.title), "", 1, "L", false, 0, "")
+		if t.subtitle != "" {
+			pdf.SetFont(pdfFontFamily, "", 13)
+			pdf.CellFormat(0, pdfTitleHeight, tr(t.subtitle), "", 1, "L", false, 0, "")
+		}
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin)
+		pdf.SetFont(pdfFontFamily, "I", 8)
+		pdf.CellFormat(0, pdfRowHeight/2, fmt.Sprintf("%d/{nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	if len(t.sections) == 1 && t.sections[0].title == "" {
+		pdf.SetHeaderFunc(func() {
+			writeTitles()
+			pdf.Ln(2)
+			writePDFTableHeader(pdf, tr, columns, widths)
+		})
+		pdf.AddPage()
+		writePDFTableCells(pdf, tr, t, t.sections[0].rows, widths)
+		return pdf.Output(w)
+	}
+	_, pageHeight := pdf.GetPageSize()
+	pdf.AddPage()
+	writeTitles()
+	for _, s := range t.sections {
+		height := pdfTitleHeight + float64(len(s.rows)+len(s.items)+1)*pdfRowHeight
+		if pdf.GetY()+height > pageHeight-pdfMargin-pdfRowHeight {
+			pdf.AddPage()
+		}
+		pdf.Ln(3)
+		pdf.SetFont(pdfFontFamily, "B", 13)
+		pdf.CellFormat(0, pdfTitleHeight, tr(s.title), "", 1, "L", false, 0, "")
+		if s.rows == nil {
+			pdf.SetFont(pdfFontFamily, "", 10)
+			for _, item := range s.items {
+				pdf.CellFormat(0, pdfRowHeight, tr(fmt.Sprintf("- %s", item)), "", 1, "L", false, 0, "")
+			}
+			continue
+		}
+		writePDFTableHeader(pdf, tr, columns, widths)
+		writePDFTableCells(pdf, tr, t, s.rows, widths)
+	}
+	return pdf.Output(w)
+}
+
+// pdfColumnWidths returns the actual widths of the given columns, so the table fills the page width
+func pdfColumnWidths(pdf *gofpdf.Fpdf, columns []tableColumn) []float64 {
+	pageWidth, _ := pdf.GetPageSize()
+	totalWidth := 0.0
+	for _, c := range columns {
+		totalWidth += c.width
+	}
+	widths := make([]float64, len(columns))
+	for i, c := range columns {
+		widths[i] = c.width * (pageWidth - 2*pdfMargin) / totalWidth
+	}
+	return widths
+}
+
+func writePDFTableHeader(pdf *gofpdf.Fpdf, tr func(string) string, columns []tableColumn, widths []float64) {
+	pdf.SetFont(pdfFontFamily, "B", 10)
+	pdf.SetFillColor(220, 220, 220)
+	for i, c := range columns {
+		pdf.CellFormat(widths[i], pdfRowHeight, tr(c.title), "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+}
+
+// writePDFTableCells writes the given rows of the given table, in its visible columns
+func writePDFTableCells(pdf *gofpdf.Fpdf, tr func(string) string, t table, rows [][]string, widths []float64) {
+	columns := t.visibleColumns()
+	for n, row := range rows {
+		for i, c := range columns {
+			// the header func changes the font and fill color when a new page is added
+			// (which may happen automatically, while writing the first cell of the row)
+			pdf.SetFont(pdfFontFamily, "", 9)
+			pdf.SetFillColor(245, 245, 245)
+			text := truncate(pdf, tr(row[c]), widths[i]-2)
+			pdf.CellFormat(widths[i], pdfRowHeight, text, "1", 0, t.columns[c].align, n%2 == 1, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+// truncate truncates the given text so it fits in the given width
+func truncate(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	for len(text) > 0 && pdf.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
@@ -1,8 +1,14 @@
 package service
 
 import (
+	"context"
+	"math/rand"
+	"sync"
 	"time"
 
+	"github.com/vatriathlon/stopwatch/metrics"
+
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -17,56 +23,175 @@ func DatabaseTransactionTimeout() time.Duration {
 	return databaseTransactionTimeout
 }
 
+// openTransactions the transactions which are not complete yet
+var openTransactions sync.WaitGroup
+
+// drainingLock guards `draining`, so no transaction is added to `openTransactions` once the shutdown
+// started to wait for them
+var drainingLock sync.Mutex
+
+// draining 'true' once the shutdown started to wait for the open transactions. The new transactions are rejected.
+var draining bool
+
+// ErrDraining the error returned when a transaction is requested while waiting for the open transactions
+var ErrDraining = errors.New("database transaction rejected: waiting for the open transactions before shutdown")
+
+// ErrTransactionTimeout the error returned when a transaction did not complete before the transaction timeout
+var ErrTransactionTimeout = errors.New("database transaction timeout")
+
+// openTransaction registers a new open transaction, unless the shutdown already started to wait for them
+func openTransaction() error {
+	drainingLock.Lock()
+	defer drainingLock.Unlock()
+	if draining {
+		return ErrDraining
+	}
+	openTransactions.Add(1)
+	return nil
+}
+
+// WaitForTransactions rejects the new transactions, then waits until all open transactions are complete, or
+// returns an error if the given context is done before
+func WaitForTransactions(ctx context.Context) error {
+	drainingLock.Lock()
+	draining = true
+	drainingLock.Unlock()
+	done := make(chan struct{})
+	go func() {
+		openTransactions.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "open transactions are not complete")
+	}
+}
+
 // TransactionManager manages the lifecycle of a database transaction. The transactional resources (such as repositories)
 // created for the transaction object make changes inside the transaction
 type TransactionManager interface {
-	BeginTransaction() (Transaction, error)
+	BeginTransaction(ctx context.Context) (Transaction, error)
 }
 
-// Transactional executes the given function in a transaction. If todo returns an error, the transaction is rolled back
-func Transactional(svc *GormService, todo func(r Repositories) error) error {
-	var tx Transaction
-	var err error
-	if tx, err = svc.BeginTransaction(); err != nil {
-		logrus.WithError(err).Error("database BeginTransaction failed!")
-		return errors.WithStack(err)
+// TransactionRetryPolicy the policy to retry the transactions which failed because of a serialization failure or a
+// deadlock. The duration to wait before retrying is doubled after each attempt.
+type TransactionRetryPolicy struct {
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+var transactionRetryPolicy = TransactionRetryPolicy{
+	MaxRetries: 5,
+	Backoff:    20 * time.Millisecond,
+}
+
+// SetTransactionRetryPolicy sets the policy to retry the transactions
+func SetTransactionRetryPolicy(p TransactionRetryPolicy) error {
+	if p.MaxRetries < 0 {
+		return errors.Errorf("invalid maximum number of transaction retries: %d (expected a positive number or zero)", p.MaxRetries)
+	}
+	if p.Backoff < 0 {
+		return errors.Errorf("invalid transaction retry backoff: %s (expected a positive duration or zero)", p.Backoff)
 	}
+	transactionRetryPolicy = p
+	return nil
+}
 
-	return func() error {
-		errorChan := make(chan error, 1)
-		txTimeout := time.After(databaseTransactionTimeout)
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
 
-		go func(r Repositories) {
-			defer func() {
-				if err := recover(); err != nil {
-					errorChan <- errors.Errorf("unknown error: %v", err)
-				}
-			}()
-			errorChan <- todo(tx)
-		}(tx)
+// retryableErrorCode returns the SQLSTATE code of the given error if the transaction which failed can be retried
+func retryableErrorCode(err error) (string, bool) {
+	e, ok := errors.Cause(err).(*pq.Error)
+	if !ok || (e.Code != serializationFailure && e.Code != deadlockDetected) {
+		return "", false
+	}
+	return string(e.Code), true
+}
 
+// Transactional executes the given function in a transaction bound to the given context. If todo returns an error,
+// or if the context is done (eg: the client disconnected) or the transaction timed out before todo returned, then
+// the transaction is rolled back. If the transaction failed because of a serialization failure or a deadlock, it is
+// retried in a new transaction according to the retry policy, so todo must not have other side effects than the
+// changes in the database. Returns `ErrDraining` once the shutdown started to wait for the open transactions.
+func Transactional(ctx context.Context, svc *GormService, todo func(r Repositories) error) error {
+	if err := openTransaction(); err != nil {
+		return errors.WithStack(err)
+	}
+	defer openTransactions.Done()
+	ctx, cancel := context.WithTimeout(ctx, databaseTransactionTimeout)
+	defer cancel()
+	policy := transactionRetryPolicy
+	backoff := policy.Backoff
+	for attempt := 0; ; attempt++ {
+		err := transactional(ctx, svc, todo)
+		code, retryable := retryableErrorCode(err)
+		if !retryable || attempt >= policy.MaxRetries {
+			return err
+		}
+		logrus.WithError(err).WithField("attempt", attempt+1).Warn("retrying the database transaction...")
+		metrics.RetryTransaction(code)
+		// add some jitter, so the conflicting transactions are not retried at the same time
+		wait := backoff
+		if backoff > 0 {
+			wait += time.Duration(rand.Int63n(int64(backoff)))
+		}
 		select {
-		case err := <-errorChan:
-			if err != nil {
-				logrus.Warn("Rolling back the transaction...")
-				logrus.WithError(err).Error("database transaction failed. Rolling back...")
-				if err2 := tx.Rollback(); err2 != nil {
-					logrus.WithError(err2).Error("database transaction rollback failed!")
-				}
-				return errors.WithStack(err)
-			}
-			if err := tx.Commit(); err != nil {
-				logrus.WithError(err).Error("database transaction commit failed!")
-			}
-			logrus.Debug("Commit the transaction!")
-			return nil
-		case <-txTimeout:
-			logrus.Debug("Rolling back the transaction...")
-			logrus.WithError(err).Error("database transaction timeout. Rolling back...")
-			if err2 := tx.Rollback(); err2 != nil {
-				logrus.WithError(err2).Error("database transaction rollback failed!")
-			}
-			return errors.New("database transaction timeout")
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
 		}
+		backoff *= 2
+	}
+}
+
+// transactional executes the given function in a single transaction bound to the given context
+func transactional(ctx context.Context, svc *GormService, todo func(r Repositories) error) error {
+	tx, err := svc.BeginTransaction(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("database BeginTransaction failed!")
+		return errors.WithStack(err)
+	}
+
+	start := time.Now()
+	err = func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = errors.Errorf("unknown error: %v", r)
+			}
+		}()
+		return todo(tx)
 	}()
+	if ctx.Err() != nil {
+		// the transaction was already rolled back when the context was done
+		tx.Rollback()
+		if ctx.Err() == context.DeadlineExceeded {
+			logrus.WithError(err).Error("database transaction timeout. Rolled back")
+			metrics.ObserveTransaction(metrics.TransactionTimeout, time.Since(start))
+			return errors.WithStack(ErrTransactionTimeout)
+		}
+		logrus.WithError(err).Warn("database transaction cancelled. Rolled back")
+		metrics.ObserveTransaction(metrics.TransactionRollback, time.Since(start))
+		return errors.Wrap(ctx.Err(), "database transaction cancelled")
+	}
+	if err != nil {
+		logrus.WithError(err).Error("database transaction failed. Rolling back...")
+		if err2 := tx.Rollback(); err2 != nil {
+			logrus.WithError(err2).Error("database transaction rollback failed!")
+		}
+		metrics.ObserveTransaction(metrics.TransactionRollback, time.Since(start))
+		return errors.WithStack(err)
+	}
+	if err := tx.Commit(); err != nil {
+		logrus.WithError(err).Error("database transaction commit failed!")
+		metrics.ObserveTransaction(metrics.TransactionRollback, time.Since(start))
+		return errors.WithStack(err)
+	}
+	logrus.Debug("Commit the transaction!")
+	metrics.ObserveTransaction(metrics.TransactionCommit, time.Since(start))
+	return nil
 }
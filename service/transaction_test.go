@@ -1,6 +1,7 @@
 package service_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -10,6 +11,8 @@ import (
 	"github.com/vatriathlon/stopwatch/service"
 	testsuite "github.com/vatriathlon/stopwatch/test/suite"
 
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -31,11 +34,128 @@ func (s *TransactionTestSuite) SetupTest() {
 	s.gormService = service.NewGormService(s.DB)
 }
 
+func TestTransactionalWhileDraining(t *testing.T) {
+	// given
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	defer service.ResumeTransactions()
+	err := service.WaitForTransactions(ctx)
+	require.NoError(t, err)
+	// when (the transactions are rejected before using the database, so none is needed)
+	err = service.Transactional(context.Background(), nil, func(r service.Repositories) error {
+		return errors.New("should not be called")
+	})
+	// then
+	require.Error(t, err)
+	assert.Equal(t, service.ErrDraining, errors.Cause(err))
+}
+
+func TestSetTransactionRetryPolicy(t *testing.T) {
+	defer service.SetTransactionRetryPolicy(service.TransactionRetryPolicy{MaxRetries: 5, Backoff: 20 * time.Millisecond})
+
+	t.Run("no retry", func(t *testing.T) {
+		// when
+		err := service.SetTransactionRetryPolicy(service.TransactionRetryPolicy{MaxRetries: 0, Backoff: 0})
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("negative max retries", func(t *testing.T) {
+		// when
+		err := service.SetTransactionRetryPolicy(service.TransactionRetryPolicy{MaxRetries: -1, Backoff: time.Millisecond})
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid maximum number of transaction retries: -1")
+	})
+
+	t.Run("negative backoff", func(t *testing.T) {
+		// when
+		err := service.SetTransactionRetryPolicy(service.TransactionRetryPolicy{MaxRetries: 5, Backoff: -time.Millisecond})
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid transaction retry backoff: -1ms")
+	})
+}
+
+func TestParseTXIsoLevel(t *testing.T) {
+	testcases := map[string]service.TXIsoLevel{
+		"":                service.TXIsoLevelDefault,
+		"default":         service.TXIsoLevelDefault,
+		"read committed":  service.TXIsoLevelReadCommitted,
+		"REPEATABLE_READ": service.TXIsoLevelRepeatableRead,
+		"serializable":    service.TXIsoLevelSerializable,
+	}
+	for value, expected := range testcases {
+		t.Run(value, func(t *testing.T) {
+			// when
+			result, err := service.ParseTXIsoLevel(value)
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, expected, result)
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		// when
+		_, err := service.ParseTXIsoLevel("read uncommitted")
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown transaction isolation level: 'read uncommitted'")
+	})
+}
+
+func (s *TransactionTestSuite) TestTransactionRetry() {
+	require.NoError(s.T(), service.SetTransactionRetryPolicy(service.TransactionRetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}))
+	defer service.SetTransactionRetryPolicy(service.TransactionRetryPolicy{MaxRetries: 5, Backoff: 20 * time.Millisecond})
+
+	s.T().Run("serialization failure", func(t *testing.T) {
+		// given
+		attempts := 0
+		// when
+		err := service.Transactional(context.Background(), s.gormService, func(r service.Repositories) error {
+			attempts++
+			if attempts < 3 {
+				return errors.Wrap(&pq.Error{Code: "40001"}, "unable to create lap")
+			}
+			return nil
+		})
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	s.T().Run("deadlock", func(t *testing.T) {
+		// given
+		attempts := 0
+		// when
+		err := service.Transactional(context.Background(), s.gormService, func(r service.Repositories) error {
+			attempts++
+			return &pq.Error{Code: "40P01"}
+		})
+		// then
+		require.Error(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	s.T().Run("other error", func(t *testing.T) {
+		// given
+		attempts := 0
+		// when
+		err := service.Transactional(context.Background(), s.gormService, func(r service.Repositories) error {
+			attempts++
+			return &pq.Error{Code: "23505"}
+		})
+		// then
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
 func (s *TransactionTestSuite) TransactionTestSuiteInTime() {
 	// given
 	computeTime := 10 * time.Second
 	// then
-	err := service.Transactional(s.gormService, func(r service.Repositories) error {
+	err := service.Transactional(context.Background(), s.gormService, func(r service.Repositories) error {
 		time.Sleep(computeTime)
 		return nil
 	})
@@ -45,21 +165,53 @@ func (s *TransactionTestSuite) TransactionTestSuiteInTime() {
 
 func (s *TransactionTestSuite) TransactionTestSuiteOut() {
 	// given
-	computeTime := 6 * time.Minute
-	service.SetDatabaseTransactionTimeout(5 * time.Second)
+	timeout := service.DatabaseTransactionTimeout()
+	defer service.SetDatabaseTransactionTimeout(timeout)
+	service.SetDatabaseTransactionTimeout(1 * time.Second)
 	// then
-	err := service.Transactional(s.gormService, func(r service.Repositories) error {
-		time.Sleep(computeTime)
-		return nil
+	err := service.Transactional(context.Background(), s.gormService, func(r service.Repositories) error {
+		return r.DB().Exec("select pg_sleep(60)").Error
 	})
 	// then
 	require.Error(s.T(), err)
 	assert.Contains(s.T(), err.Error(), "database transaction timeout")
 }
 
+func (s *TransactionTestSuite) TestTransactionCancelled() {
+
+	s.T().Run("before begin", func(t *testing.T) {
+		// given
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		// when
+		err := service.Transactional(ctx, s.gormService, func(r service.Repositories) error {
+			return nil
+		})
+		// then
+		require.Error(t, err)
+		assert.Equal(t, context.Canceled, errors.Cause(err))
+	})
+
+	s.T().Run("during statement", func(t *testing.T) {
+		// given
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		// when
+		err := service.Transactional(ctx, s.gormService, func(r service.Repositories) error {
+			return r.DB().Exec("select pg_sleep(60)").Error
+		})
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "database transaction timeout")
+		// the statement was cancelled in the database
+		assert.True(t, time.Since(start) < 10*time.Second)
+	})
+}
+
 func (s *TransactionTestSuite) TransactionTestSuitePanicAndRecoverWithStack() {
 	// then
-	err := service.Transactional(s.gormService, func(r service.Repositories) error {
+	err := service.Transactional(context.Background(), s.gormService, func(r service.Repositories) error {
 		bar := func(a, b interface{}) {
 			// This comparison while legal at compile time will cause a runtime
 			// error like this: "comparing uncomparable type
@@ -84,3 +236,74 @@ func (s *TransactionTestSuite) TransactionTestSuitePanicAndRecoverWithStack() {
 	// ensure there's a proper stack trace that contains the name of this test
 	require.Contains(s.T(), err.Error(), "(*TransactionTestSuite).TransactionTestSuitePanicAndRecoverWithStack.func1(")
 }
+
+func (s *TransactionTestSuite) TestWaitForTransactions() {
+
+	s.T().Run("no open transaction", func(t *testing.T) {
+		// given
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		defer service.ResumeTransactions()
+		// when
+		err := service.WaitForTransactions(ctx)
+		// then
+		require.NoError(t, err)
+	})
+
+	s.T().Run("open transaction complete", func(t *testing.T) {
+		// given
+		started := make(chan struct{})
+		go service.Transactional(context.Background(), s.gormService, func(r service.Repositories) error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		})
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		defer service.ResumeTransactions()
+		// when
+		err := service.WaitForTransactions(ctx)
+		// then
+		require.NoError(t, err)
+	})
+
+	s.T().Run("open transaction not complete", func(t *testing.T) {
+		// given
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		go service.Transactional(context.Background(), s.gormService, func(r service.Repositories) error {
+			close(started)
+			<-release
+			return nil
+		})
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		defer service.ResumeTransactions()
+		// when
+		err := service.WaitForTransactions(ctx)
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "open transactions are not complete")
+	})
+	s.T().Run("new transaction rejected", func(t *testing.T) {
+		// given
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		defer service.ResumeTransactions()
+		err := service.WaitForTransactions(ctx)
+		require.NoError(t, err)
+		called := false
+		// when
+		err = service.Transactional(context.Background(), s.gormService, func(r service.Repositories) error {
+			called = true
+			return nil
+		})
+		// then
+		require.Error(t, err)
+		assert.Equal(t, service.ErrDraining, errors.Cause(err))
+		assert.False(t, called)
+	})
+}
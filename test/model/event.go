@@ -0,0 +1,23 @@
+package testmodel
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vatriathlon/stopwatch/model"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// CreateEvent creates a new event (with a unique name) in the given database
+func CreateEvent(t require.TestingT, db *gorm.DB) model.Event {
+	event := model.Event{
+		Name: fmt.Sprintf("event %s", uuid.NewV4()),
+		Date: time.Now().Truncate(24 * time.Hour),
+	}
+	err := model.NewEventRepository(db).Create(&event)
+	require.NoError(t, err)
+	return event
+}
@@ -14,7 +14,6 @@ func NewTeam(raceID int, bibnumber int) model.Team {
 		BibNumber:   bibnumber,
 		RaceID:      raceID,
 		Gender:      "M",
-		Challenge:   "open",
 		AgeCategory: "Senior",
 		Member1:     newTeamMember("john", "doe", "H"),
 		Member2:     newTeamMember("jane", "doe", "F"),